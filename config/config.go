@@ -1,58 +1,210 @@
-package config
-
-import (
-	"os"
-	"strconv"
-)
-
-type Config struct {
-	SQSQueueURL   string // URL da fila SQS.
-	PGHost        string // Host do RDS.
-	PGPort        string // Porta do RDS.
-	PGName        string // Nome do banco.
-	PGUser        string // Usuário do banco.
-	PGPassword    string // Senha do banco (será obtida do AWS Secrets Manager).
-	DBSecretID    string // ID do segredo no AWS Secrets Manager para o DB.
-	AWSRegion     string // Região AWS para buscar segredos.
-	GitleaksPath  string // Caminho do binário do Gitleaks.
-	EnableVault   bool   // Habilita integração com Vault para GitHub.
-	EnableSecrets bool   // Habilita integração com AWS Secrets Manager.
-	EnableClone   bool   // Habilita clonagem do repositório.
-	EnableScan    bool   // Habilita execução do scanner (Gitleaks).
-	EnableSQS     bool   // Habilita consumo de mensagens da SQS.
-}
-
-func Load() Config {
-	parseBool := func(key string) bool {
-		val, err := strconv.ParseBool(os.Getenv(key))
-		if err != nil {
-			return false
-		}
-		return val
-	}
-	return Config{
-		SQSQueueURL:   os.Getenv("SQS_QUEUE_URL"),
-		PGHost:        os.Getenv("PG_HOST"),
-		PGPort:        os.Getenv("PG_PORT"),
-		PGName:        os.Getenv("PG_NAME"),
-		PGUser:        os.Getenv("PG_USER"),
-		PGPassword:    os.Getenv("PG_PASSWORD"), // valor padrão (pode ser vazio, pois será substituído pelo secret)
-		DBSecretID:    os.Getenv("DB_SM_SECRET_ID"),
-		AWSRegion:     os.Getenv("AWS_REGION"),
-		GitleaksPath:  os.Getenv("GITLEAKS_PATH"),
-		EnableVault:   parseBool("ENABLE_VAULT"),
-		EnableSecrets: parseBool("ENABLE_SECRETS_MANAGER"),
-		EnableClone:   parseBool("ENABLE_GIT_CLONE"),
-		EnableScan:    parseBool("ENABLE_GITLEAKS"),
-		EnableSQS:     parseBool("ENABLE_SQS"),
-	}
-}
-
-func (c Config) PostgresConnString() string {
-	return "host=" + c.PGHost +
-		" port=" + c.PGPort +
-		" dbname=" + c.PGName +
-		" user=" + c.PGUser +
-		" password=" + c.PGPassword +
-		" sslmode=disable"
-}
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NamedValue é um par "nome=valor" de uma lista em variável de ambiente.
+type NamedValue struct {
+	Name  string
+	Value string
+}
+
+type Config struct {
+	SQSQueueURL   string // URL da fila SQS.
+	PGHost        string // Host do RDS.
+	PGPort        string // Porta do RDS.
+	PGName        string // Nome do banco.
+	PGUser        string // Usuário do banco.
+	PGPassword    string // Senha do banco (será obtida do AWS Secrets Manager).
+	DBSecretID    string // ID do segredo no AWS Secrets Manager para o DB.
+	AWSRegion     string // Região AWS para buscar segredos.
+	GitleaksPath  string // Caminho do binário do Gitleaks.
+	EnableVault   bool   // Habilita integração com Vault para GitHub.
+	EnableSecrets bool   // Habilita integração com AWS Secrets Manager.
+	EnableClone   bool   // Habilita clonagem do repositório.
+	EnableScan    bool   // Habilita execução do scanner (Gitleaks).
+	EnableSQS     bool   // Habilita consumo de mensagens da SQS.
+	JobFilePath   string // Arquivo ou diretório JSONL usado como origem local de jobs.
+	APIAddr       string // Endereço da API HTTP (ex.: ":8080"); vazio desabilita.
+	APIToken      string // Token exigido nas rotas /scans da API (Authorization: Bearer).
+
+	// GitProviders são os provedores Git "nome=tipo:url" (ex.:
+	// "gitlab=gitlab:https://gitlab.empresa.com"); o GitHub público é sempre
+	// configurado como "github", a menos que seja redefinido aqui.
+	GitProviders []NamedValue
+	// SSHInsecureIgnoreHostKey desliga a verificação de known_hosts nos clones SSH.
+	SSHInsecureIgnoreHostKey bool
+
+	CloneStrategy        string // Estratégia de clone padrão: full, single-branch, shallow, blobless ou auto.
+	CloneBloblessMinSize int    // Tamanho (KB) a partir do qual "auto" usa clone blobless; 0 desabilita.
+	CloneShallowMinSize  int    // Tamanho (KB) a partir do qual "auto" usa clone shallow; 0 desabilita.
+	CloneShallowDepth    int    // Commits baixados no clone shallow.
+	CloneTimeout         int    // Tempo limite base (segundos) do clone; 0 desabilita.
+	CloneTimeoutPerGB    int    // Segundos acrescidos ao tempo limite por GB do repositório.
+	CloneMaxSize         int    // Tamanho máximo (MB) baixado em um clone; 0 desabilita.
+	MirrorCacheDir       string // Diretório do cache de mirrors; vazio desabilita.
+	MirrorCacheMaxSize   int    // Orçamento de disco (MB) do cache de mirrors.
+	WorkspaceDir         string // Diretório exclusivo dos clones de cada job; vazio usa clonescan-workspaces no diretório temporário.
+	WorkspaceMinFree     int    // Espaço livre (MB) mantido no disco dos workspaces; jobs que o violariam são adiados.
+
+	GitHubWebhookSecret string // Segredo dos webhooks do GitHub; habilita POST /webhooks/github na API.
+
+	// GitHubAppID e GitHubAppPrivateKey (PEM) autenticam no GitHub como GitHub
+	// App, com tokens de instalação no lugar do PAT; vazio usa o Vault.
+	GitHubAppID         string
+	GitHubAppPrivateKey string
+
+	GitHubAPIURL      string       // URL base da API do GitHub; vazio usa https://api.github.com.
+	GitHubOrg         string       // Organização cujos repositórios são descobertos.
+	DiscoverySiglas   []NamedValue // Regras "padrão=SIGLA" (ex.: "pix-*=PIX") aplicadas ao nome do repositório.
+	EnableDiscovery   bool         // Executa a descoberta periodicamente em background.
+	DiscoveryInterval int          // Intervalo (segundos) entre descobertas.
+
+	EnableEvents      bool   // Habilita eventos scan.completed/scan.failed via outbox.
+	EventsSQSQueueURL string // Fila SQS de destino dos eventos.
+	EventsWebhookURL  string // Webhook de destino dos eventos (alternativa à SQS).
+
+	ShutdownTimeout int // Prazo (segundos) para os jobs em execução terminarem no encerramento.
+
+	EnableCoalescing bool // Agrupa jobs simultâneos do mesmo repositório em um único scan.
+	EnableScheduler  bool // Dispara os agendamentos recorrentes de scan.
+
+	SQSVisibilityTimeout int // Timeout de visibilidade (segundos) renovado pelo heartbeat.
+	SQSMaxRetryDelay     int // Atraso máximo (segundos) antes de uma nova tentativa.
+	SQSMaxAttempts       int // Tentativas com falha antes de mover o job para a quarentena.
+
+	SQSJobMaxAge        int    // Idade máxima (segundos) de jobs da SQS; 0 desabilita.
+	SQSJobExpiryAction  string // "drop" ou "deprioritize" para jobs da SQS vencidos.
+	FileJobMaxAge       int    // Idade máxima (segundos) de jobs do arquivo JSONL; 0 desabilita.
+	FileJobExpiryAction string // "drop" ou "deprioritize" para jobs do arquivo vencidos.
+
+	SQSEndpointURL string // Endpoint customizado da SQS (ex.: o fake local); vazio usa a AWS.
+	SQSFakeAddr    string // Sobe o SQS fake em memória neste endereço (ex.: "127.0.0.1:9324").
+
+	SQSQueues           []NamedValue   // Filas "nome=url" em ordem de prioridade; substitui SQS_QUEUE_URL.
+	SQSQueueStrategy    string         // "priority" (padrão) ou "weighted".
+	SQSQueueWeights     map[string]int // Peso de cada fila na estratégia "weighted".
+	QueueMaxConcurrency map[string]int // Jobs simultâneos por fila no consumer.
+	QueueReserved       map[string]int // Workers reservados por fila no consumer.
+}
+
+func Load() Config {
+	parseBool := func(key string) bool {
+		val, err := strconv.ParseBool(os.Getenv(key))
+		if err != nil {
+			return false
+		}
+		return val
+	}
+	parseInt := func(key string, defaultVal int) int {
+		val, err := strconv.Atoi(os.Getenv(key))
+		if err != nil {
+			return defaultVal
+		}
+		return val
+	}
+	// parsePairs lê listas no formato "nome=valor,nome=valor".
+	parsePairs := func(key string) []NamedValue {
+		var pairs []NamedValue
+		for _, item := range strings.Split(os.Getenv(key), ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+			if ok && name != "" {
+				pairs = append(pairs, NamedValue{Name: name, Value: value})
+			}
+		}
+		return pairs
+	}
+	parseIntMap := func(key string) map[string]int {
+		m := make(map[string]int)
+		for _, p := range parsePairs(key) {
+			if n, err := strconv.Atoi(p.Value); err == nil {
+				m[p.Name] = n
+			}
+		}
+		return m
+	}
+	return Config{
+		SQSQueueURL:   os.Getenv("SQS_QUEUE_URL"),
+		PGHost:        os.Getenv("PG_HOST"),
+		PGPort:        os.Getenv("PG_PORT"),
+		PGName:        os.Getenv("PG_NAME"),
+		PGUser:        os.Getenv("PG_USER"),
+		PGPassword:    os.Getenv("PG_PASSWORD"), // valor padrão (pode ser vazio, pois será substituído pelo secret)
+		DBSecretID:    os.Getenv("DB_SM_SECRET_ID"),
+		AWSRegion:     os.Getenv("AWS_REGION"),
+		GitleaksPath:  os.Getenv("GITLEAKS_PATH"),
+		EnableVault:   parseBool("ENABLE_VAULT"),
+		EnableSecrets: parseBool("ENABLE_SECRETS_MANAGER"),
+		EnableClone:   parseBool("ENABLE_GIT_CLONE"),
+		EnableScan:    parseBool("ENABLE_GITLEAKS"),
+		EnableSQS:     parseBool("ENABLE_SQS"),
+		JobFilePath:   os.Getenv("JOB_FILE_PATH"),
+		APIAddr:       os.Getenv("API_ADDR"),
+		APIToken:      os.Getenv("API_TOKEN"),
+
+		GitProviders:             parsePairs("GIT_PROVIDERS"),
+		SSHInsecureIgnoreHostKey: parseBool("SSH_INSECURE_IGNORE_HOST_KEY"),
+
+		CloneStrategy:        os.Getenv("CLONE_STRATEGY"),
+		CloneBloblessMinSize: parseInt("CLONE_BLOBLESS_MIN_SIZE", 512*1024),
+		CloneShallowMinSize:  parseInt("CLONE_SHALLOW_MIN_SIZE", 0),
+		CloneShallowDepth:    parseInt("CLONE_SHALLOW_DEPTH", 50),
+		CloneTimeout:         parseInt("CLONE_TIMEOUT", 600),
+		CloneTimeoutPerGB:    parseInt("CLONE_TIMEOUT_PER_GB", 600),
+		CloneMaxSize:         parseInt("CLONE_MAX_SIZE", 10*1024),
+		MirrorCacheDir:       os.Getenv("MIRROR_CACHE_DIR"),
+		MirrorCacheMaxSize:   parseInt("MIRROR_CACHE_MAX_SIZE", 20*1024),
+		WorkspaceDir:         os.Getenv("WORKSPACE_DIR"),
+		WorkspaceMinFree:     parseInt("WORKSPACE_MIN_FREE", 1024),
+
+		GitHubWebhookSecret: os.Getenv("GITHUB_WEBHOOK_SECRET"),
+
+		GitHubAppID:         os.Getenv("GITHUB_APP_ID"),
+		GitHubAppPrivateKey: os.Getenv("GITHUB_APP_PRIVATE_KEY"),
+
+		GitHubAPIURL:      os.Getenv("GITHUB_API_URL"),
+		GitHubOrg:         os.Getenv("GITHUB_ORG"),
+		DiscoverySiglas:   parsePairs("GITHUB_DISCOVERY_SIGLAS"),
+		EnableDiscovery:   parseBool("ENABLE_DISCOVERY"),
+		DiscoveryInterval: parseInt("DISCOVERY_INTERVAL", 6*60*60),
+
+		EnableEvents:      parseBool("ENABLE_EVENTS"),
+		EventsSQSQueueURL: os.Getenv("EVENTS_SQS_QUEUE_URL"),
+		EventsWebhookURL:  os.Getenv("EVENTS_WEBHOOK_URL"),
+
+		ShutdownTimeout: parseInt("SHUTDOWN_TIMEOUT", 25),
+
+		EnableCoalescing: parseBool("ENABLE_COALESCING"),
+		EnableScheduler:  parseBool("ENABLE_SCHEDULER"),
+
+		SQSVisibilityTimeout: parseInt("SQS_VISIBILITY_TIMEOUT", 120),
+		SQSMaxRetryDelay:     parseInt("SQS_MAX_RETRY_DELAY", 900),
+		SQSMaxAttempts:       parseInt("SQS_MAX_ATTEMPTS", 5),
+
+		SQSJobMaxAge:        parseInt("SQS_JOB_MAX_AGE", 0),
+		SQSJobExpiryAction:  os.Getenv("SQS_JOB_EXPIRY_ACTION"),
+		FileJobMaxAge:       parseInt("FILE_JOB_MAX_AGE", 0),
+		FileJobExpiryAction: os.Getenv("FILE_JOB_EXPIRY_ACTION"),
+
+		SQSEndpointURL: os.Getenv("SQS_ENDPOINT_URL"),
+		SQSFakeAddr:    os.Getenv("SQS_FAKE_ADDR"),
+
+		SQSQueues:           parsePairs("SQS_QUEUES"),
+		SQSQueueStrategy:    os.Getenv("SQS_QUEUE_STRATEGY"),
+		SQSQueueWeights:     parseIntMap("SQS_QUEUE_WEIGHTS"),
+		QueueMaxConcurrency: parseIntMap("QUEUE_MAX_CONCURRENCY"),
+		QueueReserved:       parseIntMap("QUEUE_RESERVED_WORKERS"),
+	}
+}
+
+func (c Config) PostgresConnString() string {
+	return "host=" + c.PGHost +
+		" port=" + c.PGPort +
+		" dbname=" + c.PGName +
+		" user=" + c.PGUser +
+		" password=" + c.PGPassword +
+		" sslmode=disable"
+}
@@ -1,14 +1,16 @@
-module yourproject
-
-go 1.22
-
-require (
-    github.com/aws/aws-sdk-go-v2 v1.16.0
-    github.com/aws/aws-sdk-go-v2/config v1.16.0
-    github.com/aws/aws-sdk-go-v2/service/sqs v1.16.0
-    github.com/go-git/go-git/v5 v5.4.2
-    github.com/go-git/go-git/v5/plumbing/transport/http v5.4.2
-    github.com/google/uuid v1.3.0
-    github.com/lib/pq v1.10.4
-    go.uber.org/zap v1.24.0
-)
+module yourproject
+
+go 1.22
+
+require (
+    github.com/aws/aws-sdk-go-v2 v1.32.7
+    github.com/aws/aws-sdk-go-v2/config v1.28.7
+    github.com/aws/aws-sdk-go-v2/service/sqs v1.37.3
+    github.com/go-git/go-git/v5 v5.4.2
+    github.com/go-git/go-git/v5/plumbing/transport/http v5.4.2
+    github.com/google/uuid v1.3.0
+    github.com/lib/pq v1.10.4
+    go.uber.org/zap v1.24.0
+    golang.org/x/crypto v0.31.0
+    golang.org/x/sync v0.10.0
+)
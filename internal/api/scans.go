@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"yourproject/internal/db"
+	"yourproject/internal/jobschema"
+	"yourproject/internal/logger"
+	"yourproject/internal/services"
+)
+
+const maxRequestBody = 64 * 1024
+
+// createScan converte o ScanJob para a versão atual e o valida, como nas
+// mensagens da fila, cria a linha em scans e o envia ao consumer. Sem
+// schema_version, o corpo é tratado como da versão atual.
+func (s *Server) createScan(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("erro ao ler o corpo: %v", err))
+		return
+	}
+	job, err := jobschema.DecodeWithDefaults(body, map[string]any{
+		"scan_id":        uuid.New().String(),
+		"schema_version": jobschema.CurrentVersion,
+	})
+	var validationErr *jobschema.ValidationError
+	if errors.As(err, &validationErr) {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if job.MessageCreatedAt.IsZero() {
+		job.MessageCreatedAt = time.Now().UTC()
+	}
+
+	err = s.Store.CreateScan(job, "queued")
+	if errors.Is(err, db.ErrScanExists) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("API: %v", err)
+		writeError(w, http.StatusInternalServerError, "erro ao registrar o scan")
+		return
+	}
+	if err := s.Queue.Enqueue(job); err != nil {
+		logger.Log.Errorf("API: erro ao enfileirar job %s: %v", job.ScanID, err)
+		if err := s.Store.UpdateScanStatus(job.ScanID, "error"); err != nil {
+			logger.Log.Errorf("API: %v", err)
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrQueueFull) {
+			status = http.StatusServiceUnavailable
+		}
+		writeError(w, status, "não foi possível enfileirar o scan")
+		return
+	}
+
+	logger.Log.Infof("API: scan %s enfileirado para o repositório %s", job.ScanID, job.RepositoryFullName)
+	writeJSON(w, http.StatusAccepted, map[string]string{"scan_id": job.ScanID, "status": "queued"})
+}
+
+func (s *Server) getScan(w http.ResponseWriter, r *http.Request) {
+	scan, err := s.Store.GetScan(r.PathValue("id"))
+	if errors.Is(err, db.ErrScanNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("API: %v", err)
+		writeError(w, http.StatusInternalServerError, "erro ao consultar o scan")
+		return
+	}
+	writeJSON(w, http.StatusOK, scan)
+}
+
+// cancelScan cancela um scan que ainda está na fila. Scans em execução são
+// interrompidos de forma assíncrona e a resposta é 202 com status "cancelling".
+func (s *Server) cancelScan(w http.ResponseWriter, r *http.Request) {
+	scanID := r.PathValue("id")
+	cancelled, err := s.Store.CancelQueuedScan(scanID)
+	if err != nil {
+		logger.Log.Errorf("API: %v", err)
+		writeError(w, http.StatusInternalServerError, "erro ao cancelar o scan")
+		return
+	}
+	if cancelled {
+		logger.Log.Infof("API: scan %s cancelado", scanID)
+		writeJSON(w, http.StatusOK, map[string]string{"scan_id": scanID, "status": "cancelled"})
+		return
+	}
+	if s.Canceller != nil {
+		cancelling, err := s.Canceller.Cancel(scanID)
+		if err != nil {
+			logger.Log.Errorf("API: %v", err)
+			writeError(w, http.StatusInternalServerError, "erro ao cancelar o scan")
+			return
+		}
+		if cancelling {
+			logger.Log.Infof("API: cancelamento do scan %s solicitado", scanID)
+			writeJSON(w, http.StatusAccepted, map[string]string{"scan_id": scanID, "status": "cancelling"})
+			return
+		}
+	}
+
+	scan, err := s.Store.GetScan(scanID)
+	if errors.Is(err, db.ErrScanNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("API: %v", err)
+		writeError(w, http.StatusInternalServerError, "erro ao consultar o scan")
+		return
+	}
+	writeError(w, http.StatusConflict, fmt.Sprintf("scan com status %q não pode ser cancelado", scan.Status))
+}
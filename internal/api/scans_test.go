@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"yourproject/internal/db"
+	"yourproject/models"
+)
+
+// fakeScanStore implementa só os métodos de db.ScanStore usados nos testes.
+type fakeScanStore struct {
+	db.ScanStore
+	scans map[string]bool
+}
+
+func (f *fakeScanStore) CreateScan(job *models.ScanJob, status string) error {
+	if f.scans[job.ScanID] {
+		return fmt.Errorf("%w: %s", db.ErrScanExists, job.ScanID)
+	}
+	f.scans[job.ScanID] = true
+	return nil
+}
+
+type fakeQueue struct {
+	jobs []*models.ScanJob
+}
+
+func (q *fakeQueue) Enqueue(job *models.ScanJob) error {
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+func TestCreateScan(t *testing.T) {
+	const body = `{"scan_id":"0b6f3c1e-5d2a-4c59-9d8e-2f3a4b5c6d7e","repository_id":"1","repository_full_name":"org/repo","sigla":"ABC"}`
+	tests := []struct {
+		name   string
+		auth   string
+		want   int
+		queued int
+	}{
+		{name: "sem token", want: http.StatusUnauthorized},
+		{name: "token inválido", auth: "Bearer outro", want: http.StatusUnauthorized},
+		{name: "token sem Bearer", auth: "segredo", want: http.StatusUnauthorized},
+		{name: "aceito", auth: "Bearer segredo", want: http.StatusAccepted, queued: 1},
+		{name: "scan_id repetido", auth: "Bearer segredo", want: http.StatusConflict, queued: 1},
+	}
+	queue := &fakeQueue{}
+	srv := &Server{Token: "segredo", Store: &fakeScanStore{scans: map[string]bool{}}, Queue: queue}
+	h := srv.Handler()
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/scans", strings.NewReader(body))
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, esperado %d (%s)", tt.name, rec.Code, tt.want, rec.Body.String())
+		}
+		if len(queue.jobs) != tt.queued {
+			t.Errorf("%s: %d jobs enfileirados, esperado %d", tt.name, len(queue.jobs), tt.queued)
+		}
+	}
+}
+
+func TestRequireTokenSemTokenConfigurado(t *testing.T) {
+	srv := &Server{Store: &fakeScanStore{scans: map[string]bool{}}, Queue: &fakeQueue{}}
+	req := httptest.NewRequest(http.MethodGet, "/scans/s1", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status %d, esperado %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestDebugVarsRequiresToken(t *testing.T) {
+	srv := &Server{Token: "segredo", Store: &fakeScanStore{scans: map[string]bool{}}, Queue: &fakeQueue{}}
+	h := srv.Handler()
+	tests := []struct {
+		auth string
+		want int
+	}{
+		{want: http.StatusUnauthorized},
+		{auth: "Bearer outro", want: http.StatusUnauthorized},
+		{auth: "Bearer segredo", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Authorization %q: status %d, esperado %d", tt.auth, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusOK && !strings.Contains(rec.Body.String(), "memstats") {
+			t.Errorf("resposta sem as variáveis do expvar: %.100s", rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"strings"
+	"time"
+
+	"yourproject/internal/db"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+// Enqueuer coloca um job no pipeline do consumer.
+type Enqueuer interface {
+	Enqueue(job *models.ScanJob) error
+}
+
+// Canceller interrompe scans em execução.
+type Canceller interface {
+	Cancel(scanID string) (bool, error)
+}
+
+// Server expõe a API HTTP de submissão e acompanhamento de scans.
+type Server struct {
+	Addr string
+	// Token é o segredo compartilhado exigido nas rotas /scans, enviado como
+	// "Authorization: Bearer <token>".
+	Token string
+	Store db.ScanStore
+	Queue Enqueuer
+	// Canceller, se definido, permite cancelar scans em execução.
+	Canceller Canceller
+	// WebhookSecret habilita POST /webhooks/github, validando X-Hub-Signature-256.
+	WebhookSecret string
+	Webhooks      db.WebhookStore
+}
+
+// Handler monta as rotas da API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /scans", s.requireToken(s.createScan))
+	mux.HandleFunc("GET /scans/{id}", s.requireToken(s.getScan))
+	mux.HandleFunc("DELETE /scans/{id}", s.requireToken(s.cancelScan))
+	// Os webhooks são autenticados pela assinatura HMAC do GitHub.
+	if s.WebhookSecret != "" {
+		mux.HandleFunc("POST /webhooks/github", s.githubWebhook)
+	}
+	// Métricas do pipeline (atraso da fila, jobs expirados) publicadas via expvar,
+	// com o mesmo token das rotas /scans: incluem memstats e a linha de comando.
+	mux.HandleFunc("GET /debug/vars", s.requireToken(expvar.Handler().ServeHTTP))
+	return mux
+}
+
+// Start sobe o servidor HTTP em background.
+func (s *Server) Start() *http.Server {
+	srv := &http.Server{
+		Addr:              s.Addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		logger.Log.Infof("API: escutando em %s", s.Addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Log.Errorf("API: servidor HTTP encerrado com erro: %v", err)
+		}
+	}()
+	return srv
+}
+
+// requireToken rejeita com 401 as requisições sem o token da API. Sem Token
+// configurado, todas as requisições são rejeitadas.
+func (s *Server) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scans"`)
+			writeError(w, http.StatusUnauthorized, "token da API ausente ou inválido")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Log.Errorf("API: erro ao escrever resposta: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"yourproject/internal/jobschema"
+	"yourproject/internal/logger"
+	"yourproject/internal/services"
+	"yourproject/models"
+)
+
+// maxWebhookBody é o tamanho máximo de payload enviado pelo GitHub.
+const maxWebhookBody = 25 << 20
+
+type githubRepository struct {
+	ID       int64  `json:"id"`
+	FullName string `json:"full_name"`
+	Size     int    `json:"size"` // Em KB.
+	Language string `json:"language"`
+}
+
+type githubPushEvent struct {
+	Ref        string           `json:"ref"`
+	Before     string           `json:"before"`
+	After      string           `json:"after"`
+	Deleted    bool             `json:"deleted"`
+	Repository githubRepository `json:"repository"`
+}
+
+type githubPullRequestEvent struct {
+	Action string `json:"action"`
+	// Before e After só vêm preenchidos em "synchronize" (novos commits no PR).
+	Before      string `json:"before"`
+	After       string `json:"after"`
+	PullRequest struct {
+		Number int `json:"number"`
+		Base   struct {
+			SHA string `json:"sha"`
+		} `json:"base"`
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	Repository githubRepository `json:"repository"`
+}
+
+// githubWebhook recebe eventos push e pull_request do GitHub e enfileira um scan
+// restrito aos commits enviados. Reentregas (mesmo X-GitHub-Delivery) retornam o
+// scan criado na primeira entrega. A sigla vem do parâmetro ?sigla= da URL
+// configurada no webhook ou, na falta dele, do último scan do repositório.
+func (s *Server) githubWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("erro ao ler payload: %v", err))
+		return
+	}
+	if !validSignature(s.WebhookSecret, body, r.Header.Get("X-Hub-Signature-256")) {
+		writeError(w, http.StatusUnauthorized, "assinatura inválida")
+		return
+	}
+	deliveryID := r.Header.Get("X-GitHub-Delivery")
+	if deliveryID == "" {
+		writeError(w, http.StatusBadRequest, "header X-GitHub-Delivery ausente")
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	var job *models.ScanJob
+	switch event {
+	case "ping":
+		writeJSON(w, http.StatusOK, map[string]string{"status": "pong"})
+		return
+	case "push":
+		job, err = jobFromPush(body)
+	case "pull_request":
+		job, err = jobFromPullRequest(body)
+	default:
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "ignored"})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if job == nil {
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "ignored"})
+		return
+	}
+
+	job.Sigla = strings.ToUpper(r.URL.Query().Get("sigla"))
+	if job.Sigla == "" {
+		if job.Sigla, err = s.Webhooks.LatestSigla(job.RepositoryID); err != nil {
+			logger.Log.Errorf("API: %v", err)
+			writeError(w, http.StatusInternalServerError, "erro ao consultar a sigla do repositório")
+			return
+		}
+	}
+	if err := jobschema.Validate(job); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	scanID, created, err := s.Webhooks.CreateWebhookScan(deliveryID, event, job)
+	if err != nil {
+		logger.Log.Errorf("API: %v", err)
+		writeError(w, http.StatusInternalServerError, "erro ao registrar o scan")
+		return
+	}
+	if !created {
+		logger.Log.Infof("API: entrega %s repetida; scan %s já registrado", deliveryID, scanID)
+		writeJSON(w, http.StatusOK, map[string]string{"scan_id": scanID, "status": "duplicate"})
+		return
+	}
+
+	if err := s.Queue.Enqueue(job); err != nil {
+		logger.Log.Errorf("API: erro ao enfileirar job %s: %v", job.ScanID, err)
+		if err := s.Store.UpdateScanStatus(job.ScanID, "error"); err != nil {
+			logger.Log.Errorf("API: %v", err)
+		}
+		// Libera a entrega para que a reentrega pelo GitHub crie um novo scan.
+		if err := s.Webhooks.ForgetDelivery(deliveryID); err != nil {
+			logger.Log.Errorf("API: %v", err)
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrQueueFull) {
+			status = http.StatusServiceUnavailable
+		}
+		writeError(w, status, "não foi possível enfileirar o scan")
+		return
+	}
+
+	logger.Log.Infof("API: scan %s enfileirado pelo evento %s (entrega %s) para %s %s..%s",
+		job.ScanID, event, deliveryID, job.RepositoryFullName, job.BeforeSHA, job.AfterSHA)
+	writeJSON(w, http.StatusAccepted, map[string]string{"scan_id": job.ScanID, "status": "queued"})
+}
+
+// validSignature confere o HMAC-SHA256 do payload no formato "sha256=<hex>".
+func validSignature(secret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// jobFromPush monta o job do intervalo before..after do push. Pushes que apagam
+// a branch não têm commits a escanear e retornam nil.
+func jobFromPush(body []byte) (*models.ScanJob, error) {
+	var ev githubPushEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return nil, fmt.Errorf("payload push inválido: %v", err)
+	}
+	if ev.Deleted {
+		return nil, nil
+	}
+	return newWebhookJob(ev.Repository, ev.Before, ev.After), nil
+}
+
+// jobFromPullRequest monta o job dos commits do PR. Em "synchronize" só os
+// commits novos são escaneados; em "opened" e "reopened", todos os do PR. As
+// demais ações retornam nil.
+func jobFromPullRequest(body []byte) (*models.ScanJob, error) {
+	var ev githubPullRequestEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return nil, fmt.Errorf("payload pull_request inválido: %v", err)
+	}
+	switch ev.Action {
+	case "synchronize":
+		return newWebhookJob(ev.Repository, ev.Before, ev.After), nil
+	case "opened", "reopened":
+		return newWebhookJob(ev.Repository, ev.PullRequest.Base.SHA, ev.PullRequest.Head.SHA), nil
+	}
+	return nil, nil
+}
+
+func newWebhookJob(repo githubRepository, before, after string) *models.ScanJob {
+	return &models.ScanJob{
+		SchemaVersion:      jobschema.CurrentVersion,
+		ScanID:             uuid.New().String(),
+		RepositoryID:       strconv.FormatInt(repo.ID, 10),
+		RepositoryFullName: repo.FullName,
+		RepositorySize:     repo.Size,
+		RepositoryLanguage: repo.Language,
+		MessageCreatedAt:   time.Now().UTC(),
+		BeforeSHA:          before,
+		AfterSHA:           after,
+	}
+}
@@ -0,0 +1,135 @@
+// Package backfill enfileira scans em massa em ritmo controlado, gravando o
+// progresso para que um backfill interrompido seja retomado de onde parou.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"yourproject/internal/db"
+	"yourproject/internal/jobschema"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+const (
+	// DefaultRate é o ritmo padrão, em jobs por segundo.
+	DefaultRate = 5.0
+	// MaxRate é o maior ritmo aceito, em jobs por segundo.
+	MaxRate = 1000.0
+
+	batchSize        = 500
+	progressInterval = 5 * time.Second
+)
+
+// ErrRunning indica que o backfill já está sendo executado em outro processo.
+var ErrRunning = errors.New("backfill já em execução em outro processo")
+
+// ValidateRate verifica se rate está entre zero (exclusive) e MaxRate.
+func ValidateRate(rate float64) error {
+	if !(rate > 0 && rate <= MaxRate) {
+		return fmt.Errorf("ritmo inválido %v: informe mais de 0 e até %v jobs por segundo", rate, MaxRate)
+	}
+	return nil
+}
+
+// Enqueuer coloca um job no pipeline de scans.
+type Enqueuer interface {
+	Enqueue(job *models.ScanJob) error
+}
+
+// Runner enfileira os itens pendentes de um backfill.
+type Runner struct {
+	Store db.BackfillStore
+	Queue Enqueuer
+	// Rate, se maior que zero, substitui e grava o ritmo do backfill (retomada com -rate).
+	Rate float64
+	// Progress, se definido, recebe o andamento a cada poucos segundos e ao final.
+	Progress func(b *models.Backfill)
+}
+
+// Run enfileira os itens pendentes de b a no máximo b.Rate jobs por segundo.
+// Cada item é marcado logo após o envio; se o processo parar entre o envio e a
+// marcação, a retomada reenvia o mesmo ScanID. Interrompido por ctx, o backfill
+// continua "running" e pode ser retomado.
+func (r *Runner) Run(ctx context.Context, b *models.Backfill) error {
+	rate := b.Rate
+	if r.Rate != 0 {
+		rate = r.Rate
+	} else if rate <= 0 {
+		rate = DefaultRate
+	}
+	if err := ValidateRate(rate); err != nil {
+		return err
+	}
+
+	unlock, ok, err := r.Store.TryBackfillLock(b.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRunning
+	}
+	defer unlock()
+
+	if rate != b.Rate {
+		if err := r.Store.SetBackfillRate(b.ID, rate); err != nil {
+			return err
+		}
+		b.Rate = rate
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+	lastReport := time.Now()
+	defer r.report(b)
+
+	for {
+		items, err := r.Store.ListPendingBackfillItems(b.ID, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			if err := r.Store.FinishBackfill(b.ID); err != nil {
+				return err
+			}
+			b.Status = "completed"
+			logger.Log.Infof("Backfill: %s (%s) concluído com %d jobs", b.Name, b.ID, b.Enqueued)
+			return nil
+		}
+
+		for _, item := range items {
+			select {
+			case <-ctx.Done():
+				logger.Log.Infof("Backfill: %s (%s) interrompido em %d/%d", b.Name, b.ID, b.Enqueued, b.Total)
+				return ctx.Err()
+			case <-ticker.C:
+			}
+
+			job := item.Job
+			job.SchemaVersion = jobschema.CurrentVersion
+			job.MessageCreatedAt = time.Now().UTC()
+			if err := r.Store.CreateBackfillScan(&job); err != nil {
+				return err
+			}
+			if err := r.Queue.Enqueue(&job); err != nil {
+				return err
+			}
+			if err := r.Store.MarkBackfillItemEnqueued(b.ID, item.Position); err != nil {
+				return err
+			}
+			b.Enqueued++
+			if time.Since(lastReport) >= progressInterval {
+				r.report(b)
+				lastReport = time.Now()
+			}
+		}
+	}
+}
+
+func (r *Runner) report(b *models.Backfill) {
+	if r.Progress != nil {
+		r.Progress(b)
+	}
+}
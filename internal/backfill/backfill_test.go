@@ -0,0 +1,160 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"yourproject/models"
+)
+
+type fakeStore struct {
+	items    []models.BackfillItem
+	enqueued map[int]bool
+	rate     float64
+	locked   bool
+	finished bool
+}
+
+func newFakeStore(names ...string) *fakeStore {
+	s := &fakeStore{enqueued: map[int]bool{}}
+	for i, n := range names {
+		s.items = append(s.items, models.BackfillItem{Position: i, Job: models.ScanJob{ScanID: n, RepositoryFullName: n}})
+	}
+	return s
+}
+
+func (s *fakeStore) CreateBackfill(b *models.Backfill, jobs []*models.ScanJob) error { return nil }
+func (s *fakeStore) GetBackfill(id string) (*models.Backfill, error)                 { return nil, nil }
+func (s *fakeStore) ListBackfills() ([]models.Backfill, error)                       { return nil, nil }
+func (s *fakeStore) CreateBackfillScan(job *models.ScanJob) error                    { return nil }
+func (s *fakeStore) FinishBackfill(id string) error                                  { s.finished = true; return nil }
+
+func (s *fakeStore) ListPendingBackfillItems(id string, limit int) ([]models.BackfillItem, error) {
+	var pending []models.BackfillItem
+	for _, it := range s.items {
+		if !s.enqueued[it.Position] && len(pending) < limit {
+			pending = append(pending, it)
+		}
+	}
+	return pending, nil
+}
+
+func (s *fakeStore) MarkBackfillItemEnqueued(id string, position int) error {
+	s.enqueued[position] = true
+	return nil
+}
+
+func (s *fakeStore) SetBackfillRate(id string, rate float64) error {
+	s.rate = rate
+	return nil
+}
+
+func (s *fakeStore) TryBackfillLock(id string) (func(), bool, error) {
+	return func() {}, !s.locked, nil
+}
+
+type fakeQueue struct {
+	jobs []string
+	fail string
+}
+
+func (q *fakeQueue) Enqueue(job *models.ScanJob) error {
+	if job.ScanID == q.fail {
+		return errors.New("fila indisponível")
+	}
+	q.jobs = append(q.jobs, job.ScanID)
+	return nil
+}
+
+func TestValidateRate(t *testing.T) {
+	tests := []struct {
+		rate    float64
+		wantErr bool
+	}{
+		{rate: 0.5},
+		{rate: DefaultRate},
+		{rate: MaxRate},
+		{rate: 0, wantErr: true},
+		{rate: -1, wantErr: true},
+		{rate: 2e9, wantErr: true},
+		{rate: math.NaN(), wantErr: true},
+		{rate: math.Inf(1), wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := ValidateRate(tt.rate); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRate(%v) = %v, esperado erro = %v", tt.rate, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRunnerRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		stored    float64 // Ritmo gravado no backfill.
+		override  float64 // Runner.Rate (retomada com -rate).
+		locked    bool
+		fail      string
+		wantErr   bool
+		wantJobs  int
+		wantRate  float64 // Ritmo gravado pelo Runner; 0 se não gravou.
+		wantFinal bool
+	}{
+		{name: "ritmo gravado", stored: MaxRate, wantJobs: 3, wantFinal: true},
+		{name: "retomada com -rate", stored: 1, override: MaxRate, wantJobs: 3, wantRate: MaxRate, wantFinal: true},
+		{name: "ritmo acima do máximo", stored: 2e9, wantErr: true},
+		{name: "-rate negativo", stored: 1, override: -1, wantErr: true},
+		{name: "em execução", stored: MaxRate, locked: true, wantErr: true},
+		{name: "falha no envio", stored: MaxRate, fail: "b", wantErr: true, wantJobs: 1},
+	}
+	for _, tt := range tests {
+		store := newFakeStore("a", "b", "c")
+		store.locked = tt.locked
+		queue := &fakeQueue{fail: tt.fail}
+		b := &models.Backfill{ID: "bf", Rate: tt.stored, Total: 3}
+		r := &Runner{Store: store, Queue: queue, Rate: tt.override}
+
+		err := r.Run(context.Background(), b)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: erro %v, esperado erro = %v", tt.name, err, tt.wantErr)
+		}
+		if len(queue.jobs) != tt.wantJobs || b.Enqueued != tt.wantJobs || len(store.enqueued) != tt.wantJobs {
+			t.Errorf("%s: %d enviados, %d contados, %d marcados; esperado %d",
+				tt.name, len(queue.jobs), b.Enqueued, len(store.enqueued), tt.wantJobs)
+		}
+		if store.rate != tt.wantRate {
+			t.Errorf("%s: ritmo gravado %v, esperado %v", tt.name, store.rate, tt.wantRate)
+		}
+		if store.finished != tt.wantFinal {
+			t.Errorf("%s: concluído = %v, esperado %v", tt.name, store.finished, tt.wantFinal)
+		}
+	}
+}
+
+func TestRunnerRunCancelled(t *testing.T) {
+	store := newFakeStore("a")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := (&Runner{Store: store, Queue: &fakeQueue{}}).Run(ctx, &models.Backfill{ID: "bf", Rate: 1})
+	if !errors.Is(err, context.Canceled) || store.finished || len(store.enqueued) != 0 {
+		t.Errorf("erro %v, concluído = %v, %d marcados; esperado interrupção sem envio", err, store.finished, len(store.enqueued))
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	jobs := []*models.ScanJob{
+		{RepositoryID: "1", RepositoryFullName: "org/a"},
+		{RepositoryID: "1", RepositoryFullName: "grupo/a", Provider: "gitlab"},
+		{RepositoryID: "1", RepositoryFullName: "org/a-repetido"},
+		{RepositoryID: "2", RepositoryFullName: "org/b", Provider: "github"},
+	}
+	var got []string
+	for _, job := range Dedupe(jobs) {
+		got = append(got, job.RepositoryFullName)
+	}
+	if strings.Join(got, ",") != "org/a,grupo/a,org/b" {
+		t.Errorf("Dedupe = %v, esperado org/a, grupo/a e org/b", got)
+	}
+}
@@ -0,0 +1,140 @@
+package backfill
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"yourproject/internal/jobschema"
+	"yourproject/models"
+)
+
+// csvColumns são as colunas aceitas no CSV; as três primeiras são obrigatórias.
+var csvColumns = []string{"repository_id", "repository_full_name", "sigla", "repository_size", "repository_language", "provider"}
+
+// LoadFile lê a lista de repositórios de um CSV (com cabeçalho) ou de um JSONL
+// com os campos do ScanJob. Cada linha ganha um ScanID e é validada; repositórios
+// repetidos são ignorados. Qualquer linha inválida aborta a leitura.
+func LoadFile(path string) ([]*models.ScanJob, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var jobs []*models.ScanJob
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		jobs, err = readCSV(f)
+	} else {
+		jobs, err = readJSONL(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return Dedupe(jobs), nil
+}
+
+// Dedupe remove repositórios repetidos, mantendo a primeira ocorrência.
+func Dedupe(jobs []*models.ScanJob) []*models.ScanJob {
+	seen := make(map[string]bool, len(jobs))
+	out := jobs[:0]
+	for _, job := range jobs {
+		if seen[job.RepositoryKey()] {
+			continue
+		}
+		seen[job.RepositoryKey()] = true
+		out = append(out, job)
+	}
+	return out
+}
+
+func readCSV(r io.Reader) ([]*models.ScanJob, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler cabeçalho do CSV: %v", err)
+	}
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvColumns[:3] {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("coluna obrigatória %s ausente no CSV", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := index[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var jobs []*models.ScanJob
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return jobs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler CSV: %v", err)
+		}
+		job := &models.ScanJob{
+			RepositoryID:       field(record, "repository_id"),
+			RepositoryFullName: field(record, "repository_full_name"),
+			Sigla:              strings.ToUpper(field(record, "sigla")),
+			RepositoryLanguage: field(record, "repository_language"),
+			Provider:           field(record, "provider"),
+		}
+		if size := field(record, "repository_size"); size != "" {
+			if job.RepositorySize, err = strconv.Atoi(size); err != nil {
+				return nil, fmt.Errorf("linha %d: repository_size inválido %q", line, size)
+			}
+		}
+		if err := prepare(job); err != nil {
+			return nil, fmt.Errorf("linha %d: %v", line, err)
+		}
+		jobs = append(jobs, job)
+	}
+}
+
+func readJSONL(r io.Reader) ([]*models.ScanJob, error) {
+	var jobs []*models.ScanJob
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		var job models.ScanJob
+		if err := json.Unmarshal([]byte(text), &job); err != nil {
+			return nil, fmt.Errorf("linha %d: JSON inválido: %v", line, err)
+		}
+		if err := prepare(&job); err != nil {
+			return nil, fmt.Errorf("linha %d: %v", line, err)
+		}
+		jobs = append(jobs, &job)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler JSONL: %v", err)
+	}
+	return jobs, nil
+}
+
+// prepare atribui um novo ScanID ao job e o valida no envelope atual.
+func prepare(job *models.ScanJob) error {
+	job.SchemaVersion = jobschema.CurrentVersion
+	job.ScanID = uuid.New().String()
+	job.MessageCreatedAt = time.Now().UTC()
+	return jobschema.Validate(job)
+}
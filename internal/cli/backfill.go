@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"yourproject/internal/backfill"
+	"yourproject/models"
+)
+
+// runBackfill implementa "backfill start|resume|status".
+func runBackfill(args []string, deps Deps) error {
+	if len(args) == 0 {
+		return fmt.Errorf("uso: backfill start|resume <id>|status [<id>]")
+	}
+	switch args[0] {
+	case "start":
+		return backfillStart(args[1:], deps)
+	case "resume":
+		return backfillResume(args[1:], deps)
+	case "status":
+		return backfillStatus(args[1:], deps)
+	default:
+		return fmt.Errorf("subcomando de backfill desconhecido: %s", args[0])
+	}
+}
+
+func backfillStart(args []string, deps Deps) error {
+	fs := flag.NewFlagSet("backfill start", flag.ContinueOnError)
+	name := fs.String("name", "", "nome do backfill (ex.: \"regras-2024-06\")")
+	file := fs.String("file", "", "lista de repositórios em CSV (com cabeçalho) ou JSONL")
+	discover := fs.String("discover", "", "padrão de nome dos repositórios da organização (ex.: \"*\" ou \"pix-*\")")
+	rate := fs.Float64("rate", backfill.DefaultRate, "jobs enfileirados por segundo")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *name == "" || (*file == "") == (*discover == "") {
+		return fmt.Errorf("uso: backfill start -name <nome> (-file <arquivo> | -discover <padrão>) [-rate <jobs/s>]")
+	}
+	if err := backfill.ValidateRate(*rate); err != nil {
+		return err
+	}
+	if deps.Queue == nil {
+		return fmt.Errorf("backfill requer ENABLE_SQS para enfileirar os jobs")
+	}
+
+	var (
+		jobs   []*models.ScanJob
+		source string
+		err    error
+	)
+	if *file != "" {
+		source = "file:" + *file
+		jobs, err = backfill.LoadFile(*file)
+	} else {
+		if deps.Discoverer == nil {
+			return fmt.Errorf("-discover requer GITHUB_ORG")
+		}
+		source = fmt.Sprintf("discover:%s/%s", deps.Discoverer.Org, *discover)
+		jobs, err = deps.Discoverer.Candidates(context.Background(), *discover)
+	}
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		return fmt.Errorf("nenhum repositório encontrado em %s", source)
+	}
+
+	b := &models.Backfill{Name: *name, Source: source, Rate: *rate}
+	if err := deps.Store.CreateBackfill(b, backfill.Dedupe(jobs)); err != nil {
+		return err
+	}
+	fmt.Fprintf(deps.Out, "backfill %s criado com %d repositórios\n", b.ID, b.Total)
+	return runBackfillItems(b, 0, deps)
+}
+
+func backfillResume(args []string, deps Deps) error {
+	fs := flag.NewFlagSet("backfill resume", flag.ContinueOnError)
+	rate := fs.Float64("rate", 0, "jobs enfileirados por segundo (padrão: o ritmo original)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("uso: backfill resume [-rate <jobs/s>] <id>")
+	}
+	if *rate != 0 {
+		if err := backfill.ValidateRate(*rate); err != nil {
+			return err
+		}
+	}
+	if deps.Queue == nil {
+		return fmt.Errorf("backfill requer ENABLE_SQS para enfileirar os jobs")
+	}
+	b, err := deps.Store.GetBackfill(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if b.Status == "completed" {
+		fmt.Fprintf(deps.Out, "backfill %s já concluído (%d/%d)\n", b.ID, b.Enqueued, b.Total)
+		return nil
+	}
+	fmt.Fprintf(deps.Out, "retomando backfill %s em %d/%d\n", b.ID, b.Enqueued, b.Total)
+	return runBackfillItems(b, *rate, deps)
+}
+
+// runBackfillItems enfileira os itens pendentes até o fim ou até SIGINT/SIGTERM,
+// após o que o backfill pode ser retomado com "backfill resume". rate, se
+// diferente de zero, substitui o ritmo gravado.
+func runBackfillItems(b *models.Backfill, rate float64, deps Deps) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runner := &backfill.Runner{
+		Store: deps.Store,
+		Queue: deps.Queue,
+		Rate:  rate,
+		Progress: func(b *models.Backfill) {
+			fmt.Fprintf(deps.Out, "backfill %s: %d/%d enfileirados\n", b.ID, b.Enqueued, b.Total)
+		},
+	}
+	err := runner.Run(ctx, b)
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintf(deps.Out, "interrompido; retome com: backfill resume %s\n", b.ID)
+		return nil
+	}
+	return err
+}
+
+func backfillStatus(args []string, deps Deps) error {
+	var backfills []models.Backfill
+	switch len(args) {
+	case 0:
+		var err error
+		if backfills, err = deps.Store.ListBackfills(); err != nil {
+			return err
+		}
+	case 1:
+		b, err := deps.Store.GetBackfill(args[0])
+		if err != nil {
+			return err
+		}
+		backfills = append(backfills, *b)
+	default:
+		return fmt.Errorf("uso: backfill status [<id>]")
+	}
+
+	w := tabwriter.NewWriter(deps.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNOME\tSTATUS\tPROGRESSO\tRITMO\tORIGEM\tATUALIZADO EM")
+	for _, b := range backfills {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%.1f/s\t%s\t%s\n",
+			b.ID, b.Name, b.Status, b.Enqueued, b.Total, b.Rate, b.Source, b.UpdatedAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"yourproject/internal/backfill"
+	"yourproject/internal/db"
+	"yourproject/internal/discovery"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// Deps agrupa as dependências usadas pelos subcomandos administrativos.
+type Deps struct {
+	Store    *db.RDSStore
+	SQS      *sqs.Client
+	QueueURL string
+	Out      io.Writer
+	// Queue envia jobs à fila SQS; nil quando ENABLE_SQS está desligado.
+	Queue backfill.Enqueuer
+	// Discoverer é nil quando GITHUB_ORG não está configurado.
+	Discoverer *discovery.Discoverer
+}
+
+// Run executa o subcomando indicado em args[0] (ex.: "quarantine list", "schedule add").
+func Run(args []string, deps Deps) error {
+	if deps.Out == nil {
+		deps.Out = os.Stdout
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("nenhum subcomando informado")
+	}
+	switch args[0] {
+	case "quarantine":
+		return runQuarantine(args[1:], deps)
+	case "schedule":
+		return runSchedule(args[1:], deps)
+	case "discover":
+		return runDiscover(args[1:], deps)
+	case "backfill":
+		return runBackfill(args[1:], deps)
+	default:
+		return fmt.Errorf("subcomando desconhecido: %s", args[0])
+	}
+}
+
+// parseFlags faz o parse de args e rejeita flags depois dos argumentos
+// posicionais: o pacote flag para no primeiro argumento que não é flag e
+// trataria "redrive <id> -queue <url>" como dois IDs a mais, ignorando a flag.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	for _, arg := range fs.Args() {
+		if len(arg) > 1 && strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("%s: flag %s informada após os argumentos; as flags devem vir antes", fs.Name(), arg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		args    []string
+		queue   string
+		ids     []string
+		wantErr bool
+	}{
+		{args: []string{"a", "b"}, ids: []string{"a", "b"}},
+		{args: []string{"-queue", "q", "a"}, queue: "q", ids: []string{"a"}},
+		{args: []string{"a", "-queue", "q"}, wantErr: true},
+		{args: []string{"a", "--all"}, wantErr: true},
+		{args: []string{"-queue", "q", "--", "-"}, queue: "q", ids: []string{"-"}},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("quarantine redrive", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		queue := fs.String("queue", "", "")
+		fs.Bool("all", false, "")
+
+		err := parseFlags(fs, tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseFlags(%v) erro = %v, esperado erro = %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if *queue != tt.queue || strings.Join(fs.Args(), ",") != strings.Join(tt.ids, ",") {
+			t.Errorf("parseFlags(%v) = queue %q, args %v; esperado %q, %v", tt.args, *queue, fs.Args(), tt.queue, tt.ids)
+		}
+	}
+}
+
+func TestBackfillRateFlag(t *testing.T) {
+	tests := []struct {
+		args    []string
+		resume  bool
+		wantErr string
+	}{
+		{args: []string{"-name", "n", "-file", "f", "-rate", "0"}, wantErr: "ritmo inválido"},
+		{args: []string{"-name", "n", "-file", "f", "-rate", "2e9"}, wantErr: "ritmo inválido"},
+		{args: []string{"-name", "n", "-file", "f", "-rate", "NaN"}, wantErr: "ritmo inválido"},
+		{args: []string{"-name", "n", "-file", "f", "-rate", "10"}, wantErr: "ENABLE_SQS"},
+		{args: []string{"-rate", "-1", "id"}, resume: true, wantErr: "ritmo inválido"},
+		{args: []string{"-rate", "0", "id"}, resume: true, wantErr: "ENABLE_SQS"},
+	}
+	for _, tt := range tests {
+		deps := Deps{Out: io.Discard}
+		var err error
+		if tt.resume {
+			err = backfillResume(tt.args, deps)
+		} else {
+			err = backfillStart(tt.args, deps)
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("backfill %v: erro %v, esperado %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"text/tabwriter"
+)
+
+// runDiscover implementa "discover [-dry-run]": lista os repositórios da
+// organização configurada e enfileira scans para os que mudaram.
+func runDiscover(args []string, deps Deps) error {
+	fs := flag.NewFlagSet("discover", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "apenas lista os jobs que seriam enfileirados")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if deps.Discoverer == nil {
+		return fmt.Errorf("discover requer GITHUB_ORG")
+	}
+	if deps.Discoverer.Queue == nil && !*dryRun {
+		return fmt.Errorf("discover requer ENABLE_SQS para enfileirar os jobs")
+	}
+
+	summary, err := deps.Discoverer.Run(context.Background(), *dryRun)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(deps.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SCAN ID\tSIGLA\tREPOSITÓRIO\tTAMANHO (KB)\tLINGUAGEM")
+	for _, job := range summary.Jobs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			job.ScanID, job.Sigla, job.RepositoryFullName, job.RepositorySize, job.RepositoryLanguage)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	verb := "enfileirados"
+	if *dryRun {
+		verb = "a enfileirar (dry-run)"
+	}
+	fmt.Fprintf(deps.Out, "%d repositórios em %s: %d %s, %d sem alteração, %d arquivados, %d sem sigla, %d falhas\n",
+		summary.Found, deps.Discoverer.Org, len(summary.Jobs), verb, summary.Unchanged, summary.Archived, summary.WithoutSigla, summary.Failed)
+	return nil
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"yourproject/internal/logger"
+	"yourproject/internal/services"
+	"yourproject/models"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// runQuarantine implementa "quarantine list|show|redrive".
+func runQuarantine(args []string, deps Deps) error {
+	if len(args) == 0 {
+		return fmt.Errorf("uso: quarantine list|show <id>|redrive <id>...|redrive -all")
+	}
+	switch args[0] {
+	case "list":
+		return quarantineList(args[1:], deps)
+	case "show":
+		return quarantineShow(args[1:], deps)
+	case "redrive":
+		return quarantineRedrive(args[1:], deps)
+	default:
+		return fmt.Errorf("subcomando de quarantine desconhecido: %s", args[0])
+	}
+}
+
+func quarantineList(args []string, deps Deps) error {
+	fs := flag.NewFlagSet("quarantine list", flag.ContinueOnError)
+	limit := fs.Int("limit", 50, "número máximo de registros")
+	all := fs.Bool("all", false, "inclui jobs já reenviados")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	entries, err := deps.Store.ListQuarantine(*limit, *all)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(deps.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSCAN\tTENTATIVAS\tCRIADO EM\tREENVIADO\tMOTIVO")
+	for _, e := range entries {
+		redriven := "-"
+		if e.RedrivenAt != nil {
+			redriven = e.RedrivenAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n",
+			e.ID, e.ScanID, e.Attempts, e.CreatedAt.Format("2006-01-02 15:04:05"), redriven, truncate(e.Reason, 80))
+	}
+	return w.Flush()
+}
+
+func quarantineShow(args []string, deps Deps) error {
+	if len(args) != 1 {
+		return fmt.Errorf("uso: quarantine show <id>")
+	}
+	entry, err := deps.Store.GetQuarantine(args[0])
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(deps.Out, string(out))
+	return nil
+}
+
+// quarantineRedrive reenvia o corpo original das mensagens para a fila e as marca como reenviadas.
+func quarantineRedrive(args []string, deps Deps) error {
+	fs := flag.NewFlagSet("quarantine redrive", flag.ContinueOnError)
+	all := fs.Bool("all", false, "reenvia todos os jobs pendentes na quarentena")
+	queueURL := fs.String("queue", "", "fila de destino (padrão: fila de origem da mensagem)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if deps.SQS == nil {
+		return fmt.Errorf("reenvio requer a SQS habilitada (ENABLE_SQS)")
+	}
+
+	var entries []models.QuarantinedJob
+	if *all {
+		pending, err := deps.Store.ListQuarantine(1000, false)
+		if err != nil {
+			return err
+		}
+		entries = pending
+	} else {
+		if fs.NArg() == 0 {
+			return fmt.Errorf("informe os IDs a reenviar ou use -all")
+		}
+		for _, id := range fs.Args() {
+			entry, err := deps.Store.GetQuarantine(id)
+			if err != nil {
+				return err
+			}
+			if entry.RedrivenAt != nil {
+				return fmt.Errorf("job em quarentena %s já foi reenviado em %s", id, entry.RedrivenAt.Format("2006-01-02 15:04:05"))
+			}
+			entries = append(entries, *entry)
+		}
+	}
+
+	for _, e := range entries {
+		target := *queueURL
+		if target == "" {
+			target = redriveTarget(e, deps.QueueURL)
+		}
+		input := &sqs.SendMessageInput{
+			QueueUrl:    &target,
+			MessageBody: &e.Body,
+		}
+		// Em filas FIFO o reenvio não pode ser deduplicado contra a mensagem original.
+		groupID, dedupID := redriveGroup(e)
+		if _, err := deps.SQS.SendMessage(context.Background(), services.WithFIFO(input, groupID, dedupID)); err != nil {
+			return fmt.Errorf("erro ao reenviar job em quarentena %s: %v", e.ID, err)
+		}
+		if err := deps.Store.MarkQuarantineRedriven(e.ID); err != nil {
+			return err
+		}
+		logger.Log.Infof("Quarentena: job %s reenviado para %s", e.ID, target)
+		fmt.Fprintf(deps.Out, "%s reenviado para %s\n", e.ID, target)
+	}
+	return nil
+}
+
+// redriveTarget usa a fila de origem quando ela é uma fila SQS; caso contrário, a fila padrão.
+func redriveTarget(e models.QuarantinedJob, defaultQueueURL string) string {
+	if strings.HasPrefix(e.Source, "https://") || strings.HasPrefix(e.Source, "http://") {
+		return e.Source
+	}
+	return defaultQueueURL
+}
+
+// redriveGroup deriva o grupo FIFO do repositório do job e uma deduplicação
+// própria do reenvio; corpos ilegíveis vão para um grupo único de quarentena.
+func redriveGroup(e models.QuarantinedJob) (string, string) {
+	groupID := "quarantine"
+	var job models.ScanJob
+	if err := json.Unmarshal([]byte(e.Body), &job); err == nil && services.MessageGroupID(&job) != "" {
+		groupID = services.MessageGroupID(&job)
+	}
+	return groupID, e.ID
+}
+
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
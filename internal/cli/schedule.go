@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"yourproject/internal/scheduler"
+	"yourproject/models"
+)
+
+// runSchedule implementa "schedule add|list|delete".
+func runSchedule(args []string, deps Deps) error {
+	if len(args) == 0 {
+		return fmt.Errorf("uso: schedule add|list|delete <id>")
+	}
+	switch args[0] {
+	case "add":
+		return scheduleAdd(args[1:], deps)
+	case "list":
+		return scheduleList(deps)
+	case "delete":
+		if len(args) != 2 {
+			return fmt.Errorf("uso: schedule delete <id>")
+		}
+		if err := deps.Store.DeleteSchedule(args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(deps.Out, "agendamento %s removido\n", args[1])
+		return nil
+	default:
+		return fmt.Errorf("subcomando de schedule desconhecido: %s", args[0])
+	}
+}
+
+func scheduleAdd(args []string, deps Deps) error {
+	fs := flag.NewFlagSet("schedule add", flag.ContinueOnError)
+	cronExpr := fs.String("cron", "", "expressão cron de 5 campos (ex.: \"0 3 * * *\")")
+	sigla := fs.String("sigla", "", "sigla dos repositórios")
+	repoID := fs.String("repo-id", "", "ID do repositório (omita para agendar toda a sigla)")
+	repoName := fs.String("repo", "", "nome completo do repositório (org/repo)")
+	jitter := fs.Duration("jitter", 0, "atraso aleatório máximo de cada job do disparo, sorteado por repositório (ex.: 10m; máximo 15m)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *cronExpr == "" || *sigla == "" {
+		return fmt.Errorf("-cron e -sigla são obrigatórios")
+	}
+	if (*repoID == "") != (*repoName == "") {
+		return fmt.Errorf("-repo-id e -repo devem ser informados juntos")
+	}
+	if *jitter < 0 || *jitter > scheduler.MaxJitter {
+		return fmt.Errorf("-jitter deve estar entre 0 e %s", scheduler.MaxJitter)
+	}
+
+	next, err := scheduler.NextRun(*cronExpr, time.Now())
+	if err != nil {
+		return err
+	}
+	sched := &models.ScanSchedule{
+		RepositoryID:       *repoID,
+		RepositoryFullName: *repoName,
+		Sigla:              *sigla,
+		CronExpr:           *cronExpr,
+		JitterSeconds:      int(jitter.Seconds()),
+		Enabled:            true,
+		NextRunAt:          next,
+	}
+	if err := deps.Store.InsertSchedule(sched); err != nil {
+		return err
+	}
+	fmt.Fprintf(deps.Out, "agendamento %s criado; próximo disparo em %s\n", sched.ID, next.Format(time.RFC3339))
+	return nil
+}
+
+func scheduleList(deps Deps) error {
+	schedules, err := deps.Store.ListSchedules()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(deps.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSIGLA\tREPOSITÓRIO\tCRON\tJITTER\tHABILITADO\tPRÓXIMO DISPARO")
+	for _, s := range schedules {
+		repo := s.RepositoryFullName
+		if repo == "" {
+			repo = "(todos da sigla)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\t%s\n",
+			s.ID, s.Sigla, repo, s.CronExpr, time.Duration(s.JitterSeconds)*time.Second, s.Enabled, s.NextRunAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,233 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+// ErrBackfillNotFound indica que não existe backfill com o ID informado.
+var ErrBackfillNotFound = errors.New("backfill não encontrado")
+
+// BackfillStore define as operações sobre backfills e seus itens.
+type BackfillStore interface {
+	CreateBackfill(b *models.Backfill, jobs []*models.ScanJob) error
+	GetBackfill(id string) (*models.Backfill, error)
+	ListBackfills() ([]models.Backfill, error)
+	ListPendingBackfillItems(id string, limit int) ([]models.BackfillItem, error)
+	CreateBackfillScan(job *models.ScanJob) error
+	MarkBackfillItemEnqueued(id string, position int) error
+	SetBackfillRate(id string, rate float64) error
+	FinishBackfill(id string) error
+	TryBackfillLock(id string) (unlock func(), ok bool, err error)
+}
+
+const backfillColumns = `b.id, b.name, b.source, b.rate, b.status, b.created_at, b.updated_at, b.finished_at,
+	(SELECT count(*) FROM backfill_items i WHERE i.backfill_id = b.id),
+	(SELECT count(*) FROM backfill_items i WHERE i.backfill_id = b.id AND i.enqueued_at IS NOT NULL)`
+
+// CreateBackfill grava o backfill e seus itens, na ordem de jobs, em uma transação.
+func (r *RDSStore) CreateBackfill(b *models.Backfill, jobs []*models.ScanJob) error {
+	start := time.Now()
+	defer logger.Trace("CreateBackfill", start)
+
+	if b.ID == "" {
+		b.ID = uuid.New().String()
+	}
+	b.Status = "running"
+	b.Total = len(jobs)
+	b.CreatedAt = time.Now()
+	b.UpdatedAt = b.CreatedAt
+
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação do backfill: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO backfills (id, name, source, rate, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+	`, b.ID, b.Name, b.Source, b.Rate, b.Status, b.CreatedAt); err != nil {
+		return fmt.Errorf("erro ao criar backfill %s: %v", b.Name, err)
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO backfill_items (
+			backfill_id, position, scan_id, repository_id, repository_full_name, sigla, repository_size, repository_language, provider
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''))
+	`)
+	if err != nil {
+		return fmt.Errorf("erro ao preparar itens do backfill %s: %v", b.Name, err)
+	}
+	defer stmt.Close()
+	for i, job := range jobs {
+		if _, err := stmt.ExecContext(ctx, b.ID, i, job.ScanID, job.RepositoryID, job.RepositoryFullName,
+			job.Sigla, job.RepositorySize, job.RepositoryLanguage, job.Provider); err != nil {
+			return fmt.Errorf("erro ao gravar item %s do backfill %s: %v", job.RepositoryFullName, b.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("erro ao confirmar transação do backfill: %v", err)
+	}
+	return nil
+}
+
+func (r *RDSStore) GetBackfill(id string) (*models.Backfill, error) {
+	start := time.Now()
+	defer logger.Trace("GetBackfill", start)
+
+	row := r.DB.QueryRowContext(context.Background(), `SELECT `+backfillColumns+` FROM backfills b WHERE b.id = $1`, id)
+	b, err := scanBackfill(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrBackfillNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar backfill %s: %v", id, err)
+	}
+	return b, nil
+}
+
+func (r *RDSStore) ListBackfills() ([]models.Backfill, error) {
+	start := time.Now()
+	defer logger.Trace("ListBackfills", start)
+
+	rows, err := r.DB.QueryContext(context.Background(), `SELECT `+backfillColumns+` FROM backfills b ORDER BY b.created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar backfills: %v", err)
+	}
+	defer rows.Close()
+
+	var backfills []models.Backfill
+	for rows.Next() {
+		b, err := scanBackfill(rows)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler backfill: %v", err)
+		}
+		backfills = append(backfills, *b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao listar backfills: %v", err)
+	}
+	return backfills, nil
+}
+
+func scanBackfill(row rowScanner) (*models.Backfill, error) {
+	var (
+		b          models.Backfill
+		finishedAt sql.NullTime
+	)
+	if err := row.Scan(&b.ID, &b.Name, &b.Source, &b.Rate, &b.Status, &b.CreatedAt, &b.UpdatedAt, &finishedAt,
+		&b.Total, &b.Enqueued); err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		b.FinishedAt = &finishedAt.Time
+	}
+	return &b, nil
+}
+
+// ListPendingBackfillItems retorna, em ordem, os próximos itens ainda não enfileirados.
+func (r *RDSStore) ListPendingBackfillItems(id string, limit int) ([]models.BackfillItem, error) {
+	start := time.Now()
+	defer logger.Trace("ListPendingBackfillItems", start)
+
+	query := `
+		SELECT position, scan_id, repository_id, repository_full_name, sigla, repository_size, repository_language,
+			COALESCE(provider, '')
+		FROM backfill_items
+		WHERE backfill_id = $1 AND enqueued_at IS NULL
+		ORDER BY position
+		LIMIT $2
+	`
+	rows, err := r.DB.QueryContext(context.Background(), query, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar itens do backfill %s: %v", id, err)
+	}
+	defer rows.Close()
+
+	var items []models.BackfillItem
+	for rows.Next() {
+		var it models.BackfillItem
+		if err := rows.Scan(&it.Position, &it.Job.ScanID, &it.Job.RepositoryID, &it.Job.RepositoryFullName,
+			&it.Job.Sigla, &it.Job.RepositorySize, &it.Job.RepositoryLanguage, &it.Job.Provider); err != nil {
+			return nil, fmt.Errorf("erro ao ler item do backfill %s: %v", id, err)
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao listar itens do backfill %s: %v", id, err)
+	}
+	return items, nil
+}
+
+// CreateBackfillScan cria a linha "queued" do scan de um item. Se ela já existe
+// (backfill retomado após enfileirar sem registrar), nada é alterado.
+func (r *RDSStore) CreateBackfillScan(job *models.ScanJob) error {
+	start := time.Now()
+	defer logger.Trace("CreateBackfillScan", start)
+
+	query := `
+		INSERT INTO scans (id, repository_id, repository_full_name, sigla, status, provider, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 'queued', NULLIF($5, ''), $6, $6)
+		ON CONFLICT (id) DO NOTHING
+	`
+	_, err := r.DB.ExecContext(context.Background(), query,
+		job.ScanID, job.RepositoryID, job.RepositoryFullName, job.Sigla, job.Provider, time.Now())
+	if err != nil {
+		return fmt.Errorf("erro ao criar scan %s: %v", job.ScanID, err)
+	}
+	return nil
+}
+
+func (r *RDSStore) MarkBackfillItemEnqueued(id string, position int) error {
+	start := time.Now()
+	defer logger.Trace("MarkBackfillItemEnqueued", start)
+
+	ctx := context.Background()
+	now := time.Now()
+	if _, err := r.DB.ExecContext(ctx,
+		`UPDATE backfill_items SET enqueued_at = $1 WHERE backfill_id = $2 AND position = $3`, now, id, position); err != nil {
+		return fmt.Errorf("erro ao registrar item %d do backfill %s: %v", position, id, err)
+	}
+	if _, err := r.DB.ExecContext(ctx, `UPDATE backfills SET updated_at = $1 WHERE id = $2`, now, id); err != nil {
+		return fmt.Errorf("erro ao atualizar backfill %s: %v", id, err)
+	}
+	return nil
+}
+
+// SetBackfillRate grava o novo ritmo de um backfill retomado com -rate.
+func (r *RDSStore) SetBackfillRate(id string, rate float64) error {
+	start := time.Now()
+	defer logger.Trace("SetBackfillRate", start)
+
+	query := `UPDATE backfills SET rate = $1, updated_at = $2 WHERE id = $3`
+	if _, err := r.DB.ExecContext(context.Background(), query, rate, time.Now(), id); err != nil {
+		return fmt.Errorf("erro ao atualizar ritmo do backfill %s: %v", id, err)
+	}
+	return nil
+}
+
+// FinishBackfill marca o backfill como concluído.
+func (r *RDSStore) FinishBackfill(id string) error {
+	start := time.Now()
+	defer logger.Trace("FinishBackfill", start)
+
+	query := `UPDATE backfills SET status = 'completed', updated_at = $1, finished_at = $1 WHERE id = $2`
+	if _, err := r.DB.ExecContext(context.Background(), query, time.Now(), id); err != nil {
+		return fmt.Errorf("erro ao concluir backfill %s: %v", id, err)
+	}
+	return nil
+}
+
+// TryBackfillLock impede que o mesmo backfill seja executado duas vezes ao mesmo tempo.
+func (r *RDSStore) TryBackfillLock(id string) (func(), bool, error) {
+	return r.tryAdvisoryLock("backfill:"+id, "do backfill "+id)
+}
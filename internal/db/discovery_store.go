@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+// DiscoveryStore define as operações da descoberta de repositórios por organização.
+type DiscoveryStore interface {
+	ListDiscoveredPushes() (map[string]time.Time, error)
+	MarkDiscovered(job *models.ScanJob, pushedAt time.Time) error
+	TryDiscoveryLock(org string) (unlock func(), ok bool, err error)
+}
+
+// ListDiscoveredPushes retorna, por repository_id, o último push já enfileirado.
+func (r *RDSStore) ListDiscoveredPushes() (map[string]time.Time, error) {
+	start := time.Now()
+	defer logger.Trace("ListDiscoveredPushes", start)
+
+	rows, err := r.DB.QueryContext(context.Background(), `SELECT repository_id, pushed_at FROM discovered_repositories`)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar repositórios descobertos: %v", err)
+	}
+	defer rows.Close()
+
+	pushes := make(map[string]time.Time)
+	for rows.Next() {
+		var (
+			id       string
+			pushedAt time.Time
+		)
+		if err := rows.Scan(&id, &pushedAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler repositório descoberto: %v", err)
+		}
+		pushes[id] = pushedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao listar repositórios descobertos: %v", err)
+	}
+	return pushes, nil
+}
+
+// MarkDiscovered registra o push enfileirado para o repositório do job.
+func (r *RDSStore) MarkDiscovered(job *models.ScanJob, pushedAt time.Time) error {
+	start := time.Now()
+	defer logger.Trace("MarkDiscovered", start)
+
+	query := `
+		INSERT INTO discovered_repositories (repository_id, repository_full_name, sigla, pushed_at, last_scan_id, enqueued_at)
+		VALUES ($1, $2, $3, $4, $5, now())
+		ON CONFLICT (repository_id) DO UPDATE
+		SET repository_full_name = EXCLUDED.repository_full_name,
+		    sigla = EXCLUDED.sigla,
+		    pushed_at = EXCLUDED.pushed_at,
+		    last_scan_id = EXCLUDED.last_scan_id,
+		    enqueued_at = EXCLUDED.enqueued_at
+	`
+	_, err := r.DB.ExecContext(context.Background(), query,
+		job.RepositoryID, job.RepositoryFullName, job.Sigla, pushedAt, job.ScanID)
+	if err != nil {
+		return fmt.Errorf("erro ao registrar repositório descoberto %s: %v", job.RepositoryFullName, err)
+	}
+	return nil
+}
+
+// TryDiscoveryLock garante que só uma réplica execute a descoberta da organização.
+func (r *RDSStore) TryDiscoveryLock(org string) (func(), bool, error) {
+	return r.tryAdvisoryLock("repo_discovery:"+org, "da descoberta de "+org)
+}
@@ -0,0 +1,310 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+// LeaseStore define as operações de lease por repositório usadas para coalescer jobs.
+type LeaseStore interface {
+	AcquireOrAttach(job *models.ScanJob, owner string, ttl time.Duration) (string, error)
+	RenewLease(job *models.ScanJob, ttl time.Duration) error
+	ReleaseLease(job *models.ScanJob, final bool) ([]string, error)
+	FollowerOutcome(job *models.ScanJob) (string, error)
+	DetachFollower(job *models.ScanJob, status string, event *models.ScanEvent) error
+}
+
+// AcquireOrAttach tenta obter o lease do repositório para o job. Retorna o ScanID
+// do líder: se for o do próprio job, ele deve rodar o scan; caso contrário, o job
+// foi anexado ao scan líder em andamento. Leases expirados (réplica morta) são
+// assumidos junto com os scans anexados a eles.
+func (r *RDSStore) AcquireOrAttach(job *models.ScanJob, owner string, ttl time.Duration) (string, error) {
+	start := time.Now()
+	defer logger.Trace("AcquireOrAttach", start)
+
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("erro ao iniciar transação do lease: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Serializa as decisões sobre o mesmo repositório entre réplicas.
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, job.LeaseKey()); err != nil {
+		return "", fmt.Errorf("erro ao bloquear repositório %s: %v", job.LeaseKey(), err)
+	}
+
+	var (
+		leader string
+		active bool
+	)
+	err = tx.QueryRowContext(ctx,
+		`SELECT leader_scan_id, expires_at > now() FROM repo_scan_leases WHERE repository_id = $1`,
+		job.LeaseKey()).Scan(&leader, &active)
+	expiresAt := time.Now().Add(ttl)
+
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO repo_scan_leases (repository_id, leader_scan_id, owner, acquired_at, expires_at)
+			VALUES ($1, $2, $3, now(), $4)
+		`, job.LeaseKey(), job.ScanID, owner, expiresAt); err != nil {
+			return "", fmt.Errorf("erro ao criar lease do repositório %s: %v", job.LeaseKey(), err)
+		}
+		if err := dropOwnFollower(ctx, tx, job.ScanID); err != nil {
+			return "", err
+		}
+		leader = job.ScanID
+
+	case err != nil:
+		return "", fmt.Errorf("erro ao ler lease do repositório %s: %v", job.LeaseKey(), err)
+
+	case active && leader != job.ScanID:
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO repo_scan_followers (scan_id, leader_scan_id, repository_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (scan_id) DO NOTHING
+		`, job.ScanID, leader, job.LeaseKey()); err != nil {
+			return "", fmt.Errorf("erro ao anexar scan %s ao scan %s: %v", job.ScanID, leader, err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE scans SET status = 'coalesced', coalesced_into = $1, updated_at = $2 WHERE id = $3`,
+			leader, time.Now(), job.ScanID); err != nil {
+			return "", fmt.Errorf("erro ao atualizar status do scan %s: %v", job.ScanID, err)
+		}
+
+	default:
+		// Lease expirado ou reentrega do próprio líder: assume o lease e os anexados.
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE repo_scan_leases
+			SET leader_scan_id = $1, owner = $2, acquired_at = now(), expires_at = $3
+			WHERE repository_id = $4
+		`, job.ScanID, owner, expiresAt, job.LeaseKey()); err != nil {
+			return "", fmt.Errorf("erro ao assumir lease do repositório %s: %v", job.LeaseKey(), err)
+		}
+		if leader != job.ScanID {
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE repo_scan_followers SET leader_scan_id = $1 WHERE leader_scan_id = $2`,
+				job.ScanID, leader); err != nil {
+				return "", fmt.Errorf("erro ao transferir scans anexados ao scan %s: %v", leader, err)
+			}
+		}
+		if err := dropOwnFollower(ctx, tx, job.ScanID); err != nil {
+			return "", err
+		}
+		leader = job.ScanID
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("erro ao confirmar transação do lease: %v", err)
+	}
+	return leader, nil
+}
+
+// RenewLease estende o lease enquanto o líder ainda está rodando.
+func (r *RDSStore) RenewLease(job *models.ScanJob, ttl time.Duration) error {
+	query := `UPDATE repo_scan_leases SET expires_at = $1 WHERE repository_id = $2 AND leader_scan_id = $3`
+	res, err := r.DB.ExecContext(context.Background(), query, time.Now().Add(ttl), job.LeaseKey(), job.ScanID)
+	if err != nil {
+		return fmt.Errorf("erro ao renovar lease do repositório %s: %v", job.LeaseKey(), err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("lease do repositório %s não pertence mais ao scan %s", job.LeaseKey(), job.ScanID)
+	}
+	return nil
+}
+
+// dropOwnFollower desfaz o anexo ainda pendente do job que acaba de se tornar
+// líder (ex.: o líder anterior morreu enquanto o job o aguardava).
+func dropOwnFollower(ctx context.Context, tx *sql.Tx, scanID string) error {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM repo_scan_followers WHERE scan_id = $1 AND outcome IS NULL`, scanID); err != nil {
+		return fmt.Errorf("erro ao desanexar scan %s: %v", scanID, err)
+	}
+	return nil
+}
+
+// ReleaseLease libera o lease do líder. Se o líder chegou a um status final
+// (sucesso, cancelamento ou falha permanente), os scans anexados recebem esse
+// status e uma cópia do seu evento na outbox, e o resultado fica registrado para
+// os jobs anexados que o aguardam (FollowerOutcome). Caso contrário eles são
+// desanexados e voltam a "queued", para disputar o lease de novo.
+// Retorna os ScanIDs concluídos ou desanexados junto com o líder.
+func (r *RDSStore) ReleaseLease(job *models.ScanJob, final bool) ([]string, error) {
+	start := time.Now()
+	defer logger.Trace("ReleaseLease", start)
+
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao iniciar transação do lease: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, job.LeaseKey()); err != nil {
+		return nil, fmt.Errorf("erro ao bloquear repositório %s: %v", job.LeaseKey(), err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM repo_scan_leases WHERE repository_id = $1 AND leader_scan_id = $2`,
+		job.LeaseKey(), job.ScanID); err != nil {
+		return nil, fmt.Errorf("erro ao liberar lease do repositório %s: %v", job.LeaseKey(), err)
+	}
+
+	followers, err := queryScanIDs(ctx, tx,
+		`SELECT scan_id FROM repo_scan_followers WHERE leader_scan_id = $1 AND outcome IS NULL`, job.ScanID)
+	if err != nil {
+		return nil, err
+	}
+	if len(followers) == 0 {
+		return nil, tx.Commit()
+	}
+
+	if !final {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM repo_scan_followers WHERE leader_scan_id = $1 AND outcome IS NULL`, job.ScanID); err != nil {
+			return nil, fmt.Errorf("erro ao desanexar scans do scan %s: %v", job.ScanID, err)
+		}
+		for _, id := range followers {
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE scans SET status = 'queued', coalesced_into = NULL, updated_at = $1 WHERE id = $2`,
+				time.Now(), id); err != nil {
+				return nil, fmt.Errorf("erro ao atualizar status do scan %s: %v", id, err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("erro ao confirmar transação do lease: %v", err)
+		}
+		return followers, nil
+	}
+
+	var status string
+	if err := tx.QueryRowContext(ctx, `SELECT status FROM scans WHERE id = $1`, job.ScanID).Scan(&status); err != nil {
+		return nil, fmt.Errorf("erro ao ler status do scan %s: %v", job.ScanID, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE repo_scan_followers SET outcome = $1 WHERE leader_scan_id = $2 AND outcome IS NULL`,
+		status, job.ScanID); err != nil {
+		return nil, fmt.Errorf("erro ao registrar resultado do scan %s nos anexados: %v", job.ScanID, err)
+	}
+	leaderEvent, err := latestOutboxEvent(ctx, tx, job.ScanID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range followers {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE scans SET status = $1, updated_at = $2 WHERE id = $3`, status, time.Now(), id); err != nil {
+			return nil, fmt.Errorf("erro ao atualizar status do scan %s: %v", id, err)
+		}
+		if leaderEvent != nil {
+			event := *leaderEvent
+			event.EventID = uuid.New().String()
+			event.ScanID = id
+			if err := insertOutboxEvent(ctx, tx, &event); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("erro ao confirmar transação do lease: %v", err)
+	}
+	return followers, nil
+}
+
+// FollowerOutcome retorna o status final do líder ao qual o job foi anexado e
+// desfaz o anexo, ou "" se o job não está anexado ou o líder ainda não terminou.
+func (r *RDSStore) FollowerOutcome(job *models.ScanJob) (string, error) {
+	var outcome string
+	err := r.DB.QueryRowContext(context.Background(),
+		`DELETE FROM repo_scan_followers WHERE scan_id = $1 AND outcome IS NOT NULL RETURNING outcome`,
+		job.ScanID).Scan(&outcome)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler resultado do scan anexado %s: %v", job.ScanID, err)
+	}
+	return outcome, nil
+}
+
+// DetachFollower desanexa o job interrompido enquanto aguardava o líder e grava
+// status no scan, com o evento na outbox se informado.
+func (r *RDSStore) DetachFollower(job *models.ScanJob, status string, event *models.ScanEvent) error {
+	start := time.Now()
+	defer logger.Trace("DetachFollower", start)
+
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação do lease: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM repo_scan_followers WHERE scan_id = $1`, job.ScanID); err != nil {
+		return fmt.Errorf("erro ao desanexar scan %s: %v", job.ScanID, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE scans SET status = $1, coalesced_into = NULL, updated_at = $2 WHERE id = $3`,
+		status, time.Now(), job.ScanID); err != nil {
+		return fmt.Errorf("erro ao atualizar status do scan %s: %v", job.ScanID, err)
+	}
+	if event != nil {
+		if err := insertOutboxEvent(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("erro ao confirmar transação do lease: %v", err)
+	}
+	return nil
+}
+
+// queryScanIDs executa query e retorna a coluna scan_id das linhas retornadas.
+func queryScanIDs(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler scans anexados: %v", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("erro ao ler scan anexado: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler scans anexados: %v", err)
+	}
+	return ids, nil
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// latestOutboxEvent retorna o evento mais recente do scan na outbox, se houver.
+func latestOutboxEvent(ctx context.Context, q queryRower, scanID string) (*models.ScanEvent, error) {
+	var payload []byte
+	err := q.QueryRowContext(ctx,
+		`SELECT payload FROM scan_events_outbox WHERE scan_id = $1 ORDER BY created_at DESC LIMIT 1`,
+		scanID).Scan(&payload)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler evento do scan %s: %v", scanID, err)
+	}
+	var event models.ScanEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("erro ao desserializar evento do scan %s: %v", scanID, err)
+	}
+	return &event, nil
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+// OutboxStore define as operações da outbox transacional de eventos.
+type OutboxStore interface {
+	CompleteScan(job *models.ScanJob, status string, findings []models.GitleaksFinding, event *models.ScanEvent) error
+	ProcessOutbox(limit int, publish func(models.OutboxEvent) error) (int, error)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// CompleteScan grava os achados, o status final e o evento de saída na mesma
+// transação: um evento nunca é emitido para dados que sofreram rollback. Um
+// scan concluído com sucesso perde a falha de clone de uma tentativa anterior.
+func (r *RDSStore) CompleteScan(job *models.ScanJob, status string, findings []models.GitleaksFinding, event *models.ScanEvent) error {
+	start := time.Now()
+	defer logger.Trace("CompleteScan", start)
+
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação do scan %s: %v", job.ScanID, err)
+	}
+	defer tx.Rollback()
+
+	for _, f := range findings {
+		if err := insertFinding(ctx, tx, job, f); err != nil {
+			return err
+		}
+	}
+	query := `
+		UPDATE scans SET status = $1, updated_at = $2,
+		       error_class = CASE WHEN $1 = 'success' THEN NULL ELSE error_class END,
+		       error_reason = CASE WHEN $1 = 'success' THEN NULL ELSE error_reason END
+		WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, status, time.Now(), job.ScanID); err != nil {
+		return fmt.Errorf("erro ao atualizar status do scan %s: %v", job.ScanID, err)
+	}
+	if event != nil {
+		if err := insertOutboxEvent(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("erro ao confirmar transação do scan %s: %v", job.ScanID, err)
+	}
+	return nil
+}
+
+// outboxClaimTTL é por quanto tempo os eventos lidos por ProcessOutbox ficam
+// reservados para a réplica; se ela morrer durante a publicação, os eventos
+// voltam a ser lidos depois desse prazo.
+const outboxClaimTTL = 5 * time.Minute
+
+const (
+	// outboxRetryBaseDelay é o atraso da nova tentativa após a primeira falha de
+	// publicação; dobra a cada falha até outboxMaxRetryDelay.
+	outboxRetryBaseDelay = 30 * time.Second
+	outboxMaxRetryDelay  = time.Hour
+	// outboxMaxAttempts é o número de publicações com falha após o qual o evento
+	// é marcado como falho (failed_at) e deixa de ser lido pelo relay.
+	outboxMaxAttempts = 10
+)
+
+// outboxRetryDelay calcula o backoff exponencial após attempts falhas.
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxRetryBaseDelay
+	for i := 1; i < attempts && delay < outboxMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxRetryDelay)
+}
+
+// ProcessOutbox reserva até limit eventos pendentes (SKIP LOCKED, seguro entre
+// réplicas), chama publish para cada um fora de transação e registra o
+// resultado. Um evento com falha só volta a ser lido após o backoff, sem
+// bloquear os mais novos, e após outboxMaxAttempts falhas é marcado como falho,
+// mantendo last_error. Retorna quantos eventos foram publicados.
+func (r *RDSStore) ProcessOutbox(limit int, publish func(models.OutboxEvent) error) (int, error) {
+	start := time.Now()
+	defer logger.Trace("ProcessOutbox", start)
+
+	events, err := r.claimOutbox(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	published := 0
+	for _, e := range events {
+		if pubErr := publish(e); pubErr != nil {
+			attempts := e.Attempts + 1
+			if attempts >= outboxMaxAttempts {
+				logger.Log.Errorf("Outbox: evento %s (%s) descartado após %d tentativas: %v", e.ID, e.EventType, attempts, pubErr)
+				if _, err := r.DB.ExecContext(ctx,
+					`UPDATE scan_events_outbox SET attempts = $1, last_error = $2, claimed_until = NULL, failed_at = $3 WHERE id = $4`,
+					attempts, pubErr.Error(), time.Now(), e.ID); err != nil {
+					return published, fmt.Errorf("erro ao registrar falha do evento %s: %v", e.ID, err)
+				}
+				continue
+			}
+			delay := outboxRetryDelay(attempts)
+			logger.Log.Warnf("Outbox: erro ao publicar evento %s (%s), nova tentativa em %s: %v", e.ID, e.EventType, delay, pubErr)
+			if _, err := r.DB.ExecContext(ctx,
+				`UPDATE scan_events_outbox SET attempts = $1, last_error = $2, claimed_until = $3 WHERE id = $4`,
+				attempts, pubErr.Error(), time.Now().Add(delay), e.ID); err != nil {
+				return published, fmt.Errorf("erro ao registrar falha do evento %s: %v", e.ID, err)
+			}
+			continue
+		}
+		if _, err := r.DB.ExecContext(ctx,
+			`UPDATE scan_events_outbox SET published_at = $1, attempts = attempts + 1, last_error = NULL, claimed_until = NULL WHERE id = $2`,
+			time.Now(), e.ID); err != nil {
+			return published, fmt.Errorf("erro ao marcar evento %s como publicado: %v", e.ID, err)
+		}
+		published++
+	}
+	return published, nil
+}
+
+// claimOutbox reserva por outboxClaimTTL até limit eventos pendentes, não
+// reservados e fora do backoff, em ordem de criação.
+func (r *RDSStore) claimOutbox(limit int) ([]models.OutboxEvent, error) {
+	rows, err := r.DB.QueryContext(context.Background(), `
+		UPDATE scan_events_outbox
+		SET claimed_until = now() + $2 * interval '1 second'
+		WHERE id IN (
+			SELECT id
+			FROM scan_events_outbox
+			WHERE published_at IS NULL AND failed_at IS NULL AND (claimed_until IS NULL OR claimed_until < now())
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_type, scan_id, payload, attempts, created_at
+	`, limit, outboxClaimTTL.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("erro ao reservar eventos pendentes da outbox: %v", err)
+	}
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var e models.OutboxEvent
+		if err := rows.Scan(&e.ID, &e.EventType, &e.ScanID, &e.Payload, &e.Attempts, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler evento da outbox: %v", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao reservar eventos pendentes da outbox: %v", err)
+	}
+	// RETURNING não garante a ordem do SELECT interno.
+	sort.Slice(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	return events, nil
+}
+
+func insertOutboxEvent(ctx context.Context, q execer, event *models.ScanEvent) error {
+	if event.EventID == "" {
+		event.EventID = uuid.New().String()
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar evento do scan %s: %v", event.ScanID, err)
+	}
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO scan_events_outbox (id, event_type, scan_id, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, event.EventID, event.Type, event.ScanID, payload, event.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar evento do scan %s na outbox: %v", event.ScanID, err)
+	}
+	return nil
+}
+
+// insertFinding é a versão transacional de RDSStore.InsertFinding.
+func insertFinding(ctx context.Context, q execer, job *models.ScanJob, finding models.GitleaksFinding) error {
+	query := `
+		INSERT INTO resultado_exploracao_credencial_exposta (
+			codigo_resultado_exploracao,
+			codigo_exploracao_credencial_exposta,
+			codigo_repositorio,
+			nome_divisao_repositorio,
+			nome_caminho_arquivo,
+			numero_linha_inicio,
+			nome_regra_credencial,
+			nome_unico_credencial,
+			data_hora_criacao_registro
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := q.ExecContext(ctx, query,
+		uuid.New().String(),
+		uuid.New().String(),
+		job.RepositoryID,
+		job.Sigla,
+		finding.File,
+		finding.StartLine,
+		finding.RuleID,
+		finding.Secret,
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("erro ao inserir achado: %v", err)
+	}
+	return nil
+}
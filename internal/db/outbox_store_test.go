@@ -0,0 +1,24 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutboxRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 4, want: 4 * time.Minute},
+		{attempts: 8, want: time.Hour},
+		{attempts: outboxMaxAttempts, want: time.Hour},
+	}
+	for _, tt := range tests {
+		if got := outboxRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("outboxRetryDelay(%d) = %s, esperado %s", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+// QuarantineStore define as operações sobre jobs em quarentena.
+type QuarantineStore interface {
+	InsertQuarantine(entry *models.QuarantinedJob) error
+	ListQuarantine(limit int, includeRedriven bool) ([]models.QuarantinedJob, error)
+	GetQuarantine(id string) (*models.QuarantinedJob, error)
+	MarkQuarantineRedriven(id string) error
+}
+
+func (r *RDSStore) InsertQuarantine(entry *models.QuarantinedJob) error {
+	start := time.Now()
+	defer logger.Trace("InsertQuarantine", start)
+
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	attrs, err := json.Marshal(entry.Attributes)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar atributos da mensagem: %v", err)
+	}
+
+	query := `
+		INSERT INTO scan_job_quarantine (
+			id, source, message_id, scan_id, body, attributes, reason, attempts, created_at
+		) VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9)
+	`
+	_, err = r.DB.ExecContext(context.Background(), query,
+		entry.ID,
+		entry.Source,
+		entry.MessageID,
+		entry.ScanID,
+		entry.Body,
+		attrs,
+		entry.Reason,
+		entry.Attempts,
+		entry.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("erro ao inserir job em quarentena: %v", err)
+	}
+	return nil
+}
+
+func (r *RDSStore) ListQuarantine(limit int, includeRedriven bool) ([]models.QuarantinedJob, error) {
+	start := time.Now()
+	defer logger.Trace("ListQuarantine", start)
+
+	query := `
+		SELECT id, source, message_id, COALESCE(scan_id, ''), body, attributes, reason, attempts, created_at, redriven_at
+		FROM scan_job_quarantine
+		WHERE $1 OR redriven_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.DB.QueryContext(context.Background(), query, includeRedriven, limit)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar quarentena: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []models.QuarantinedJob
+	for rows.Next() {
+		entry, err := scanQuarantine(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao listar quarentena: %v", err)
+	}
+	return entries, nil
+}
+
+func (r *RDSStore) GetQuarantine(id string) (*models.QuarantinedJob, error) {
+	start := time.Now()
+	defer logger.Trace("GetQuarantine", start)
+
+	query := `
+		SELECT id, source, message_id, COALESCE(scan_id, ''), body, attributes, reason, attempts, created_at, redriven_at
+		FROM scan_job_quarantine
+		WHERE id = $1
+	`
+	entry, err := scanQuarantine(r.DB.QueryRowContext(context.Background(), query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("job em quarentena %s não encontrado", id)
+	}
+	return entry, err
+}
+
+func (r *RDSStore) MarkQuarantineRedriven(id string) error {
+	start := time.Now()
+	defer logger.Trace("MarkQuarantineRedriven", start)
+
+	query := `UPDATE scan_job_quarantine SET redriven_at = $1 WHERE id = $2`
+	_, err := r.DB.ExecContext(context.Background(), query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("erro ao marcar job %s como reenviado: %v", id, err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanQuarantine(row rowScanner) (*models.QuarantinedJob, error) {
+	var (
+		entry      models.QuarantinedJob
+		attrs      []byte
+		redrivenAt sql.NullTime
+	)
+	err := row.Scan(
+		&entry.ID,
+		&entry.Source,
+		&entry.MessageID,
+		&entry.ScanID,
+		&entry.Body,
+		&attrs,
+		&entry.Reason,
+		&entry.Attempts,
+		&entry.CreatedAt,
+		&redrivenAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler job em quarentena: %v", err)
+	}
+	if err := json.Unmarshal(attrs, &entry.Attributes); err != nil {
+		return nil, fmt.Errorf("erro ao desserializar atributos da mensagem: %v", err)
+	}
+	if redrivenAt.Valid {
+		entry.RedrivenAt = &redrivenAt.Time
+	}
+	return &entry, nil
+}
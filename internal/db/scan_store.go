@@ -0,0 +1,264 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+var (
+	// ErrScanNotFound indica que não existe linha em scans para o ID informado.
+	ErrScanNotFound = errors.New("scan não encontrado")
+	// ErrScanExists indica que já existe um scan com o ID informado.
+	ErrScanExists = errors.New("scan já existe")
+)
+
+// uniqueViolation é o código do Postgres para violação de chave única.
+const uniqueViolation = "23505"
+
+// ScanStore define as operações sobre a tabela scans usadas pela API.
+type ScanStore interface {
+	CreateScan(job *models.ScanJob, status string) error
+	UpdateScanStatus(scanID, status string) error
+	GetScan(scanID string) (*models.Scan, error)
+	GetScanStatus(scanID string) (string, error)
+	CancelQueuedScan(scanID string) (bool, error)
+	RejectScan(scanID, reason string) error
+	ExpireScan(scanID, reason string) error
+	RequestCancel(scanID string) (bool, error)
+	ListCancelRequested(scanIDs []string) ([]string, error)
+	SetCloneStrategy(scanID, strategy string) error
+	SetCloneError(scanID, class, reason string) error
+}
+
+func (r *RDSStore) CreateScan(job *models.ScanJob, status string) error {
+	start := time.Now()
+	defer logger.Trace("CreateScan", start)
+
+	query := `
+		INSERT INTO scans (id, repository_id, repository_full_name, sigla, status, before_sha, after_sha, provider, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9, $9)
+	`
+	_, err := r.DB.ExecContext(context.Background(), query,
+		job.ScanID,
+		job.RepositoryID,
+		job.RepositoryFullName,
+		job.Sigla,
+		status,
+		job.BeforeSHA,
+		job.AfterSHA,
+		job.Provider,
+		time.Now(),
+	)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: %s", ErrScanExists, job.ScanID)
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao criar scan %s: %v", job.ScanID, err)
+	}
+	return nil
+}
+
+func (r *RDSStore) GetScan(scanID string) (*models.Scan, error) {
+	start := time.Now()
+	defer logger.Trace("GetScan", start)
+
+	query := `
+		SELECT id, COALESCE(repository_id, ''), COALESCE(repository_full_name, ''), COALESCE(sigla, ''),
+		       status, COALESCE(error_reason, ''), COALESCE(error_class, ''), COALESCE(before_sha, ''), COALESCE(after_sha, ''),
+		       COALESCE(clone_strategy, ''), COALESCE(provider, ''), created_at, updated_at
+		FROM scans
+		WHERE id = $1
+	`
+	var s models.Scan
+	err := r.DB.QueryRowContext(context.Background(), query, scanID).Scan(
+		&s.ID,
+		&s.RepositoryID,
+		&s.RepositoryFullName,
+		&s.Sigla,
+		&s.Status,
+		&s.ErrorReason,
+		&s.ErrorClass,
+		&s.BeforeSHA,
+		&s.AfterSHA,
+		&s.CloneStrategy,
+		&s.Provider,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrScanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar scan %s: %v", scanID, err)
+	}
+	return &s, nil
+}
+
+func (r *RDSStore) GetScanStatus(scanID string) (string, error) {
+	start := time.Now()
+	defer logger.Trace("GetScanStatus", start)
+
+	var status string
+	err := r.DB.QueryRowContext(context.Background(), `SELECT status FROM scans WHERE id = $1`, scanID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", ErrScanNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("erro ao buscar status do scan %s: %v", scanID, err)
+	}
+	return status, nil
+}
+
+// CancelQueuedScan marca como cancelado um scan que ainda não começou a rodar.
+// Retorna false se o scan não está mais na fila.
+func (r *RDSStore) CancelQueuedScan(scanID string) (bool, error) {
+	start := time.Now()
+	defer logger.Trace("CancelQueuedScan", start)
+
+	query := `UPDATE scans SET status = 'cancelled', updated_at = $1 WHERE id = $2 AND status = 'queued'`
+	res, err := r.DB.ExecContext(context.Background(), query, time.Now(), scanID)
+	if err != nil {
+		return false, fmt.Errorf("erro ao cancelar scan %s: %v", scanID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("erro ao cancelar scan %s: %v", scanID, err)
+	}
+	return n > 0, nil
+}
+
+// StartScan marca o scan como "running" se ele não foi cancelado. A checagem e
+// a mudança de status são um único UPDATE condicional, para que um
+// cancelamento concorrente não seja sobrescrito. Retorna false se o scan já
+// está cancelado; jobs sem linha em scans (enviados direto à fila) rodam.
+func (r *RDSStore) StartScan(scanID string) (bool, error) {
+	start := time.Now()
+	defer logger.Trace("StartScan", start)
+
+	query := `UPDATE scans SET status = 'running', updated_at = $1 WHERE id = $2 AND status <> 'cancelled'`
+	res, err := r.DB.ExecContext(context.Background(), query, time.Now(), scanID)
+	if err != nil {
+		return false, fmt.Errorf("erro ao iniciar scan %s: %v", scanID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("erro ao iniciar scan %s: %v", scanID, err)
+	}
+	if n > 0 {
+		return true, nil
+	}
+	status, err := r.GetScanStatus(scanID)
+	if errors.Is(err, ErrScanNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return status != "cancelled", nil
+}
+
+// RejectScan marca o scan como rejeitado e grava o motivo da rejeição. Jobs
+// enviados direto à fila não têm linha em scans; ela é criada para que a
+// rejeição possa ser consultada.
+func (r *RDSStore) RejectScan(scanID, reason string) error {
+	start := time.Now()
+	defer logger.Trace("RejectScan", start)
+
+	query := `
+		INSERT INTO scans (id, status, error_reason, created_at, updated_at)
+		VALUES ($1, 'rejected', $2, $3, $3)
+		ON CONFLICT (id) DO UPDATE SET status = 'rejected', error_reason = EXCLUDED.error_reason, updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.DB.ExecContext(context.Background(), query, scanID, reason, time.Now())
+	if err != nil {
+		return fmt.Errorf("erro ao rejeitar scan %s: %v", scanID, err)
+	}
+	return nil
+}
+
+// ExpireScan marca como expirado o scan de um job descartado por idade.
+func (r *RDSStore) ExpireScan(scanID, reason string) error {
+	start := time.Now()
+	defer logger.Trace("ExpireScan", start)
+
+	query := `UPDATE scans SET status = 'expired', error_reason = $1, updated_at = $2 WHERE id = $3`
+	_, err := r.DB.ExecContext(context.Background(), query, reason, time.Now(), scanID)
+	if err != nil {
+		return fmt.Errorf("erro ao expirar scan %s: %v", scanID, err)
+	}
+	return nil
+}
+
+// RequestCancel registra o pedido de cancelamento de um scan em execução.
+// Retorna false se o scan não está rodando.
+func (r *RDSStore) RequestCancel(scanID string) (bool, error) {
+	start := time.Now()
+	defer logger.Trace("RequestCancel", start)
+
+	query := `UPDATE scans SET cancel_requested_at = $1, updated_at = $1 WHERE id = $2 AND status = 'running'`
+	res, err := r.DB.ExecContext(context.Background(), query, time.Now(), scanID)
+	if err != nil {
+		return false, fmt.Errorf("erro ao pedir cancelamento do scan %s: %v", scanID, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("erro ao pedir cancelamento do scan %s: %v", scanID, err)
+	}
+	return n > 0, nil
+}
+
+// ListCancelRequested retorna, dentre os scans informados, os que têm pedido de cancelamento.
+func (r *RDSStore) ListCancelRequested(scanIDs []string) ([]string, error) {
+	query := `SELECT id FROM scans WHERE id = ANY($1) AND cancel_requested_at IS NOT NULL`
+	rows, err := r.DB.QueryContext(context.Background(), query, pq.Array(scanIDs))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar pedidos de cancelamento: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("erro ao ler pedido de cancelamento: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao consultar pedidos de cancelamento: %v", err)
+	}
+	return ids, nil
+}
+
+// SetCloneStrategy registra a estratégia usada no clone do scan, para auditoria.
+func (r *RDSStore) SetCloneStrategy(scanID, strategy string) error {
+	start := time.Now()
+	defer logger.Trace("SetCloneStrategy", start)
+
+	query := `UPDATE scans SET clone_strategy = $1, updated_at = $2 WHERE id = $3`
+	if _, err := r.DB.ExecContext(context.Background(), query, strategy, time.Now(), scanID); err != nil {
+		return fmt.Errorf("erro ao registrar estratégia de clone do scan %s: %v", scanID, err)
+	}
+	return nil
+}
+
+// SetCloneError registra a falha do clone do scan: a classe (timeout,
+// too_large, auth ou not_found; vazia se desconhecida) e a mensagem de erro.
+func (r *RDSStore) SetCloneError(scanID, class, reason string) error {
+	start := time.Now()
+	defer logger.Trace("SetCloneError", start)
+
+	query := `UPDATE scans SET error_class = NULLIF($1, ''), error_reason = $2, updated_at = $3 WHERE id = $4`
+	if _, err := r.DB.ExecContext(context.Background(), query, class, reason, time.Now(), scanID); err != nil {
+		return fmt.Errorf("erro ao registrar falha de clone do scan %s: %v", scanID, err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+// ScheduleStore define as operações sobre agendamentos recorrentes de scan.
+type ScheduleStore interface {
+	InsertSchedule(s *models.ScanSchedule) error
+	ListSchedules() ([]models.ScanSchedule, error)
+	DeleteSchedule(id string) error
+	ListDueSchedules(now time.Time, limit int) ([]models.ScanSchedule, error)
+	TryScheduleLock(id string) (unlock func(), ok bool, err error)
+	AdvanceSchedule(id string, prevNextRun, nextRun time.Time) (bool, error)
+	ListRepositoriesBySigla(sigla string) ([]models.ScanJob, error)
+}
+
+const scheduleColumns = `id, COALESCE(repository_id, ''), COALESCE(repository_full_name, ''), sigla,
+	cron_expr, jitter_seconds, enabled, next_run_at, last_run_at, created_at`
+
+func (r *RDSStore) InsertSchedule(s *models.ScanSchedule) error {
+	start := time.Now()
+	defer logger.Trace("InsertSchedule", start)
+
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	query := `
+		INSERT INTO scan_schedules (
+			id, repository_id, repository_full_name, sigla, cron_expr, jitter_seconds, enabled, next_run_at, created_at
+		) VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.DB.ExecContext(context.Background(), query,
+		s.ID, s.RepositoryID, s.RepositoryFullName, s.Sigla, s.CronExpr, s.JitterSeconds, s.Enabled, s.NextRunAt, time.Now())
+	if err != nil {
+		return fmt.Errorf("erro ao inserir agendamento: %v", err)
+	}
+	return nil
+}
+
+func (r *RDSStore) ListSchedules() ([]models.ScanSchedule, error) {
+	start := time.Now()
+	defer logger.Trace("ListSchedules", start)
+
+	return r.querySchedules(`SELECT ` + scheduleColumns + ` FROM scan_schedules ORDER BY sigla, repository_full_name`)
+}
+
+func (r *RDSStore) DeleteSchedule(id string) error {
+	start := time.Now()
+	defer logger.Trace("DeleteSchedule", start)
+
+	res, err := r.DB.ExecContext(context.Background(), `DELETE FROM scan_schedules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("erro ao remover agendamento %s: %v", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("agendamento %s não encontrado", id)
+	}
+	return nil
+}
+
+// ListDueSchedules retorna agendamentos habilitados cujo próximo disparo já passou.
+func (r *RDSStore) ListDueSchedules(now time.Time, limit int) ([]models.ScanSchedule, error) {
+	start := time.Now()
+	defer logger.Trace("ListDueSchedules", start)
+
+	return r.querySchedules(`SELECT `+scheduleColumns+`
+		FROM scan_schedules
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT $2`, now, limit)
+}
+
+// TryScheduleLock tenta obter o advisory lock do agendamento em uma conexão
+// dedicada, garantindo que só uma réplica dispare o agendamento. unlock libera
+// o lock e devolve a conexão ao pool.
+func (r *RDSStore) TryScheduleLock(id string) (func(), bool, error) {
+	return r.tryAdvisoryLock("scan_schedule:"+id, "do agendamento "+id)
+}
+
+// tryAdvisoryLock obtém pg_try_advisory_lock(hashtext(key)) em uma conexão
+// dedicada; what descreve o lock nas mensagens de erro.
+func (r *RDSStore) tryAdvisoryLock(key, what string) (func(), bool, error) {
+	ctx := context.Background()
+	conn, err := r.DB.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("erro ao obter conexão para o lock %s: %v", what, err)
+	}
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, key).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("erro ao obter lock %s: %v", what, err)
+	}
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+	unlock := func() {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, key); err != nil {
+			logger.Log.Errorf("Erro ao liberar lock %s: %v", what, err)
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
+
+// AdvanceSchedule move o próximo disparo apenas se ele ainda for prevNextRun,
+// evitando disparo duplo caso outra réplica já o tenha processado.
+func (r *RDSStore) AdvanceSchedule(id string, prevNextRun, nextRun time.Time) (bool, error) {
+	start := time.Now()
+	defer logger.Trace("AdvanceSchedule", start)
+
+	query := `UPDATE scan_schedules SET next_run_at = $1, last_run_at = $2 WHERE id = $3 AND next_run_at = $4`
+	res, err := r.DB.ExecContext(context.Background(), query, nextRun, time.Now(), id, prevNextRun)
+	if err != nil {
+		return false, fmt.Errorf("erro ao avançar agendamento %s: %v", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("erro ao avançar agendamento %s: %v", id, err)
+	}
+	return n > 0, nil
+}
+
+// ListRepositoriesBySigla retorna os repositórios já escaneados de uma sigla,
+// com os dados do scan mais recente de cada um.
+func (r *RDSStore) ListRepositoriesBySigla(sigla string) ([]models.ScanJob, error) {
+	start := time.Now()
+	defer logger.Trace("ListRepositoriesBySigla", start)
+
+	query := `
+		SELECT DISTINCT ON (repository_id) repository_id, repository_full_name, sigla
+		FROM scans
+		WHERE sigla = $1 AND repository_id IS NOT NULL AND repository_full_name IS NOT NULL
+		ORDER BY repository_id, created_at DESC
+	`
+	rows, err := r.DB.QueryContext(context.Background(), query, sigla)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar repositórios da sigla %s: %v", sigla, err)
+	}
+	defer rows.Close()
+
+	var repos []models.ScanJob
+	for rows.Next() {
+		var job models.ScanJob
+		if err := rows.Scan(&job.RepositoryID, &job.RepositoryFullName, &job.Sigla); err != nil {
+			return nil, fmt.Errorf("erro ao ler repositório da sigla %s: %v", sigla, err)
+		}
+		repos = append(repos, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao listar repositórios da sigla %s: %v", sigla, err)
+	}
+	return repos, nil
+}
+
+func (r *RDSStore) querySchedules(query string, args ...any) ([]models.ScanSchedule, error) {
+	rows, err := r.DB.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar agendamentos: %v", err)
+	}
+	defer rows.Close()
+
+	var schedules []models.ScanSchedule
+	for rows.Next() {
+		var (
+			s       models.ScanSchedule
+			lastRun sql.NullTime
+		)
+		if err := rows.Scan(&s.ID, &s.RepositoryID, &s.RepositoryFullName, &s.Sigla,
+			&s.CronExpr, &s.JitterSeconds, &s.Enabled, &s.NextRunAt, &lastRun, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler agendamento: %v", err)
+		}
+		if lastRun.Valid {
+			s.LastRunAt = &lastRun.Time
+		}
+		schedules = append(schedules, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao listar agendamentos: %v", err)
+	}
+	return schedules, nil
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+// WebhookStore define as operações usadas pelo receptor de webhooks do GitHub.
+type WebhookStore interface {
+	CreateWebhookScan(deliveryID, event string, job *models.ScanJob) (string, bool, error)
+	ForgetDelivery(deliveryID string) error
+	ForgetScanDelivery(scanID string) error
+	LatestSigla(repositoryID string) (string, error)
+}
+
+// CreateWebhookScan registra a entrega e cria o scan "queued" na mesma transação.
+// Se a entrega já foi recebida, nada é criado e o ScanID da primeira entrega é
+// retornado com created = false.
+func (r *RDSStore) CreateWebhookScan(deliveryID, event string, job *models.ScanJob) (string, bool, error) {
+	start := time.Now()
+	defer logger.Trace("CreateWebhookScan", start)
+
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, fmt.Errorf("erro ao iniciar transação da entrega %s: %v", deliveryID, err)
+	}
+	defer tx.Rollback()
+
+	// O scan precisa existir antes da entrega por causa da chave estrangeira;
+	// ambos são desfeitos juntos se a entrega for repetida.
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO scans (id, repository_id, repository_full_name, sigla, status, before_sha, after_sha, provider, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 'queued', NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $8)
+	`, job.ScanID, job.RepositoryID, job.RepositoryFullName, job.Sigla, job.BeforeSHA, job.AfterSHA, job.Provider, time.Now())
+	if err != nil {
+		return "", false, fmt.Errorf("erro ao criar scan %s: %v", job.ScanID, err)
+	}
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO github_webhook_deliveries (delivery_id, event, scan_id, received_at)
+		VALUES ($1, $2, $3, now())
+		ON CONFLICT (delivery_id) DO NOTHING
+	`, deliveryID, event, job.ScanID)
+	if err != nil {
+		return "", false, fmt.Errorf("erro ao registrar entrega %s: %v", deliveryID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var scanID string
+		err := tx.QueryRowContext(ctx,
+			`SELECT scan_id FROM github_webhook_deliveries WHERE delivery_id = $1`, deliveryID).Scan(&scanID)
+		if err != nil {
+			return "", false, fmt.Errorf("erro ao ler entrega %s: %v", deliveryID, err)
+		}
+		return scanID, false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", false, fmt.Errorf("erro ao confirmar transação da entrega %s: %v", deliveryID, err)
+	}
+	return job.ScanID, true, nil
+}
+
+// ForgetDelivery remove o registro da entrega para que uma reentrega seja aceita,
+// usado quando o scan criado não pôde ser enfileirado.
+func (r *RDSStore) ForgetDelivery(deliveryID string) error {
+	start := time.Now()
+	defer logger.Trace("ForgetDelivery", start)
+
+	_, err := r.DB.ExecContext(context.Background(),
+		`DELETE FROM github_webhook_deliveries WHERE delivery_id = $1`, deliveryID)
+	if err != nil {
+		return fmt.Errorf("erro ao remover entrega %s: %v", deliveryID, err)
+	}
+	return nil
+}
+
+// ForgetScanDelivery remove o registro da entrega que criou o scan, se houver,
+// usado quando o job do scan se perdeu no encerramento do serviço.
+func (r *RDSStore) ForgetScanDelivery(scanID string) error {
+	start := time.Now()
+	defer logger.Trace("ForgetScanDelivery", start)
+
+	_, err := r.DB.ExecContext(context.Background(),
+		`DELETE FROM github_webhook_deliveries WHERE scan_id = $1`, scanID)
+	if err != nil {
+		return fmt.Errorf("erro ao remover entrega do scan %s: %v", scanID, err)
+	}
+	return nil
+}
+
+// LatestSigla retorna a sigla do scan mais recente do repositório, ou "" se o
+// repositório nunca foi escaneado.
+func (r *RDSStore) LatestSigla(repositoryID string) (string, error) {
+	start := time.Now()
+	defer logger.Trace("LatestSigla", start)
+
+	query := `
+		SELECT sigla FROM scans
+		WHERE repository_id = $1 AND sigla IS NOT NULL AND sigla <> ''
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	var sigla string
+	err := r.DB.QueryRowContext(context.Background(), query, repositoryID).Scan(&sigla)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("erro ao buscar sigla do repositório %s: %v", repositoryID, err)
+	}
+	return sigla, nil
+}
@@ -0,0 +1,253 @@
+// Package discovery enumera os repositórios de uma organização do GitHub e
+// enfileira scans para os que mudaram desde a última descoberta.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"yourproject/internal/db"
+	"yourproject/internal/github"
+	"yourproject/internal/jobschema"
+	"yourproject/internal/logger"
+	"yourproject/internal/services"
+	"yourproject/models"
+)
+
+const (
+	defaultInterval = 6 * time.Hour
+	// runTimeout limita cada descoberta em background, que segura o advisory
+	// lock da organização: uma conexão travada não bloqueia as demais réplicas.
+	runTimeout = 30 * time.Minute
+)
+
+// ErrRunning indica que outra réplica está executando a descoberta da organização.
+var ErrRunning = errors.New("descoberta já em execução em outra réplica")
+
+// Enqueuer coloca um job no pipeline de scans.
+type Enqueuer interface {
+	Enqueue(job *models.ScanJob) error
+}
+
+// boundedQueue é implementado por filas com capacidade limitada, como a fila em
+// memória; a descoberta para quando ela enche em vez de criar scans que não
+// seriam enfileirados.
+type boundedQueue interface {
+	Full() bool
+}
+
+// ScanStore cria a linha em scans e resolve a sigla de repositórios já escaneados.
+type ScanStore interface {
+	CreateScan(job *models.ScanJob, status string) error
+	UpdateScanStatus(scanID, status string) error
+	LatestSigla(repositoryID string) (string, error)
+}
+
+// SiglaRule associa os repositórios cujo nome casa com Pattern (sintaxe de
+// path.Match, ex.: "pix-*") à Sigla.
+type SiglaRule struct {
+	Pattern string
+	Sigla   string
+}
+
+// Summary resume uma execução da descoberta.
+type Summary struct {
+	Found        int
+	Archived     int // Arquivados ou desabilitados.
+	Unchanged    int // Sem push desde o último enfileiramento.
+	WithoutSigla int
+	Failed       int
+	Deferred     int // Não avaliados porque a fila encheu; ficam para a próxima descoberta.
+	// Jobs enfileirados (ou que seriam, em dry-run).
+	Jobs []*models.ScanJob
+}
+
+// Discoverer lista os repositórios de Org e enfileira um scan por repositório
+// alterado. A sigla vem da primeira regra de Siglas que casar com o nome do
+// repositório ou, na falta dela, do último scan do repositório.
+type Discoverer struct {
+	GitHub   *github.Client
+	Org      string
+	Siglas   []SiglaRule
+	Store    db.DiscoveryStore
+	Scans    ScanStore
+	Queue    Enqueuer
+	Interval time.Duration
+}
+
+// Start executa a descoberta periodicamente em background.
+func (d *Discoverer) Start() {
+	if d.Interval <= 0 {
+		d.Interval = defaultInterval
+	}
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+			summary, err := d.Run(ctx, false)
+			cancel()
+			switch {
+			case errors.Is(err, ErrRunning):
+				logger.Log.Debugf("Discovery: %v", err)
+			case err != nil:
+				logger.Log.Errorf("Discovery: %v", err)
+			default:
+				logger.Log.Infof("Discovery: %s: %d repositórios, %d enfileirados, %d sem alteração, %d arquivados, %d sem sigla, %d falhas, %d adiados",
+					d.Org, summary.Found, len(summary.Jobs), summary.Unchanged, summary.Archived, summary.WithoutSigla, summary.Failed, summary.Deferred)
+			}
+			time.Sleep(d.Interval)
+		}
+	}()
+}
+
+// Run executa uma descoberta. Em dry-run nada é gravado nem enfileirado. Se a
+// fila enche, a descoberta para e os repositórios restantes ficam para a próxima.
+func (d *Discoverer) Run(ctx context.Context, dryRun bool) (*Summary, error) {
+	unlock, ok, err := d.Store.TryDiscoveryLock(d.Org)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrRunning
+	}
+	defer unlock()
+
+	repos, err := d.GitHub.ListOrgRepositories(ctx, d.Org)
+	if err != nil {
+		return nil, err
+	}
+	pushes, err := d.Store.ListDiscoveredPushes()
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{Found: len(repos)}
+	for i, repo := range repos {
+		repoID := strconv.FormatInt(repo.ID, 10)
+		if repo.Archived || repo.Disabled {
+			summary.Archived++
+			continue
+		}
+		if last, ok := pushes[repoID]; ok && !repo.PushedAt.After(last) {
+			summary.Unchanged++
+			continue
+		}
+		job, err := d.newJob(repo)
+		switch {
+		case err != nil:
+			logger.Log.Errorf("Discovery: %v", err)
+			summary.Failed++
+			continue
+		case job == nil:
+			summary.WithoutSigla++
+			continue
+		}
+		if !dryRun {
+			err := d.enqueue(job, repo.PushedAt)
+			if errors.Is(err, services.ErrQueueFull) {
+				summary.Deferred = len(repos) - i
+				logger.Log.Warnf("Discovery: fila cheia; %d repositórios ficam para a próxima descoberta", summary.Deferred)
+				break
+			}
+			if err != nil {
+				logger.Log.Errorf("Discovery: %v", err)
+				summary.Failed++
+				continue
+			}
+		}
+		summary.Jobs = append(summary.Jobs, job)
+	}
+	return summary, nil
+}
+
+// Candidates lista, como jobs, os repositórios ativos da organização cujo nome
+// casa com pattern (sintaxe de path.Match), mudados ou não desde a última
+// descoberta. Nada é gravado; usado para montar backfills.
+func (d *Discoverer) Candidates(ctx context.Context, pattern string) ([]*models.ScanJob, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("padrão de repositório inválido %q: %v", pattern, err)
+	}
+	repos, err := d.GitHub.ListOrgRepositories(ctx, d.Org)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []*models.ScanJob
+	for _, repo := range repos {
+		if repo.Archived || repo.Disabled {
+			continue
+		}
+		if ok, _ := path.Match(pattern, repo.Name); !ok {
+			continue
+		}
+		job, err := d.newJob(repo)
+		if err != nil {
+			logger.Log.Errorf("Discovery: %v", err)
+			continue
+		}
+		if job != nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// newJob monta e valida o job do repositório. Repositórios sem sigla mapeada
+// retornam nil sem erro.
+func (d *Discoverer) newJob(repo github.Repository) (*models.ScanJob, error) {
+	repoID := strconv.FormatInt(repo.ID, 10)
+	sigla, err := d.siglaFor(repoID, repo.Name)
+	if err != nil {
+		return nil, err
+	}
+	if sigla == "" {
+		logger.Log.Warnf("Discovery: repositório %s sem sigla mapeada; ignorado", repo.FullName)
+		return nil, nil
+	}
+	job := &models.ScanJob{
+		SchemaVersion:      jobschema.CurrentVersion,
+		ScanID:             uuid.New().String(),
+		RepositoryID:       repoID,
+		RepositoryFullName: repo.FullName,
+		RepositorySize:     repo.Size,
+		RepositoryLanguage: repo.Language,
+		Sigla:              sigla,
+		MessageCreatedAt:   time.Now().UTC(),
+	}
+	if err := jobschema.Validate(job); err != nil {
+		return nil, fmt.Errorf("repositório %s: %v", repo.FullName, err)
+	}
+	return job, nil
+}
+
+// enqueue cria o scan, envia o job e registra o push enfileirado. Se o envio
+// falhar, o push não é registrado e o repositório volta na próxima descoberta.
+// Com a fila já cheia, retorna services.ErrQueueFull sem criar o scan.
+func (d *Discoverer) enqueue(job *models.ScanJob, pushedAt time.Time) error {
+	if q, ok := d.Queue.(boundedQueue); ok && q.Full() {
+		return services.ErrQueueFull
+	}
+	if err := d.Scans.CreateScan(job, "queued"); err != nil {
+		return err
+	}
+	if err := d.Queue.Enqueue(job); err != nil {
+		if err := d.Scans.UpdateScanStatus(job.ScanID, "error"); err != nil {
+			logger.Log.Errorf("Discovery: %v", err)
+		}
+		return err
+	}
+	return d.Store.MarkDiscovered(job, pushedAt)
+}
+
+func (d *Discoverer) siglaFor(repoID, name string) (string, error) {
+	for _, rule := range d.Siglas {
+		if ok, _ := path.Match(rule.Pattern, name); ok {
+			return strings.ToUpper(rule.Sigla), nil
+		}
+	}
+	return d.Scans.LatestSigla(repoID)
+}
@@ -0,0 +1,245 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"yourproject/internal/github"
+	"yourproject/internal/services"
+	"yourproject/models"
+)
+
+var lastRun = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+// orgRepos são os repositórios da organização "acme" servidos em duas páginas.
+var orgRepos = [][]github.Repository{
+	{
+		{ID: 1, Name: "pix-api", FullName: "acme/pix-api", PushedAt: lastRun.Add(time.Hour)},
+		{ID: 2, Name: "pix-old", FullName: "acme/pix-old", Archived: true, PushedAt: lastRun.Add(time.Hour)},
+		{ID: 3, Name: "pix-lib", FullName: "acme/pix-lib", PushedAt: lastRun.Add(-time.Hour)},
+	},
+	{
+		{ID: 4, Name: "legado", FullName: "acme/legado", PushedAt: lastRun.Add(time.Hour)},
+		{ID: 5, Name: "sem-dono", FullName: "acme/sem-dono", PushedAt: lastRun.Add(time.Hour)},
+		{ID: 6, Name: "pix-quebrado", FullName: "acme/pix-quebrado", PushedAt: lastRun.Add(time.Hour)},
+	},
+}
+
+func newGitHub(t *testing.T) *github.Client {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/acme/repos" {
+			http.NotFound(w, r)
+			return
+		}
+		page := orgRepos[0]
+		if r.URL.Query().Get("page") == "2" {
+			page = orgRepos[1]
+		} else {
+			w.Header().Set("Link", `<`+srv.URL+`/orgs/acme/repos?page=2>; rel="next"`)
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(srv.Close)
+	return &github.Client{BaseURL: srv.URL}
+}
+
+type fakeStore struct {
+	locked     bool
+	pushes     map[string]time.Time
+	discovered []string
+}
+
+func (s *fakeStore) ListDiscoveredPushes() (map[string]time.Time, error) { return s.pushes, nil }
+
+func (s *fakeStore) MarkDiscovered(job *models.ScanJob, pushedAt time.Time) error {
+	s.discovered = append(s.discovered, job.RepositoryFullName)
+	return nil
+}
+
+func (s *fakeStore) TryDiscoveryLock(org string) (func(), bool, error) {
+	return func() {}, !s.locked, nil
+}
+
+type fakeScans struct {
+	status map[string]string // RepositoryFullName → status.
+	ids    map[string]string // ScanID → RepositoryFullName.
+}
+
+func (s *fakeScans) CreateScan(job *models.ScanJob, status string) error {
+	s.ids[job.ScanID] = job.RepositoryFullName
+	s.status[job.RepositoryFullName] = status
+	return nil
+}
+
+func (s *fakeScans) UpdateScanStatus(scanID, status string) error {
+	s.status[s.ids[scanID]] = status
+	return nil
+}
+
+func (s *fakeScans) LatestSigla(repositoryID string) (string, error) {
+	if repositoryID == "4" {
+		return "LEG", nil
+	}
+	return "", nil
+}
+
+type fakeQueue struct {
+	jobs     []*models.ScanJob
+	capacity int // 0 = sem limite.
+}
+
+func (q *fakeQueue) Enqueue(job *models.ScanJob) error {
+	if q.Full() {
+		return services.ErrQueueFull
+	}
+	if job.RepositoryFullName == "acme/pix-quebrado" {
+		return errors.New("fila indisponível")
+	}
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+func (q *fakeQueue) Full() bool { return q.capacity > 0 && len(q.jobs) >= q.capacity }
+
+func newDiscoverer(t *testing.T) (*Discoverer, *fakeStore, *fakeScans, *fakeQueue) {
+	store := &fakeStore{pushes: map[string]time.Time{"1": lastRun, "3": lastRun}}
+	scans := &fakeScans{status: map[string]string{}, ids: map[string]string{}}
+	queue := &fakeQueue{}
+	d := &Discoverer{
+		GitHub: newGitHub(t),
+		Org:    "acme",
+		Siglas: []SiglaRule{{Pattern: "pix-*", Sigla: "pix"}},
+		Store:  store,
+		Scans:  scans,
+		Queue:  queue,
+	}
+	return d, store, scans, queue
+}
+
+func names(jobs []*models.ScanJob) []string {
+	out := make([]string, len(jobs))
+	for i, j := range jobs {
+		out[i] = j.RepositoryFullName + "=" + j.Sigla
+	}
+	sort.Strings(out)
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRun(t *testing.T) {
+	d, store, scans, queue := newDiscoverer(t)
+	summary, err := d.Run(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Summary{Found: 6, Archived: 1, Unchanged: 1, WithoutSigla: 1, Failed: 1}
+	got := *summary
+	got.Jobs = nil
+	if got.Found != want.Found || got.Archived != want.Archived || got.Unchanged != want.Unchanged ||
+		got.WithoutSigla != want.WithoutSigla || got.Failed != want.Failed {
+		t.Errorf("resumo %+v, esperado %+v", got, want)
+	}
+	if jobs := names(summary.Jobs); !equal(jobs, []string{"acme/legado=LEG", "acme/pix-api=PIX"}) {
+		t.Errorf("jobs %v", jobs)
+	}
+	if jobs := names(queue.jobs); !equal(jobs, []string{"acme/legado=LEG", "acme/pix-api=PIX"}) {
+		t.Errorf("enfileirados %v", jobs)
+	}
+	sort.Strings(store.discovered)
+	if !equal(store.discovered, []string{"acme/legado", "acme/pix-api"}) {
+		t.Errorf("pushes registrados %v", store.discovered)
+	}
+	// A falha no envio não registra o push e marca o scan como "error".
+	if s := scans.status["acme/pix-quebrado"]; s != "error" {
+		t.Errorf("scan com falha no envio ficou %q, esperado error", s)
+	}
+}
+
+// Com a fila cheia a descoberta para sem criar scans; os repositórios restantes
+// não são registrados e voltam na próxima.
+func TestRunQueueFull(t *testing.T) {
+	d, store, scans, queue := newDiscoverer(t)
+	queue.capacity = 1
+	summary, err := d.Run(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobs := names(summary.Jobs); !equal(jobs, []string{"acme/pix-api=PIX"}) {
+		t.Errorf("jobs %v", jobs)
+	}
+	// legado, sem-dono e pix-quebrado ficam para a próxima descoberta.
+	if summary.Deferred != 3 || summary.Failed != 0 {
+		t.Errorf("%d adiados e %d falhas, esperado 3 e 0", summary.Deferred, summary.Failed)
+	}
+	if len(scans.status) != 1 || scans.status["acme/pix-api"] != "queued" {
+		t.Errorf("scans criados %v, esperado só acme/pix-api", scans.status)
+	}
+	if !equal(store.discovered, []string{"acme/pix-api"}) {
+		t.Errorf("pushes registrados %v", store.discovered)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	d, store, scans, queue := newDiscoverer(t)
+	summary, err := d.Run(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobs := names(summary.Jobs); !equal(jobs, []string{"acme/legado=LEG", "acme/pix-api=PIX", "acme/pix-quebrado=PIX"}) {
+		t.Errorf("jobs %v", jobs)
+	}
+	if len(queue.jobs) != 0 || len(scans.status) != 0 || len(store.discovered) != 0 {
+		t.Errorf("dry-run gravou ou enfileirou: %d jobs, %d scans, %d pushes", len(queue.jobs), len(scans.status), len(store.discovered))
+	}
+}
+
+func TestRunLocked(t *testing.T) {
+	d, store, _, _ := newDiscoverer(t)
+	store.locked = true
+	if _, err := d.Run(context.Background(), false); !errors.Is(err, ErrRunning) {
+		t.Errorf("erro %v, esperado ErrRunning", err)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	d, _, _, _ := newDiscoverer(t)
+	tests := []struct {
+		pattern string
+		want    []string
+		wantErr bool
+	}{
+		// Repositórios sem alteração também entram; arquivados não.
+		{pattern: "pix-*", want: []string{"acme/pix-api=PIX", "acme/pix-lib=PIX", "acme/pix-quebrado=PIX"}},
+		{pattern: "*", want: []string{"acme/legado=LEG", "acme/pix-api=PIX", "acme/pix-lib=PIX", "acme/pix-quebrado=PIX"}},
+		{pattern: "[", wantErr: true},
+	}
+	for _, tt := range tests {
+		jobs, err := d.Candidates(context.Background(), tt.pattern)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Candidates(%q): erro %v", tt.pattern, err)
+			continue
+		}
+		if got := names(jobs); !tt.wantErr && !equal(got, tt.want) {
+			t.Errorf("Candidates(%q) = %v, esperado %v", tt.pattern, got, tt.want)
+		}
+	}
+}
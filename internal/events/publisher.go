@@ -0,0 +1,69 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Publisher entrega um evento serializado ao destino configurado.
+type Publisher interface {
+	Publish(ctx context.Context, eventType string, payload []byte) error
+}
+
+// SQSPublisher publica eventos em uma fila SQS, com o tipo em um atributo da mensagem.
+type SQSPublisher struct {
+	Client   *sqs.Client
+	QueueURL string
+}
+
+func (p *SQSPublisher) Publish(ctx context.Context, eventType string, payload []byte) error {
+	_, err := p.Client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    &p.QueueURL,
+		MessageBody: aws.String(string(payload)),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"event_type": {DataType: aws.String("String"), StringValue: aws.String(eventType)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao publicar evento na SQS: %v", err)
+	}
+	return nil
+}
+
+// WebhookPublisher publica eventos via HTTP POST em um endpoint local ou interno.
+type WebhookPublisher struct {
+	URL    string
+	Client *http.Client
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, eventType string, payload []byte) error {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("erro ao montar requisição do webhook: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Type", eventType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao chamar webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook respondeu com status %d", resp.StatusCode)
+	}
+	return nil
+}
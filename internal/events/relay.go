@@ -0,0 +1,55 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"yourproject/internal/db"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+const (
+	defaultRelayInterval  = 5 * time.Second
+	defaultRelayBatchSize = 50
+)
+
+// OutboxRelay publica periodicamente os eventos pendentes da outbox.
+type OutboxRelay struct {
+	Store     db.OutboxStore
+	Publisher Publisher
+	Interval  time.Duration // Intervalo entre varreduras quando a outbox está vazia.
+	BatchSize int
+}
+
+// Start inicia o relay em background.
+func (r *OutboxRelay) Start() {
+	if r.Interval <= 0 {
+		r.Interval = defaultRelayInterval
+	}
+	if r.BatchSize <= 0 {
+		r.BatchSize = defaultRelayBatchSize
+	}
+	go func() {
+		for {
+			n, err := r.Store.ProcessOutbox(r.BatchSize, r.publish)
+			if err != nil {
+				logger.Log.Errorf("Outbox: erro ao processar eventos: %v", err)
+			}
+			// Lote cheio indica backlog: segue sem esperar.
+			if err != nil || n < r.BatchSize {
+				time.Sleep(r.Interval)
+			}
+		}
+	}()
+}
+
+func (r *OutboxRelay) publish(e models.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := r.Publisher.Publish(ctx, e.EventType, e.Payload); err != nil {
+		return err
+	}
+	logger.Log.Debugf("Outbox: evento %s (%s) do scan %s publicado", e.ID, e.EventType, e.ScanID)
+	return nil
+}
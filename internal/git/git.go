@@ -1,6 +1,77 @@
-package git
-
-// GitClient define a interface para operações de clonagem.
-type GitClient interface {
-	CloneRepo(repoURL string) (string, error)
-}
+package git
+
+import (
+	"context"
+	"fmt"
+
+	"yourproject/internal/vault"
+	"yourproject/models"
+)
+
+// GitClient define a interface para operações de clonagem.
+// O clone é abortado quando ctx é cancelado. Em caso de erro, Clone.Strategy
+// ainda informa a estratégia tentada.
+type GitClient interface {
+	CloneRepo(ctx context.Context, repoURL string, opts CloneOptions) (Clone, error)
+}
+
+// Clone é o resultado de CloneRepo.
+type Clone struct {
+	Dir      string
+	Strategy Strategy // Estratégia efetivamente usada.
+	// Env deve ser acrescentado ao ambiente dos processos que leem o clone com
+	// o git (ex.: gitleaks): no blobless leva a autenticação usada para baixar
+	// os blobs sob demanda, que não é gravada no repositório.
+	Env []string
+}
+
+// Strategy define quanto do repositório é baixado no clone.
+type Strategy string
+
+const (
+	// StrategyAuto escolhe a estratégia pelo tamanho do repositório.
+	StrategyAuto Strategy = ""
+	// StrategyFull clona todas as branches com o histórico completo.
+	StrategyFull Strategy = models.CloneStrategyFull
+	// StrategySingleBranch clona só a branch padrão, com o histórico completo.
+	StrategySingleBranch Strategy = models.CloneStrategySingleBranch
+	// StrategyShallow clona só os últimos commits da branch padrão.
+	StrategyShallow Strategy = models.CloneStrategyShallow
+	// StrategyBlobless clona todos os commits sem o conteúdo dos arquivos, que é
+	// baixado sob demanda pelo git quando o scanner lê os diffs (partial clone).
+	StrategyBlobless Strategy = models.CloneStrategyBlobless
+	// StrategyMirror copia o mirror do repositório mantido em cache, atualizado
+	// com fetch incremental. Não pode ser pedida pelo job: é usada no lugar de
+	// full quando o cache de mirrors está habilitado.
+	StrategyMirror Strategy = "mirror"
+)
+
+// ParseStrategy converte o nome da estratégia; "" e "auto" retornam StrategyAuto.
+func ParseStrategy(s string) (Strategy, error) {
+	if !models.ValidCloneStrategy(s) {
+		return "", fmt.Errorf("estratégia de clone desconhecida %q (use full, single-branch, shallow, blobless ou auto)", s)
+	}
+	if s == models.CloneStrategyAuto {
+		return StrategyAuto, nil
+	}
+	return Strategy(s), nil
+}
+
+// CloneOptions ajusta o clone de um repositório.
+type CloneOptions struct {
+	Strategy Strategy // StrategyAuto usa o padrão do cliente ou escolhe por SizeKB.
+	SizeKB   int      // Tamanho do repositório informado pelo GitHub; 0 se desconhecido.
+	// Dir é o diretório de destino, que não deve existir; vazio cria
+	// repo_<nanos> em os.TempDir().
+	Dir string
+	// RepositoryID identifica o mirror do repositório no cache; vazio não usa o cache.
+	RepositoryID string
+	// Credentials autenticam o clone HTTPS; nil usa as credenciais do GitHub do Vault.
+	Credentials *vault.Credentials
+	// SSHKey, se definida, autentica o clone por SSH; a URL deve ser SSH.
+	SSHKey *vault.SSHKey
+	// AllBranches indica que o scan precisa de commits fora da branch padrão
+	// (ex.: intervalo de um push ou pull request); a escolha automática não usa
+	// single-branch nem shallow nesse caso.
+	AllBranches bool
+}
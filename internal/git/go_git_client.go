@@ -1,41 +1,256 @@
-package git
-
-import (
-	"fmt"
-	"os"
-	"path/filepath"
-	"time"
-
-	git "github.com/go-git/go-git/v5"
-	httpAuth "github.com/go-git/go-git/v5/plumbing/transport/http"
-	"yourproject/internal/logger"
-	"yourproject/internal/vault"
-)
-
-type GoGitClient struct {
-	Vault vault.VaultClient
-}
-
-func (c *GoGitClient) CloneRepo(repoURL string) (string, error) {
-	start := time.Now()
-	defer logger.Trace("CloneRepo", start)
-
-	creds, err := c.Vault.GetGitHubCredentials()
-	if err != nil {
-		return "", fmt.Errorf("erro ao recuperar credenciais do GitHub: %v", err)
-	}
-
-	dir := filepath.Join(os.TempDir(), fmt.Sprintf("repo_%d", time.Now().UnixNano()))
-	_, err = git.PlainClone(dir, false, &git.CloneOptions{
-		URL: repoURL,
-		Auth: &httpAuth.BasicAuth{
-			Username: creds.Username,
-			Password: creds.Token,
-		},
-		Progress: os.Stdout,
-	})
-	if err != nil {
-		return "", fmt.Errorf("git clone falhou: %v", err)
-	}
-	return dir, nil
-}
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	httpAuth "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"yourproject/internal/logger"
+	"yourproject/internal/vault"
+)
+
+const defaultShallowDepth = 50
+
+type GoGitClient struct {
+	Vault vault.VaultClient
+
+	// DefaultStrategy é usada quando o job não define estratégia; StrategyAuto
+	// escolhe pelo tamanho do repositório.
+	DefaultStrategy Strategy
+	// BloblessMinSize é o tamanho (KB) a partir do qual a escolha automática usa
+	// blobless; 0 desabilita.
+	BloblessMinSize int
+	// ShallowMinSize é o tamanho (KB) a partir do qual a escolha automática usa
+	// shallow, escaneando só os commits recentes; 0 desabilita.
+	ShallowMinSize int
+	ShallowDepth   int    // Commits baixados no clone shallow; 0 usa 50.
+	GitPath        string // Binário do git usado no clone blobless e nos mirrors; vazio procura "git" no PATH.
+	// Mirrors, se definido, substitui os clones full pela cópia de um mirror em cache.
+	Mirrors *MirrorCache
+	// SSHInsecureIgnoreHostKey desliga a verificação da chave do servidor nos
+	// clones SSH. Por padrão o host precisa constar nos known_hosts.
+	SSHInsecureIgnoreHostKey bool
+	// CloneTimeout é o tempo limite base do clone, acrescido de
+	// CloneTimeoutPerGB por GB do repositório; 0 desabilita o limite.
+	CloneTimeout      time.Duration
+	CloneTimeoutPerGB time.Duration
+	// MaxCloneBytes aborta o clone que gravar mais que isso em disco (no mirror,
+	// o crescimento do mirror); 0 desabilita o limite.
+	MaxCloneBytes int64
+}
+
+func (c *GoGitClient) CloneRepo(ctx context.Context, repoURL string, opts CloneOptions) (Clone, error) {
+	start := time.Now()
+	defer logger.Trace("CloneRepo", start)
+
+	if c.MaxCloneBytes > 0 && int64(opts.SizeKB)*1024 > c.MaxCloneBytes {
+		return Clone{}, fmt.Errorf("%w: %d MB informados pelo provedor, limite de %d MB",
+			ErrCloneTooLarge, opts.SizeKB>>10, c.MaxCloneBytes>>20)
+	}
+
+	var (
+		err      error
+		auth     *httpAuth.BasicAuth
+		sshAuth  *gitssh.PublicKeys
+		strategy = c.strategyFor(opts)
+	)
+	if opts.SSHKey != nil {
+		if sshAuth, err = c.sshAuth(opts.SSHKey); err != nil {
+			return Clone{}, err
+		}
+		// Mirrors e partial clones usam o git, que não recebe a chave do Vault.
+		if strategy == StrategyBlobless {
+			logger.Log.Debugf("GitClient: clone blobless indisponível por SSH; %s será clonado por completo", repoURL)
+			strategy = StrategyFull
+		}
+	} else {
+		creds := opts.Credentials
+		if creds == nil {
+			if creds, err = c.Vault.GetGitHubCredentials(); err != nil {
+				return Clone{}, fmt.Errorf("erro ao recuperar credenciais do GitHub: %v", err)
+			}
+		}
+		auth = &httpAuth.BasicAuth{
+			Username: creds.Username,
+			Password: creds.Token,
+		}
+	}
+
+	useMirror := strategy == StrategyFull && c.Mirrors != nil && opts.RepositoryID != "" && sshAuth == nil
+	var gitPath string
+	if strategy == StrategyBlobless || useMirror {
+		if gitPath, err = exec.LookPath(c.gitBinary()); err != nil {
+			// O go-git não suporta partial clone nem mirrors; sem o git instalado o clone é completo.
+			logger.Log.Warnf("GitClient: git não encontrado (%v); %s será clonado por completo com o go-git", err, repoURL)
+			strategy = StrategyFull
+		} else if useMirror {
+			strategy = StrategyMirror
+		}
+	}
+	logger.Log.Debugf("GitClient: clonando %s com a estratégia %s", repoURL, strategy)
+
+	dir := opts.Dir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("repo_%d", time.Now().UnixNano()))
+	}
+
+	// O clone roda em um contexto próprio, encerrado pelo tempo limite ou pelo
+	// limite de bytes, para que uma conexão travada não prenda o worker.
+	cloneCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if timeout := c.cloneTimeout(opts.SizeKB); timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		cloneCtx, cancelTimeout = context.WithTimeoutCause(cloneCtx, timeout, fmt.Errorf("%w (%s)", ErrCloneTimeout, timeout))
+		defer cancelTimeout()
+	}
+	// O mirror é medido pelo próprio cache, depois que o lock do repositório é obtido.
+	if c.MaxCloneBytes > 0 && strategy != StrategyMirror {
+		go watchSize(cloneCtx, cancel, c.MaxCloneBytes, dir)
+	}
+
+	switch strategy {
+	case StrategyMirror:
+		err = c.Mirrors.Checkout(cloneCtx, gitPath, opts.RepositoryID, repoURL, dir, auth, c.MaxCloneBytes, cancel)
+	case StrategyBlobless:
+		err = runGit(cloneCtx, gitPath, auth, "clone", "--quiet", "--no-checkout", "--filter=blob:none", repoURL, dir)
+	default:
+		cloneOpts := &git.CloneOptions{
+			URL:      repoURL,
+			Auth:     auth,
+			Progress: os.Stdout,
+		}
+		if sshAuth != nil {
+			cloneOpts.Auth = sshAuth
+		}
+		switch strategy {
+		case StrategySingleBranch:
+			cloneOpts.SingleBranch = true
+		case StrategyShallow:
+			cloneOpts.SingleBranch = true
+			cloneOpts.Depth = c.ShallowDepth
+			if cloneOpts.Depth <= 0 {
+				cloneOpts.Depth = defaultShallowDepth
+			}
+		}
+		_, err = git.PlainCloneContext(cloneCtx, dir, false, cloneOpts)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		if ctx.Err() != nil {
+			return Clone{Strategy: strategy}, fmt.Errorf("git clone interrompido: %w", context.Cause(ctx))
+		}
+		if cloneCtx.Err() != nil {
+			return Clone{Strategy: strategy}, fmt.Errorf("git clone (%s) abortado: %w", strategy, context.Cause(cloneCtx))
+		}
+		if class := classify(err); class != nil {
+			return Clone{Strategy: strategy}, fmt.Errorf("git clone (%s) falhou: %w: %v", strategy, class, err)
+		}
+		return Clone{Strategy: strategy}, fmt.Errorf("git clone (%s) falhou: %w", strategy, err)
+	}
+	clone := Clone{Dir: dir, Strategy: strategy}
+	if strategy == StrategyBlobless {
+		// Sem checkout, o scanner lê o histórico e o git baixa os blobs dos diffs
+		// sob demanda, com a mesma autenticação do clone.
+		clone.Env = gitEnv(auth)
+	}
+	return clone, nil
+}
+
+// strategyFor resolve a estratégia do clone: a do job, a padrão do cliente ou,
+// em StrategyAuto, a escolhida pelo tamanho do repositório. Com AllBranches,
+// single-branch e shallow nunca são usadas, mesmo quando pedidas.
+func (c *GoGitClient) strategyFor(opts CloneOptions) Strategy {
+	strategy := opts.Strategy
+	if strategy == StrategyAuto {
+		strategy = c.DefaultStrategy
+	}
+	if opts.AllBranches && (strategy == StrategySingleBranch || strategy == StrategyShallow) {
+		logger.Log.Debugf("GitClient: estratégia %s ignorada; o scan precisa de commits fora da branch padrão", strategy)
+		strategy = StrategyAuto
+	}
+	if strategy != StrategyAuto {
+		return strategy
+	}
+	switch {
+	case c.ShallowMinSize > 0 && opts.SizeKB >= c.ShallowMinSize && !opts.AllBranches:
+		return StrategyShallow
+	case c.BloblessMinSize > 0 && opts.SizeKB >= c.BloblessMinSize:
+		return StrategyBlobless
+	}
+	return StrategyFull
+}
+
+func (c *GoGitClient) gitBinary() string {
+	if c.GitPath == "" {
+		return "git"
+	}
+	return c.GitPath
+}
+
+// sshAuth monta a autenticação SSH com a chave do Vault. A chave do servidor é
+// verificada com os known_hosts da chave ou, sem eles, com os do sistema.
+func (c *GoGitClient) sshAuth(key *vault.SSHKey) (*gitssh.PublicKeys, error) {
+	auth, err := gitssh.NewPublicKeys("git", key.PrivateKey, key.Passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("chave SSH inválida: %v", err)
+	}
+	if c.SSHInsecureIgnoreHostKey {
+		auth.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+		return auth, nil
+	}
+
+	var files []string
+	if len(key.KnownHosts) > 0 {
+		// knownhosts só lê arquivos; o conteúdo é carregado na criação do callback.
+		f, err := os.CreateTemp("", "known_hosts_")
+		if err != nil {
+			return nil, fmt.Errorf("erro ao gravar known_hosts: %v", err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(key.KnownHosts)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro ao gravar known_hosts: %v", err)
+		}
+		files = append(files, f.Name())
+	}
+	callback, err := gitssh.NewKnownHostsCallback(files...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao carregar known_hosts: %v", err)
+	}
+	auth.HostKeyCallback = callback
+	return auth, nil
+}
+
+// runGit executa o git com args. A autenticação vai pelo ambiente, como header
+// HTTP, para não aparecer na linha de comando nem ser gravada no repositório.
+func runGit(ctx context.Context, gitPath string, auth *httpAuth.BasicAuth, args ...string) error {
+	cmd := exec.CommandContext(ctx, gitPath, args...)
+	cmd.Env = append(os.Environ(), gitEnv(auth)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// gitEnv retorna as variáveis de ambiente que desligam o prompt de senha do git
+// e, com auth, enviam a autenticação como header HTTP.
+func gitEnv(auth *httpAuth.BasicAuth) []string {
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	if auth == nil || auth.Password == "" {
+		return env
+	}
+	header := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password))
+	return append(env, "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0="+header)
+}
@@ -0,0 +1,171 @@
+package git
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"yourproject/internal/vault"
+)
+
+func TestStrategyFor(t *testing.T) {
+	c := &GoGitClient{ShallowMinSize: 1000, BloblessMinSize: 500}
+	tests := []struct {
+		name   string
+		client Strategy // DefaultStrategy do cliente.
+		opts   CloneOptions
+		want   Strategy
+	}{
+		{name: "pequeno", opts: CloneOptions{SizeKB: 10}, want: StrategyFull},
+		{name: "médio", opts: CloneOptions{SizeKB: 600}, want: StrategyBlobless},
+		{name: "grande", opts: CloneOptions{SizeKB: 2000}, want: StrategyShallow},
+		{name: "grande com todas as branches", opts: CloneOptions{SizeKB: 2000, AllBranches: true}, want: StrategyBlobless},
+		{name: "pedida pelo job", opts: CloneOptions{Strategy: StrategySingleBranch, SizeKB: 10}, want: StrategySingleBranch},
+		{name: "padrão do cliente", client: StrategyFull, opts: CloneOptions{SizeKB: 2000}, want: StrategyFull},
+		{name: "job shallow com todas as branches", opts: CloneOptions{Strategy: StrategyShallow, SizeKB: 10, AllBranches: true}, want: StrategyFull},
+		{name: "job single-branch com todas as branches", opts: CloneOptions{Strategy: StrategySingleBranch, SizeKB: 600, AllBranches: true}, want: StrategyBlobless},
+		{name: "padrão shallow com todas as branches", client: StrategyShallow, opts: CloneOptions{SizeKB: 10, AllBranches: true}, want: StrategyFull},
+		{name: "job blobless com todas as branches", opts: CloneOptions{Strategy: StrategyBlobless, SizeKB: 10, AllBranches: true}, want: StrategyBlobless},
+	}
+	for _, tt := range tests {
+		c.DefaultStrategy = tt.client
+		if got := c.strategyFor(tt.opts); got != tt.want {
+			t.Errorf("%s: %q, esperado %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Strategy
+		wantErr bool
+	}{
+		{in: "", want: StrategyAuto},
+		{in: "auto", want: StrategyAuto},
+		{in: "blobless", want: StrategyBlobless},
+		{in: "mirror", wantErr: true},
+		{in: "Full", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseStrategy(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseStrategy(%q) = %q, %v; esperado %q, erro = %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// newHostKey gera uma chave de servidor SSH para os testes.
+func newHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSSHAuthHostKey(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := pem.EncodeToMemory(block)
+
+	const host = "git.exemplo.com:22"
+	deployKey, systemKey, unknownKey := newHostKey(t), newHostKey(t), newHostKey(t)
+	// known_hosts do sistema, lido quando a chave do Vault não traz os seus.
+	system := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(system, []byte(knownhosts.Line([]string{host}, systemKey)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SSH_KNOWN_HOSTS", system)
+
+	tests := []struct {
+		name       string
+		knownHosts []byte
+		insecure   bool
+		hostKey    ssh.PublicKey
+		wantErr    bool
+	}{
+		{name: "known_hosts da chave", knownHosts: []byte(knownhosts.Line([]string{host}, deployKey)), hostKey: deployKey},
+		{name: "servidor desconhecido", knownHosts: []byte(knownhosts.Line([]string{host}, deployKey)), hostKey: unknownKey, wantErr: true},
+		// Com known_hosts na chave, os do sistema não são usados.
+		{name: "só os known_hosts da chave", knownHosts: []byte(knownhosts.Line([]string{host}, deployKey)), hostKey: systemKey, wantErr: true},
+		{name: "sem known_hosts usa os do sistema", hostKey: systemKey},
+		{name: "sem known_hosts e servidor desconhecido", hostKey: unknownKey, wantErr: true},
+		{name: "verificação desabilitada", knownHosts: []byte(knownhosts.Line([]string{host}, deployKey)), insecure: true, hostKey: unknownKey},
+	}
+	for _, tt := range tests {
+		c := &GoGitClient{SSHInsecureIgnoreHostKey: tt.insecure}
+		auth, err := c.sshAuth(&vault.SSHKey{PrivateKey: privateKey, KnownHosts: tt.knownHosts})
+		if err != nil {
+			t.Fatalf("%s: sshAuth: %v", tt.name, err)
+		}
+		remote := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 10), Port: 22}
+		err = auth.HostKeyCallback(host, remote, tt.hostKey)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: verificação da chave do servidor: %v, esperado erro = %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// TestCloneBlobless clona um repositório local com --filter=blob:none e confere
+// que a autenticação volta em Clone.Env em vez de ficar gravada no clone.
+func TestCloneBlobless(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git não encontrado")
+	}
+	src := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "uploadpack.allowFilter", "true"},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "--allow-empty", "-m", "primeiro"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", src}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	c := &GoGitClient{}
+	dir := filepath.Join(t.TempDir(), "clone")
+	clone, err := c.CloneRepo(context.Background(), "file://"+src, CloneOptions{
+		Strategy:    StrategyBlobless,
+		Dir:         dir,
+		Credentials: &vault.Credentials{Username: "x-access-token", Token: "segredo"},
+	})
+	if err != nil {
+		t.Fatalf("CloneRepo: %v", err)
+	}
+	if clone.Dir != dir || clone.Strategy != StrategyBlobless {
+		t.Errorf("clone em %s com %s, esperado %s com blobless", clone.Dir, clone.Strategy, dir)
+	}
+
+	config, err := os.ReadFile(filepath.Join(dir, ".git", "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.ToLower(string(config)), "extraheader") {
+		t.Errorf("autenticação gravada em .git/config:\n%s", config)
+	}
+	env := strings.Join(clone.Env, "\n")
+	if !strings.Contains(env, "GIT_CONFIG_KEY_0=http.extraHeader") || !strings.Contains(env, "GIT_CONFIG_VALUE_0=Authorization: Basic ") {
+		t.Errorf("Clone.Env sem a autenticação: %v", clone.Env)
+	}
+}
@@ -0,0 +1,146 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"yourproject/internal/logger"
+)
+
+// Classes de falha do clone gravadas no scan (scans.error_class).
+const (
+	ErrorClassTimeout  = "timeout"
+	ErrorClassTooLarge = "too_large"
+	ErrorClassAuth     = "auth"
+	ErrorClassNotFound = "not_found"
+)
+
+var (
+	// ErrCloneTimeout indica que o clone excedeu o tempo limite calculado para o
+	// tamanho do repositório (ex.: conexão travada).
+	ErrCloneTimeout = errors.New("tempo limite do clone excedido")
+	// ErrCloneTooLarge indica que o repositório excede o limite de bytes do clone.
+	ErrCloneTooLarge = errors.New("repositório excede o tamanho máximo de clone")
+	// ErrCloneAuth indica credenciais ou chave SSH recusadas pelo servidor.
+	ErrCloneAuth = errors.New("autenticação recusada pelo servidor Git")
+	// ErrCloneNotFound indica repositório inexistente ou sem acesso de leitura.
+	ErrCloneNotFound = errors.New("repositório não encontrado")
+)
+
+// sizeCheckInterval é o intervalo entre as medições do disco ocupado pelo clone.
+const sizeCheckInterval = time.Second
+
+// ErrorClass retorna a classe da falha de clone em err ou "" se ela não se
+// encaixa em nenhuma classe conhecida.
+func ErrorClass(err error) string {
+	switch {
+	case errors.Is(err, ErrCloneTimeout):
+		return ErrorClassTimeout
+	case errors.Is(err, ErrCloneTooLarge):
+		return ErrorClassTooLarge
+	case errors.Is(err, ErrCloneAuth):
+		return ErrorClassAuth
+	case errors.Is(err, ErrCloneNotFound):
+		return ErrorClassNotFound
+	}
+	return ""
+}
+
+// Trechos da saída de erro do git e das mensagens de transporte do go-git que
+// identificam a classe da falha. São específicos do protocolo ("remote: ",
+// "fatal: ...", status HTTP) para não classificar erros locais que por acaso
+// contenham "not found" ou "permission denied".
+var (
+	authMessages = []string{
+		"fatal: authentication failed",
+		"fatal: could not read username",
+		"remote: invalid username or password",
+		"returned error: 401",
+		"returned error: 403",
+		"permission denied (publickey",
+		"host key verification failed",
+		"ssh: unable to authenticate",
+		"knownhosts: key",
+	}
+	notFoundMessages = []string{
+		"remote: repository not found",
+		"remote: the project you were looking for could not be found",
+		"returned error: 404",
+		"does not appear to be a git repository",
+	}
+)
+
+// classify associa o erro do go-git ou do git a uma das classes de falha. O git
+// só informa o motivo na saída de erro, então a mensagem é comparada com os
+// textos usados pelo git e pelos principais provedores.
+func classify(err error) error {
+	switch {
+	case errors.Is(err, transport.ErrAuthenticationRequired), errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, transport.ErrInvalidAuthMethod):
+		return ErrCloneAuth
+	case errors.Is(err, transport.ErrRepositoryNotFound):
+		return ErrCloneNotFound
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range authMessages {
+		if strings.Contains(msg, s) {
+			return ErrCloneAuth
+		}
+	}
+	for _, s := range notFoundMessages {
+		if strings.Contains(msg, s) {
+			return ErrCloneNotFound
+		}
+	}
+	// Para um 404 por HTTP o git informa "fatal: repository '<url>' not found".
+	if i := strings.Index(msg, "fatal: repository '"); i >= 0 && strings.Contains(msg[i:], "' not found") {
+		return ErrCloneNotFound
+	}
+	return nil
+}
+
+// cloneTimeout calcula o tempo limite do clone: CloneTimeout mais
+// CloneTimeoutPerGB para cada GB do repositório. Retorna 0 (sem limite) se
+// CloneTimeout não estiver definido. O tamanho é convertido para MB antes da
+// multiplicação para não estourar o int64 de nanossegundos.
+func (c *GoGitClient) cloneTimeout(sizeKB int) time.Duration {
+	if c.CloneTimeout <= 0 {
+		return 0
+	}
+	return c.CloneTimeout + c.CloneTimeoutPerGB*time.Duration(sizeKB>>10)/1024
+}
+
+// watchSize mede a cada segundo o quanto paths cresceram desde o início do
+// clone e cancela ctx com ErrCloneTooLarge quando o crescimento passa de limit.
+// Como os pacotes recebidos são gravados em disco conforme chegam, o
+// crescimento acompanha os bytes transferidos. Retorna quando ctx termina.
+func watchSize(ctx context.Context, cancel context.CancelCauseFunc, limit int64, paths ...string) {
+	base := pathsSize(paths)
+	ticker := time.NewTicker(sizeCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if grown := pathsSize(paths) - base; grown > limit {
+			logger.Log.Warnf("GitClient: clone em %s passou de %d MB; abortando", paths[0], limit>>20)
+			cancel(ErrCloneTooLarge)
+			return
+		}
+	}
+}
+
+// pathsSize soma o tamanho de paths, ignorando os que ainda não existem.
+func pathsSize(paths []string) int64 {
+	var total int64
+	for _, p := range paths {
+		size, _ := dirSize(p)
+		total += size
+	}
+	return total
+}
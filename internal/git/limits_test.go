@@ -0,0 +1,101 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "go-git sem autenticação", err: fmt.Errorf("clone: %w", transport.ErrAuthenticationRequired), want: ErrCloneAuth},
+		{name: "go-git sem repositório", err: transport.ErrRepositoryNotFound, want: ErrCloneNotFound},
+		{name: "git https 401", err: errors.New("git clone: exit status 128: fatal: Authentication failed for 'https://github.com/acme/pix.git/'"), want: ErrCloneAuth},
+		{name: "git sem prompt", err: errors.New("git clone: exit status 128: fatal: could not read Username for 'https://github.com': terminal prompts disabled"), want: ErrCloneAuth},
+		{name: "git https 403", err: errors.New("git clone: exit status 128: fatal: unable to access 'https://x/': The requested URL returned error: 403"), want: ErrCloneAuth},
+		{name: "ssh recusado", err: errors.New("git clone: exit status 128: git@github.com: Permission denied (publickey)."), want: ErrCloneAuth},
+		{name: "github sem acesso", err: errors.New("git clone: exit status 128: remote: Repository not found.\nfatal: repository 'https://github.com/acme/pix.git/' not found"), want: ErrCloneNotFound},
+		{name: "git https 404", err: errors.New("git clone: exit status 128: fatal: repository 'https://x/acme/pix.git/' not found"), want: ErrCloneNotFound},
+		{name: "caminho inválido", err: errors.New("git clone: exit status 128: fatal: '/tmp/x' does not appear to be a git repository"), want: ErrCloneNotFound},
+		// Erros locais com textos parecidos não são falhas do servidor.
+		{name: "disco sem permissão", err: errors.New("open /tmp/clone/.git/config: permission denied")},
+		{name: "arquivo inexistente", err: errors.New("stat /tmp/clone/HEAD: file not found")},
+		{name: "objeto ausente", err: errors.New("object not found")},
+		{name: "branch inexistente", err: errors.New("git clone: exit status 128: warning: Could not find remote branch dev to clone.\nfatal: Remote branch dev not found in upstream origin")},
+	}
+	for _, tt := range tests {
+		if got := classify(tt.err); got != tt.want {
+			t.Errorf("%s: classify = %v, esperado %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCloneTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   time.Duration
+		perGB  time.Duration
+		sizeKB int
+		want   time.Duration
+	}{
+		{name: "desabilitado", perGB: time.Minute, sizeKB: 1 << 20},
+		{name: "repositório pequeno", base: 10 * time.Minute, perGB: 10 * time.Minute, sizeKB: 100, want: 10 * time.Minute},
+		{name: "1 GB", base: 10 * time.Minute, perGB: 10 * time.Minute, sizeKB: 1 << 20, want: 20 * time.Minute},
+		{name: "512 MB", base: 10 * time.Minute, perGB: 10 * time.Minute, sizeKB: 1 << 19, want: 15 * time.Minute},
+		// Com a multiplicação em nanossegundos, 100 GB a 10 min/GB estourava o int64.
+		{name: "100 GB", base: 10 * time.Minute, perGB: 10 * time.Minute, sizeKB: 100 << 20, want: 1010 * time.Minute},
+	}
+	for _, tt := range tests {
+		c := &GoGitClient{CloneTimeout: tt.base, CloneTimeoutPerGB: tt.perGB}
+		if got := c.cloneTimeout(tt.sizeKB); got != tt.want {
+			t.Errorf("%s: cloneTimeout(%d) = %s, esperado %s", tt.name, tt.sizeKB, got, tt.want)
+		}
+	}
+}
+
+func TestWatchSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		write int   // Bytes gravados após o início da medição.
+		limit int64 // Crescimento máximo aceito.
+		want  error // Causa do cancelamento.
+	}{
+		{name: "dentro do limite", write: 1024, limit: 1 << 20, want: context.DeadlineExceeded},
+		{name: "acima do limite", write: 1 << 20, limit: 1024, want: ErrCloneTooLarge},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		// O que já existe antes do clone não conta para o limite.
+		if err := os.WriteFile(filepath.Join(dir, "antigo"), make([]byte, 1<<20), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		ctx, stop := context.WithTimeout(ctx, 2*sizeCheckInterval)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			watchSize(ctx, cancel, tt.limit, dir, filepath.Join(dir, "ainda-nao-existe"))
+		}()
+		// Grava depois da medição inicial, antes da primeira comparação.
+		time.Sleep(sizeCheckInterval / 2)
+		if err := os.WriteFile(filepath.Join(dir, "pack"), make([]byte, tt.write), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		<-done
+		if got := context.Cause(ctx); !errors.Is(got, tt.want) {
+			t.Errorf("%s: causa %v, esperado %v", tt.name, got, tt.want)
+		}
+		stop()
+		cancel(nil)
+	}
+}
@@ -0,0 +1,252 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	httpAuth "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"yourproject/internal/logger"
+)
+
+// DefaultMirrorCacheSize é o orçamento de disco padrão do cache de mirrors.
+const DefaultMirrorCacheSize = 20 << 30
+
+var safeMirrorName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// MirrorCache mantém em Dir um mirror bare por RepositoryID. O primeiro scan do
+// repositório clona o mirror e os seguintes só fazem fetch incremental. Cada
+// scan recebe uma cópia local do mirror (hardlinks no mesmo sistema de
+// arquivos), então o mirror pode ser atualizado ou removido durante o scan.
+// Quando o total passa de MaxBytes, os mirrors usados há mais tempo são removidos.
+type MirrorCache struct {
+	Dir      string
+	MaxBytes int64 // 0 usa DefaultMirrorCacheSize.
+
+	mu      sync.Mutex
+	entries map[string]*mirrorEntry
+}
+
+// mirrorEntry é um mirror no cache. lock serializa clone, fetch, cópia e
+// remoção do mirror; size e lastUsed são protegidos por MirrorCache.mu. A
+// entrada continua no mapa após a remoção, para que o repositório tenha sempre
+// um único lock.
+type mirrorEntry struct {
+	lock     sync.Mutex
+	path     string
+	size     int64
+	lastUsed time.Time
+}
+
+// Checkout atualiza (ou cria) o mirror do repositório e cria em dir uma cópia
+// sem checkout para o scanner. Com maxBytes > 0, o crescimento do mirror é
+// medido só enquanto o lock do repositório está com este job (o fetch de outro
+// worker no mesmo mirror não conta) e cancel recebe ErrCloneTooLarge quando
+// passa de maxBytes.
+func (m *MirrorCache) Checkout(ctx context.Context, gitPath, repositoryID, repoURL, dir string, auth *httpAuth.BasicAuth, maxBytes int64, cancel context.CancelCauseFunc) error {
+	start := time.Now()
+	defer logger.Trace("MirrorCheckout", start)
+
+	entry, err := m.entry(repositoryID)
+	if err != nil {
+		return err
+	}
+	entry.lock.Lock()
+	stopWatch := func() {}
+	if maxBytes > 0 {
+		watchCtx, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			watchSize(watchCtx, cancel, maxBytes, entry.path, entry.path+".tmp")
+		}()
+		stopWatch = func() {
+			stop()
+			<-done
+		}
+	}
+	err = m.update(ctx, gitPath, entry, repoURL, auth)
+	if err == nil {
+		err = runGit(ctx, gitPath, nil, "clone", "--quiet", "--no-checkout", entry.path, dir)
+	}
+	if err == nil {
+		// O mtime guarda a ordem de uso entre reinícios do serviço.
+		now := time.Now()
+		os.Chtimes(entry.path, now, now)
+	}
+	stopWatch()
+	size, sizeErr := dirSize(entry.path)
+	entry.lock.Unlock()
+
+	m.mu.Lock()
+	entry.lastUsed = time.Now()
+	if sizeErr == nil {
+		entry.size = size
+	}
+	m.mu.Unlock()
+	m.evict()
+	return err
+}
+
+// update faz o fetch do mirror existente ou clona um novo. Um mirror cujo fetch
+// falha (ex.: corrompido) é descartado e clonado de novo.
+func (m *MirrorCache) update(ctx context.Context, gitPath string, entry *mirrorEntry, repoURL string, auth *httpAuth.BasicAuth) error {
+	if _, err := os.Stat(entry.path); err == nil {
+		err := runGit(ctx, gitPath, auth, "-C", entry.path, "fetch", "--quiet", "--prune", "origin")
+		if err == nil {
+			logger.Log.Debugf("GitClient: mirror %s atualizado", entry.path)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		logger.Log.Warnf("GitClient: fetch do mirror %s falhou (%v); clonando de novo", entry.path, err)
+		if err := os.RemoveAll(entry.path); err != nil {
+			return fmt.Errorf("erro ao remover mirror %s: %v", entry.path, err)
+		}
+	}
+
+	// O clone vai para um diretório temporário e só é renomeado quando completo,
+	// para que um clone interrompido nunca seja usado como mirror.
+	tmp := entry.path + ".tmp"
+	os.RemoveAll(tmp)
+	if err := runGit(ctx, gitPath, auth, "clone", "--quiet", "--mirror", repoURL, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, entry.path); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("erro ao gravar mirror %s: %v", entry.path, err)
+	}
+	logger.Log.Debugf("GitClient: mirror %s criado", entry.path)
+	return nil
+}
+
+// entry retorna o mirror do repositório, carregando o cache do disco no primeiro uso.
+func (m *MirrorCache) entry(repositoryID string) (*mirrorEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.entries == nil {
+		if err := m.load(); err != nil {
+			return nil, err
+		}
+	}
+	name := repositoryID
+	if !safeMirrorName.MatchString(name) {
+		sum := sha256.Sum256([]byte(repositoryID))
+		name = hex.EncodeToString(sum[:])
+	}
+	entry, ok := m.entries[name]
+	if !ok {
+		entry = &mirrorEntry{path: filepath.Join(m.Dir, name+".git")}
+		m.entries[name] = entry
+	}
+	return entry, nil
+}
+
+// load cria Dir e registra os mirrors já existentes, removendo clones
+// temporários deixados por uma execução interrompida.
+func (m *MirrorCache) load() error {
+	if err := os.MkdirAll(m.Dir, 0o700); err != nil {
+		return fmt.Errorf("erro ao criar diretório do cache de mirrors %s: %v", m.Dir, err)
+	}
+	items, err := os.ReadDir(m.Dir)
+	if err != nil {
+		return fmt.Errorf("erro ao ler cache de mirrors %s: %v", m.Dir, err)
+	}
+	m.entries = make(map[string]*mirrorEntry)
+	for _, item := range items {
+		path := filepath.Join(m.Dir, item.Name())
+		if strings.HasSuffix(item.Name(), ".tmp") {
+			os.RemoveAll(path)
+			continue
+		}
+		name, ok := strings.CutSuffix(item.Name(), ".git")
+		if !ok || !item.IsDir() {
+			continue
+		}
+		info, err := item.Info()
+		if err != nil {
+			continue
+		}
+		size, err := dirSize(path)
+		if err != nil {
+			logger.Log.Warnf("GitClient: erro ao medir mirror %s: %v", path, err)
+		}
+		m.entries[name] = &mirrorEntry{path: path, size: size, lastUsed: info.ModTime()}
+	}
+	logger.Log.Infof("GitClient: cache de mirrors %s com %d repositórios", m.Dir, len(m.entries))
+	return nil
+}
+
+// evict remove os mirrors usados há mais tempo até o cache caber em MaxBytes.
+// Mirrors em uso por outro worker são pulados.
+func (m *MirrorCache) evict() {
+	limit := m.MaxBytes
+	if limit <= 0 {
+		limit = DefaultMirrorCacheSize
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var total int64
+	names := make([]string, 0, len(m.entries))
+	for name, entry := range m.entries {
+		total += entry.size
+		names = append(names, name)
+	}
+	if total <= limit {
+		return
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return m.entries[names[i]].lastUsed.Before(m.entries[names[j]].lastUsed)
+	})
+	for _, name := range names {
+		if total <= limit {
+			break
+		}
+		entry := m.entries[name]
+		if entry.size == 0 || !entry.lock.TryLock() {
+			continue
+		}
+		err := os.RemoveAll(entry.path)
+		entry.lock.Unlock()
+		if err != nil {
+			logger.Log.Errorf("GitClient: erro ao remover mirror %s: %v", entry.path, err)
+			continue
+		}
+		logger.Log.Infof("GitClient: mirror %s removido do cache (%d MB)", entry.path, entry.size>>20)
+		total -= entry.size
+		entry.size = 0
+	}
+}
+
+// dirSize soma o tamanho dos arquivos em dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
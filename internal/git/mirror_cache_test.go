@@ -0,0 +1,132 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMirrorCacheEvict(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int64
+		locked   string   // Mirror em uso por outro worker durante a remoção.
+		want     []string // Mirrors que continuam no disco.
+	}{
+		{name: "dentro do orçamento", maxBytes: 3 << 10, want: []string{"antigo", "medio", "novo"}},
+		{name: "remove o usado há mais tempo", maxBytes: 2 << 10, want: []string{"medio", "novo"}},
+		{name: "remove até caber", maxBytes: 1 << 10, want: []string{"novo"}},
+		{name: "pula o mirror em uso", maxBytes: 2 << 10, locked: "antigo", want: []string{"antigo", "novo"}},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		// O mtime do mirror guarda a ordem de uso; cada mirror tem 1 KB.
+		for i, name := range []string{"antigo", "medio", "novo"} {
+			path := filepath.Join(dir, name+".git")
+			if err := os.Mkdir(path, 0o700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(path, "pack"), make([]byte, 1<<10), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			used := time.Now().Add(time.Duration(i-3) * time.Hour)
+			if err := os.Chtimes(path, used, used); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		m := &MirrorCache{Dir: dir, MaxBytes: tt.maxBytes}
+		if tt.locked != "" {
+			entry, err := m.entry(tt.locked)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry.lock.Lock()
+			m.evict()
+			entry.lock.Unlock()
+		} else {
+			if _, err := m.entry("novo"); err != nil {
+				t.Fatal(err)
+			}
+			m.evict()
+		}
+
+		var got []string
+		for _, name := range []string{"antigo", "medio", "novo"} {
+			if _, err := os.Stat(filepath.Join(dir, name+".git")); err == nil {
+				got = append(got, name)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: mirrors %v, esperado %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: mirrors %v, esperado %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// TestMirrorCacheCheckoutLock confere que o Checkout espera o lock do mirror e
+// que o que outro worker grava no mirror antes disso não conta para o limite de
+// tamanho deste job.
+func TestMirrorCacheCheckoutLock(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git não encontrado")
+	}
+	src := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-q", "--allow-empty", "-m", "primeiro"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", src}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	m := &MirrorCache{Dir: t.TempDir()}
+	ctx := context.Background()
+	noCancel := func(error) {}
+	if err := m.Checkout(ctx, gitPath, "repo-1", "file://"+src, filepath.Join(t.TempDir(), "primeiro"), nil, 0, noCancel); err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	entry, err := m.entry("repo-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.lock.Lock()
+	cloneCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	done := make(chan error, 1)
+	go func() {
+		done <- m.Checkout(cloneCtx, gitPath, "repo-1", "file://"+src, filepath.Join(t.TempDir(), "segundo"), nil, 512<<10, cancel)
+	}()
+
+	// Simula o fetch de outro worker: 1 MB gravado no mirror enquanto o lock é
+	// dele, depois de o Checkout deste job já ter começado a esperar.
+	time.Sleep(sizeCheckInterval / 2)
+	if err := os.WriteFile(filepath.Join(entry.path, "objects", "pack", "outro-worker"), make([]byte, 1<<20), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Checkout terminou (%v) sem o lock do mirror", err)
+	case <-time.After(2 * sizeCheckInterval):
+	}
+	entry.lock.Unlock()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	if cause := context.Cause(cloneCtx); cause != nil {
+		t.Errorf("clone cancelado com %v; o fetch de outro worker não conta para o limite", cause)
+	}
+}
@@ -0,0 +1,151 @@
+// Package github implementa o cliente mínimo da API REST do GitHub usado pelo
+// serviço. BaseURL permite apontar para o GitHub Enterprise ou para um servidor
+// HTTP local que simule a API.
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"yourproject/internal/logger"
+	"yourproject/internal/vault"
+)
+
+// DefaultBaseURL é o endereço da API pública do GitHub.
+const DefaultBaseURL = "https://api.github.com"
+
+const perPage = 100
+
+// apiTimeout limita cada requisição à API, inclusive a leitura da resposta.
+const apiTimeout = 30 * time.Second
+
+// defaultHTTPClient é usado quando HTTPClient não é definido.
+var defaultHTTPClient = &http.Client{Timeout: apiTimeout}
+
+// Repository é o subconjunto dos campos de repositório usados pelo serviço.
+type Repository struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	FullName      string    `json:"full_name"`
+	Size          int       `json:"size"` // Em KB.
+	Language      string    `json:"language"`
+	Archived      bool      `json:"archived"`
+	Disabled      bool      `json:"disabled"`
+	Fork          bool      `json:"fork"`
+	DefaultBranch string    `json:"default_branch"`
+	Topics        []string  `json:"topics"`
+	PushedAt      time.Time `json:"pushed_at"`
+}
+
+// APIError é uma resposta de erro da API.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API do GitHub retornou %d: %s", e.StatusCode, e.Message)
+}
+
+// Client chama a API REST do GitHub autenticando com o token das credenciais.
+type Client struct {
+	BaseURL     string // Vazio usa DefaultBaseURL.
+	Credentials vault.VaultClient
+	HTTPClient  *http.Client // Vazio usa um cliente com timeout de 30s.
+}
+
+// ListOrgRepositories lista todos os repositórios da organização, seguindo a
+// paginação do header Link.
+func (c *Client) ListOrgRepositories(ctx context.Context, org string) ([]Repository, error) {
+	start := time.Now()
+	defer logger.Trace("ListOrgRepositories", start)
+
+	next := fmt.Sprintf("%s/orgs/%s/repos?type=all&per_page=%d", c.baseURL(), url.PathEscape(org), perPage)
+	var repos []Repository
+	for next != "" {
+		var page []Repository
+		link, err := c.get(ctx, next, org, &page)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar repositórios da organização %s: %w", org, err)
+		}
+		repos = append(repos, page...)
+		next = nextPage(link)
+		// O token só é enviado ao próprio BaseURL.
+		if next != "" && !strings.HasPrefix(next, c.baseURL()+"/") {
+			return nil, fmt.Errorf("próxima página de %s fora de %s: %s", org, c.baseURL(), next)
+		}
+	}
+	return repos, nil
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL == "" {
+		return DefaultBaseURL
+	}
+	return strings.TrimSuffix(c.BaseURL, "/")
+}
+
+// get faz o GET autenticado com as credenciais de owner, decodifica o JSON em
+// out e retorna o header Link.
+func (c *Client) get(ctx context.Context, u, owner string, out any) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.Credentials != nil {
+		creds, err := c.Credentials.GetCredentials("github", owner)
+		if err != nil {
+			return "", fmt.Errorf("erro ao recuperar credenciais do GitHub: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+creds.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var payload struct {
+			Message string `json:"message"`
+		}
+		msg := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &payload) == nil && payload.Message != "" {
+			msg = payload.Message
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			msg += " (limite de requisições esgotado; reset em " + resp.Header.Get("X-RateLimit-Reset") + ")"
+		}
+		return "", &APIError{StatusCode: resp.StatusCode, Message: msg}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return "", fmt.Errorf("erro ao decodificar resposta de %s: %v", u, err)
+	}
+	return resp.Header.Get("Link"), nil
+}
+
+// nextPage extrai a URL rel="next" do header Link.
+func nextPage(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(target), "<>")
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package jobschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"yourproject/models"
+)
+
+// CurrentVersion é a versão do envelope ScanJob produzida e aceita pelo serviço.
+const CurrentVersion = 5
+
+// upconverters convertem o documento da versão N para N+1.
+var upconverters = map[int]func(doc map[string]any) error{
+	1: upconvertV1,
+	2: passthrough,
+	3: passthrough,
+	4: passthrough,
+}
+
+// Decode lê uma mensagem ScanJob de qualquer versão suportada, converte para a
+// versão atual e valida. Se o JSON for legível mas inválido, o job decodificado
+// (ou, se nem isso for possível, só o scan_id) é retornado junto com o erro
+// para que o motivo possa ser gravado no scan.
+func Decode(body []byte) (*models.ScanJob, error) {
+	return DecodeWithDefaults(body, nil)
+}
+
+// DecodeWithDefaults é Decode com valores para os campos ausentes da mensagem,
+// aplicados antes da conversão de versão (ex.: o scan_id gerado pela API).
+func DecodeWithDefaults(body []byte, defaults map[string]any) (*models.ScanJob, error) {
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("JSON inválido: %v", err)
+	}
+	if doc == nil {
+		return nil, &ValidationError{Field: "", Reason: "mensagem vazia"}
+	}
+	for k, v := range defaults {
+		if _, ok := doc[k]; ok {
+			continue
+		}
+		// Números do documento são json.Number (UseNumber).
+		if n, ok := v.(int); ok {
+			v = json.Number(strconv.Itoa(n))
+		}
+		doc[k] = v
+	}
+
+	version, err := schemaVersion(doc)
+	if err != nil {
+		return partialJob(doc), err
+	}
+	if version > CurrentVersion {
+		return partialJob(doc), &ValidationError{Field: "schema_version", Reason: fmt.Sprintf("versão %d não suportada (máxima: %d)", version, CurrentVersion)}
+	}
+	for v := version; v < CurrentVersion; v++ {
+		if err := upconverters[v](doc); err != nil {
+			return partialJob(doc), fmt.Errorf("erro ao converter job da versão %d para %d: %v", v, v+1, err)
+		}
+	}
+	doc["schema_version"] = CurrentVersion
+
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao normalizar job: %v", err)
+	}
+	var job models.ScanJob
+	strict := json.NewDecoder(bytes.NewReader(normalized))
+	strict.DisallowUnknownFields()
+	if err := strict.Decode(&job); err != nil {
+		return partialJob(doc), &ValidationError{Field: "", Reason: fmt.Sprintf("estrutura inválida: %v", err)}
+	}
+	if err := Validate(&job); err != nil {
+		return &job, err
+	}
+	return &job, nil
+}
+
+// partialJob retorna um job só com o scan_id do documento, para que a rejeição
+// de uma mensagem que não pôde ser decodificada ainda seja gravada no scan.
+func partialJob(doc map[string]any) *models.ScanJob {
+	scanID, ok := doc["scan_id"].(string)
+	if !ok || scanID == "" {
+		return nil
+	}
+	return &models.ScanJob{ScanID: scanID}
+}
+
+// schemaVersion lê schema_version; mensagens sem o campo são da versão 1 (legado).
+func schemaVersion(doc map[string]any) (int, error) {
+	raw, ok := doc["schema_version"]
+	if !ok || raw == nil {
+		return 1, nil
+	}
+	num, ok := raw.(json.Number)
+	if !ok {
+		return 0, &ValidationError{Field: "schema_version", Reason: "deve ser um número inteiro"}
+	}
+	v, err := num.Int64()
+	if err != nil || v < 1 {
+		return 0, &ValidationError{Field: "schema_version", Reason: fmt.Sprintf("valor inválido %q", num.String())}
+	}
+	return int(v), nil
+}
+
+// upconvertV1 normaliza mensagens legadas: espaços extras, URL completa no lugar
+// de org/repo, tamanho enviado como string e sigla em minúsculas.
+func upconvertV1(doc map[string]any) error {
+	for k, v := range doc {
+		if s, ok := v.(string); ok {
+			doc[k] = strings.TrimSpace(s)
+		}
+	}
+	if name, ok := doc["repository_full_name"].(string); ok {
+		name = strings.TrimPrefix(name, "https://github.com/")
+		name = strings.TrimSuffix(name, ".git")
+		doc["repository_full_name"] = strings.Trim(name, "/")
+	}
+	if size, ok := doc["repository_size"].(string); ok {
+		if size == "" {
+			delete(doc, "repository_size")
+		} else {
+			doc["repository_size"] = json.Number(size)
+		}
+	}
+	if sigla, ok := doc["sigla"].(string); ok {
+		doc["sigla"] = strings.ToUpper(sigla)
+	}
+	return nil
+}
+
+// passthrough converte entre versões que só acrescentaram campos opcionais
+// (3: before_sha e after_sha; 4: clone_strategy; 5: provider). Jobs sem esses
+// campos mantêm o comportamento anterior: histórico completo, estratégia de
+// clone automática e repositório do GitHub.
+func passthrough(doc map[string]any) error {
+	return nil
+}
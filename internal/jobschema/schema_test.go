@@ -0,0 +1,130 @@
+package jobschema
+
+import (
+	"errors"
+	"testing"
+
+	"yourproject/models"
+)
+
+const testScanID = "0b6f3c1e-5d2a-4c59-9d8e-2f3a4b5c6d7e"
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantErr    bool
+		wantScanID string // Esperado no job retornado, inclusive com erro.
+		wantRepo   string
+		wantSigla  string
+	}{
+		{
+			name:       "v1 legado convertido",
+			body:       `{"scan_id":"` + testScanID + `","repository_id":"1","repository_full_name":" https://github.com/org/repo.git ","repository_size":"10","sigla":"abc"}`,
+			wantScanID: testScanID, wantRepo: "org/repo", wantSigla: "ABC",
+		},
+		{
+			name:       "v4 com clone_strategy",
+			body:       `{"schema_version":4,"scan_id":"` + testScanID + `","repository_id":"1","repository_full_name":"org/repo","sigla":"ABC","clone_strategy":"shallow"}`,
+			wantScanID: testScanID, wantRepo: "org/repo", wantSigla: "ABC",
+		},
+		{
+			name:       "clone_strategy interna",
+			body:       `{"schema_version":4,"scan_id":"` + testScanID + `","repository_id":"1","repository_full_name":"org/repo","sigla":"ABC","clone_strategy":"mirror"}`,
+			wantErr:    true,
+			wantScanID: testScanID,
+		},
+		{
+			name:       "versão futura",
+			body:       `{"schema_version":99,"scan_id":"` + testScanID + `"}`,
+			wantErr:    true,
+			wantScanID: testScanID,
+		},
+		{
+			name:       "campo desconhecido",
+			body:       `{"schema_version":5,"scan_id":"` + testScanID + `","extra":true}`,
+			wantErr:    true,
+			wantScanID: testScanID,
+		},
+		{name: "JSON inválido", body: `{`, wantErr: true},
+		{name: "null", body: `null`, wantErr: true},
+	}
+	for _, tt := range tests {
+		job, err := Decode([]byte(tt.body))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: erro = %v, esperado erro = %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		scanID := ""
+		if job != nil {
+			scanID = job.ScanID
+		}
+		if scanID != tt.wantScanID {
+			t.Errorf("%s: scan_id %q, esperado %q", tt.name, scanID, tt.wantScanID)
+		}
+		if err != nil {
+			continue
+		}
+		if job.SchemaVersion != CurrentVersion || job.RepositoryFullName != tt.wantRepo || job.Sigla != tt.wantSigla {
+			t.Errorf("%s: job %+v", tt.name, job)
+		}
+	}
+}
+
+func TestDecodeWithDefaults(t *testing.T) {
+	defaults := map[string]any{"scan_id": testScanID, "schema_version": CurrentVersion}
+
+	job, err := DecodeWithDefaults([]byte(`{"repository_id":"1","repository_full_name":"org/repo","sigla":"ABC"}`), defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ScanID != testScanID || job.SchemaVersion != CurrentVersion {
+		t.Errorf("padrões não aplicados: %+v", job)
+	}
+
+	// Campos informados prevalecem: a versão 1 passa pela conversão do legado.
+	job, err = DecodeWithDefaults([]byte(`{"schema_version":1,"repository_id":"1","repository_full_name":"org/repo","sigla":"abc"}`), defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Sigla != "ABC" {
+		t.Errorf("sigla %q: job v1 não foi convertido", job.Sigla)
+	}
+
+	_, err = DecodeWithDefaults([]byte(`{"schema_version":5,"repository_id":"1","repository_full_name":"org/repo","sigla":"abc"}`), defaults)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "sigla" {
+		t.Errorf("erro %v, esperado sigla inválida", err)
+	}
+}
+
+func TestValidateProvider(t *testing.T) {
+	defer providers.Store(nil)
+	tests := []struct {
+		name       string
+		configured []string // nil não configura os provedores.
+		provider   string
+		wantErr    bool
+	}{
+		{name: "sem provedores configurados", provider: "gitlab"},
+		{name: "GitHub implícito", configured: []string{"github"}},
+		{name: "configurado", configured: []string{"github", "gitlab"}, provider: "gitlab"},
+		{name: "desconhecido", configured: []string{"github"}, provider: "gitlab", wantErr: true},
+		{name: "formato inválido", provider: "GitLab", wantErr: true},
+	}
+	for _, tt := range tests {
+		providers.Store(nil)
+		if tt.configured != nil {
+			SetProviders(tt.configured)
+		}
+		job := &models.ScanJob{
+			SchemaVersion: CurrentVersion, ScanID: testScanID, RepositoryID: "1",
+			RepositoryFullName: "org/repo", Sigla: "ABC", Provider: tt.provider,
+		}
+		err := Validate(job)
+		var verr *ValidationError
+		if (err != nil) != tt.wantErr || (err != nil && (!errors.As(err, &verr) || verr.Field != "provider")) {
+			t.Errorf("%s: erro %v, esperado erro = %v no campo provider", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package jobschema
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"yourproject/models"
+)
+
+// MaxRepositorySize é o maior tamanho aceito, em KB (mesma unidade da API do GitHub).
+const MaxRepositorySize = 100 * 1024 * 1024
+
+var (
+	scanIDPattern   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	repoNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+	// Outros provedores aceitam grupos aninhados (GitLab) ou projeto (Azure DevOps).
+	repoPathPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+(/[A-Za-z0-9_.-]+)+$`)
+	providerPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+	siglaPattern    = regexp.MustCompile(`^[A-Z0-9]{3}$`)
+	shaPattern      = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// providers são os provedores Git configurados; nil aceita qualquer nome
+// válido (ex.: ferramentas que usam o pacote sem os provedores do serviço).
+var providers atomic.Pointer[map[string]bool]
+
+// SetProviders define os provedores Git configurados. Jobs com outro provedor
+// são rejeitados por Validate; o provedor vazio (GitHub) é sempre aceito.
+func SetProviders(names []string) {
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+	providers.Store(&known)
+}
+
+// knownProvider indica se name está entre os provedores configurados.
+func knownProvider(name string) bool {
+	known := providers.Load()
+	return known == nil || (*known)[name]
+}
+
+// providerNames lista os provedores configurados, para a mensagem de erro.
+func providerNames() string {
+	var names []string
+	for name := range *providers.Load() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// ValidationError descreve o campo rejeitado e o motivo.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return "job inválido: " + e.Reason
+	}
+	return fmt.Sprintf("job inválido: campo %s: %s", e.Field, e.Reason)
+}
+
+// Validate aplica as regras do envelope atual e retorna o primeiro campo inválido.
+func Validate(job *models.ScanJob) error {
+	switch {
+	case job.SchemaVersion != CurrentVersion:
+		return &ValidationError{Field: "schema_version", Reason: fmt.Sprintf("esperado %d, recebido %d", CurrentVersion, job.SchemaVersion)}
+	case job.ScanID == "":
+		return &ValidationError{Field: "scan_id", Reason: "obrigatório"}
+	case !scanIDPattern.MatchString(job.ScanID):
+		return &ValidationError{Field: "scan_id", Reason: fmt.Sprintf("%q não é um UUID", job.ScanID)}
+	case job.RepositoryID == "":
+		return &ValidationError{Field: "repository_id", Reason: "obrigatório"}
+	case job.RepositoryFullName == "":
+		return &ValidationError{Field: "repository_full_name", Reason: "obrigatório"}
+	case job.Provider != "" && !providerPattern.MatchString(job.Provider):
+		return &ValidationError{Field: "provider", Reason: fmt.Sprintf("%q deve ter só letras minúsculas, dígitos e hífens", job.Provider)}
+	case job.Provider != "" && !knownProvider(job.Provider):
+		return &ValidationError{Field: "provider", Reason: fmt.Sprintf("%q não configurado (configurados: %s)", job.Provider, providerNames())}
+	case (job.Provider == "" || job.Provider == "github") && !repoNamePattern.MatchString(job.RepositoryFullName),
+		strings.Contains(job.RepositoryFullName, ".."):
+		return &ValidationError{Field: "repository_full_name", Reason: fmt.Sprintf("%q não está no formato org/repo", job.RepositoryFullName)}
+	case !repoPathPattern.MatchString(job.RepositoryFullName):
+		return &ValidationError{Field: "repository_full_name", Reason: fmt.Sprintf("%q não é um caminho de repositório", job.RepositoryFullName)}
+	case job.RepositorySize < 0 || job.RepositorySize > MaxRepositorySize:
+		return &ValidationError{Field: "repository_size", Reason: fmt.Sprintf("%d fora do intervalo [0, %d] KB", job.RepositorySize, MaxRepositorySize)}
+	case job.Sigla == "":
+		return &ValidationError{Field: "sigla", Reason: "obrigatório"}
+	case !siglaPattern.MatchString(job.Sigla):
+		return &ValidationError{Field: "sigla", Reason: fmt.Sprintf("%q deve ter 3 caracteres alfanuméricos maiúsculos", job.Sigla)}
+	case job.BeforeSHA != "" && !shaPattern.MatchString(job.BeforeSHA):
+		return &ValidationError{Field: "before_sha", Reason: fmt.Sprintf("%q não é um SHA de commit", job.BeforeSHA)}
+	case job.AfterSHA != "" && !shaPattern.MatchString(job.AfterSHA):
+		return &ValidationError{Field: "after_sha", Reason: fmt.Sprintf("%q não é um SHA de commit", job.AfterSHA)}
+	case job.BeforeSHA != "" && job.AfterSHA == "":
+		return &ValidationError{Field: "after_sha", Reason: "obrigatório quando before_sha é informado"}
+	case !models.ValidCloneStrategy(job.CloneStrategy):
+		return &ValidationError{Field: "clone_strategy", Reason: fmt.Sprintf("%q desconhecida (use full, single-branch, shallow, blobless ou auto)", job.CloneStrategy)}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Package metrics publica as métricas do pipeline via expvar (GET /debug/vars,
+// autenticado com o token da API).
+package metrics
+
+import (
+	"expvar"
+	"time"
+)
+
+var (
+	// Atraso, em segundos, entre MessageCreatedAt e a retirada do job por um worker, por origem.
+	queueLag    = expvar.NewMap("queue_lag_seconds")
+	queueLagSum = expvar.NewMap("queue_lag_seconds_sum")
+	dequeued    = expvar.NewMap("jobs_dequeued_total")
+	expired     = expvar.NewMap("jobs_expired_total")
+	processed   = expvar.NewMap("jobs_processed_total") // Chave "<origem>.<resultado>".
+	running     = expvar.NewMap("jobs_running")         // Jobs em execução, por origem.
+	waiting     = expvar.NewMap("jobs_held")            // Jobs retidos aguardando worker, por origem.
+	cloneFailed = expvar.NewMap("clone_failures_total") // Clones que falharam, por classe de erro.
+)
+
+// ObserveQueueLag registra o atraso do job retirado da origem. A média no período
+// é queue_lag_seconds_sum / jobs_dequeued_total.
+func ObserveQueueLag(source string, lag time.Duration) {
+	v := new(expvar.Float)
+	v.Set(lag.Seconds())
+	queueLag.Set(source, v)
+	queueLagSum.AddFloat(source, lag.Seconds())
+	dequeued.Add(source, 1)
+}
+
+// IncJobsExpired contabiliza um job descartado por ter excedido a idade máxima.
+func IncJobsExpired(source string) {
+	expired.Add(source, 1)
+}
+
+// IncJobsProcessed contabiliza um job finalizado pelo consumer com o resultado
+// informado ("success", "error", "failed", "cancelled", "released" ou "deferred").
+func IncJobsProcessed(source, result string) {
+	processed.Add(source+"."+result, 1)
+}
+
+// SetQueueJobs atualiza os jobs em execução e retidos no consumer para a origem.
+func SetQueueJobs(source string, busy, held int) {
+	b := new(expvar.Int)
+	b.Set(int64(busy))
+	running.Set(source, b)
+	h := new(expvar.Int)
+	h.Set(int64(held))
+	waiting.Set(source, h)
+}
+
+// IncCloneFailures contabiliza um clone que falhou com a classe de erro
+// informada ("timeout", "too_large", "auth", "not_found" ou "other").
+func IncCloneFailures(class string) {
+	cloneFailed.Add(class, 1)
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"yourproject/internal/vault"
+)
+
+// azure atende o Azure DevOps Services e o Azure DevOps Server. O nome
+// completo é "organização/projeto/repo" (no Server, "coleção/projeto/repo").
+type azure struct {
+	base
+}
+
+func newAzure(b base) *azure {
+	if b.baseURL == "" {
+		b.baseURL = "https://dev.azure.com"
+	}
+	if b.baseURL == "https://dev.azure.com" {
+		b.sshHost = "ssh.dev.azure.com"
+	}
+	return &azure{base: b}
+}
+
+func (p *azure) CloneURL(fullName string) (string, error) {
+	parts, err := splitName(fullName, 3, "organização/projeto/repo")
+	if err != nil {
+		return "", err
+	}
+	return p.baseURL + "/" + parts[0] + "/" + parts[1] + "/_git/" + parts[2], nil
+}
+
+// SSHCloneURL usa o formato v3 do Azure DevOps Services ou, no Azure DevOps
+// Server, o mesmo caminho da URL web.
+func (p *azure) SSHCloneURL(fullName string) (string, error) {
+	parts, err := splitName(fullName, 3, "organização/projeto/repo")
+	if err != nil {
+		return "", err
+	}
+	if p.sshHost == "ssh.dev.azure.com" {
+		return "git@ssh.dev.azure.com:v3/" + fullName, nil
+	}
+	return "ssh://git@" + p.sshHost + "/" + parts[0] + "/" + parts[1] + "/_git/" + parts[2], nil
+}
+
+// Credentials: o Azure DevOps aceita qualquer usuário junto com o PAT.
+func (p *azure) Credentials(fullName string) (*vault.Credentials, error) {
+	return p.credentials(fullName, "pat")
+}
+
+func (p *azure) Repository(ctx context.Context, fullName string) (*Repository, error) {
+	parts, err := splitName(fullName, 3, "organização/projeto/repo")
+	if err != nil {
+		return nil, err
+	}
+	creds, err := p.Credentials(fullName)
+	if err != nil {
+		return nil, err
+	}
+	var repo struct {
+		ID            string `json:"id"`
+		Name          string `json:"name"`
+		Size          int64  `json:"size"` // Em bytes.
+		DefaultBranch string `json:"defaultBranch"`
+	}
+	u := p.baseURL + "/" + parts[0] + "/" + parts[1] + "/_apis/git/repositories/" + parts[2] + "?api-version=7.0"
+	err = p.getJSON(ctx, u, func(req *http.Request) {
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(":"+creds.Token)))
+	}, &repo)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{
+		ID:            repo.ID,
+		FullName:      parts[0] + "/" + parts[1] + "/" + repo.Name,
+		SizeKB:        int(repo.Size / 1024),
+		DefaultBranch: strings.TrimPrefix(repo.DefaultBranch, "refs/heads/"),
+	}, nil
+}
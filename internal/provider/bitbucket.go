@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"yourproject/internal/vault"
+)
+
+// bitbucketSSHPort é a porta SSH padrão do Bitbucket Server.
+const bitbucketSSHPort = "7999"
+
+// bitbucket atende o Bitbucket Server (Data Center). O nome completo é
+// "PROJETO/repo", com a chave do projeto.
+type bitbucket struct {
+	base
+}
+
+func (p *bitbucket) CloneURL(fullName string) (string, error) {
+	parts, err := splitName(fullName, 2, "PROJETO/repo")
+	if err != nil {
+		return "", err
+	}
+	return p.baseURL + "/scm/" + parts[0] + "/" + parts[1] + ".git", nil
+}
+
+func (p *bitbucket) SSHCloneURL(fullName string) (string, error) {
+	parts, err := splitName(fullName, 2, "PROJETO/repo")
+	if err != nil {
+		return "", err
+	}
+	return "ssh://git@" + p.sshHost + ":" + bitbucketSSHPort + "/" + strings.ToLower(parts[0]) + "/" + parts[1] + ".git", nil
+}
+
+// Credentials usa o usuário x-token-auth dos HTTP access tokens quando o
+// Vault não informa um usuário (tokens pessoais exigem o usuário do dono).
+func (p *bitbucket) Credentials(fullName string) (*vault.Credentials, error) {
+	return p.credentials(fullName, "x-token-auth")
+}
+
+// Repository consulta o repositório; o Bitbucket Server não informa tamanho
+// nem linguagem.
+func (p *bitbucket) Repository(ctx context.Context, fullName string) (*Repository, error) {
+	parts, err := splitName(fullName, 2, "PROJETO/repo")
+	if err != nil {
+		return nil, err
+	}
+	creds, err := p.Credentials(fullName)
+	if err != nil {
+		return nil, err
+	}
+	var repo struct {
+		ID      int64  `json:"id"`
+		Slug    string `json:"slug"`
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+	}
+	err = p.getJSON(ctx, p.baseURL+"/rest/api/1.0/projects/"+parts[0]+"/repos/"+parts[1], func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+creds.Token)
+	}, &repo)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{
+		ID:       strconv.FormatInt(repo.ID, 10),
+		FullName: repo.Project.Key + "/" + repo.Slug,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"yourproject/internal/vault"
+)
+
+// gitea atende instalações do Gitea (e do Forgejo). O nome completo é "dono/repo".
+type gitea struct {
+	base
+}
+
+func (p *gitea) CloneURL(fullName string) (string, error) {
+	if _, err := splitName(fullName, 2, "dono/repo"); err != nil {
+		return "", err
+	}
+	return p.baseURL + "/" + fullName + ".git", nil
+}
+
+func (p *gitea) SSHCloneURL(fullName string) (string, error) {
+	if _, err := splitName(fullName, 2, "dono/repo"); err != nil {
+		return "", err
+	}
+	return "git@" + p.sshHost + ":" + fullName + ".git", nil
+}
+
+// Credentials: o Gitea identifica o usuário pelo token, qualquer que seja o usuário informado.
+func (p *gitea) Credentials(fullName string) (*vault.Credentials, error) {
+	return p.credentials(fullName, "oauth2")
+}
+
+func (p *gitea) Repository(ctx context.Context, fullName string) (*Repository, error) {
+	if _, err := splitName(fullName, 2, "dono/repo"); err != nil {
+		return nil, err
+	}
+	creds, err := p.Credentials(fullName)
+	if err != nil {
+		return nil, err
+	}
+	var repo struct {
+		ID            int64  `json:"id"`
+		FullName      string `json:"full_name"`
+		Size          int    `json:"size"` // Em KB.
+		Language      string `json:"language"`
+		DefaultBranch string `json:"default_branch"`
+	}
+	err = p.getJSON(ctx, p.baseURL+"/api/v1/repos/"+fullName, func(req *http.Request) {
+		req.Header.Set("Authorization", "token "+creds.Token)
+	}, &repo)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{
+		ID:            strconv.FormatInt(repo.ID, 10),
+		FullName:      repo.FullName,
+		SizeKB:        repo.Size,
+		Language:      repo.Language,
+		DefaultBranch: repo.DefaultBranch,
+	}, nil
+}
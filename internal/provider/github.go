@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"yourproject/internal/github"
+	"yourproject/internal/vault"
+)
+
+// githubProvider atende o github.com e o GitHub Enterprise Server.
+type githubProvider struct {
+	base
+	apiURL string
+}
+
+func newGitHub(b base) *githubProvider {
+	p := &githubProvider{base: b, apiURL: b.baseURL + "/api/v3"}
+	if b.baseURL == "" {
+		p.baseURL = "https://github.com"
+		p.apiURL = github.DefaultBaseURL
+		p.sshHost = "github.com"
+	}
+	return p
+}
+
+func (p *githubProvider) CloneURL(fullName string) (string, error) {
+	if _, err := splitName(fullName, 2, "org/repo"); err != nil {
+		return "", err
+	}
+	return p.baseURL + "/" + fullName, nil
+}
+
+func (p *githubProvider) SSHCloneURL(fullName string) (string, error) {
+	if _, err := splitName(fullName, 2, "org/repo"); err != nil {
+		return "", err
+	}
+	return "git@" + p.sshHost + ":" + fullName + ".git", nil
+}
+
+func (p *githubProvider) Credentials(fullName string) (*vault.Credentials, error) {
+	return p.credentials(fullName, "x-access-token")
+}
+
+func (p *githubProvider) Repository(ctx context.Context, fullName string) (*Repository, error) {
+	if _, err := splitName(fullName, 2, "org/repo"); err != nil {
+		return nil, err
+	}
+	creds, err := p.Credentials(fullName)
+	if err != nil {
+		return nil, err
+	}
+	var repo github.Repository
+	err = p.getJSON(ctx, p.apiURL+"/repos/"+fullName, func(req *http.Request) {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", "Bearer "+creds.Token)
+	}, &repo)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{
+		ID:            strconv.FormatInt(repo.ID, 10),
+		FullName:      repo.FullName,
+		SizeKB:        repo.Size,
+		Language:      repo.Language,
+		DefaultBranch: repo.DefaultBranch,
+	}, nil
+}
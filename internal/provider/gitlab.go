@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"yourproject/internal/vault"
+)
+
+// gitlab atende o gitlab.com e instalações próprias. O nome completo inclui os
+// grupos e subgrupos (ex.: "grupo/subgrupo/repo").
+type gitlab struct {
+	base
+}
+
+func newGitLab(b base) *gitlab {
+	if b.baseURL == "" {
+		b.baseURL = "https://gitlab.com"
+		b.sshHost = "gitlab.com"
+	}
+	return &gitlab{base: b}
+}
+
+func (p *gitlab) CloneURL(fullName string) (string, error) {
+	return p.baseURL + "/" + fullName + ".git", nil
+}
+
+func (p *gitlab) SSHCloneURL(fullName string) (string, error) {
+	return "git@" + p.sshHost + ":" + fullName + ".git", nil
+}
+
+func (p *gitlab) Credentials(fullName string) (*vault.Credentials, error) {
+	return p.credentials(fullName, "oauth2")
+}
+
+func (p *gitlab) Repository(ctx context.Context, fullName string) (*Repository, error) {
+	creds, err := p.Credentials(fullName)
+	if err != nil {
+		return nil, err
+	}
+	var project struct {
+		ID                int64  `json:"id"`
+		PathWithNamespace string `json:"path_with_namespace"`
+		DefaultBranch     string `json:"default_branch"`
+		Statistics        struct {
+			RepositorySize int64 `json:"repository_size"` // Em bytes.
+		} `json:"statistics"`
+	}
+	u := p.baseURL + "/api/v4/projects/" + url.PathEscape(fullName) + "?statistics=true"
+	err = p.getJSON(ctx, u, func(req *http.Request) {
+		req.Header.Set("PRIVATE-TOKEN", creds.Token)
+	}, &project)
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{
+		ID:            strconv.FormatInt(project.ID, 10),
+		FullName:      project.PathWithNamespace,
+		SizeKB:        int(project.Statistics.RepositorySize / 1024),
+		DefaultBranch: project.DefaultBranch,
+	}, nil
+}
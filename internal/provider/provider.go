@@ -0,0 +1,210 @@
+// Package provider abstrai os serviços de hospedagem Git (GitHub, GitLab,
+// Bitbucket Server, Azure DevOps e Gitea): cada provedor monta a URL de clone,
+// escolhe as credenciais no Vault e consulta os metadados do repositório.
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"yourproject/internal/vault"
+)
+
+// Default é o provedor dos jobs sem o campo provider.
+const Default = "github"
+
+// apiTimeout limita cada consulta à API do provedor.
+const apiTimeout = 30 * time.Second
+
+// Tipos de provedor aceitos em New.
+const (
+	KindGitHub    = "github"
+	KindGitLab    = "gitlab"
+	KindBitbucket = "bitbucket-server"
+	KindAzure     = "azure-devops"
+	KindGitea     = "gitea"
+)
+
+// ErrUnknownProvider indica um job com provedor não configurado.
+var ErrUnknownProvider = errors.New("provedor Git não configurado")
+
+// Repository são os metadados de um repositório usados pelo serviço.
+type Repository struct {
+	ID            string
+	FullName      string
+	SizeKB        int // 0 se o provedor não informa o tamanho.
+	Language      string
+	DefaultBranch string
+}
+
+// Provider é um serviço de hospedagem Git configurado.
+type Provider interface {
+	// Name é o nome do provedor nos jobs (ex.: "gitlab-interno").
+	Name() string
+	// CloneURL monta a URL HTTPS de clone a partir do nome completo do
+	// repositório no formato do provedor (ex.: "grupo/subgrupo/repo").
+	CloneURL(fullName string) (string, error)
+	// SSHCloneURL monta a URL SSH de clone.
+	SSHCloneURL(fullName string) (string, error)
+	// Credentials retorna as credenciais HTTPS do repositório, com o usuário
+	// exigido pelo provedor quando o Vault só guarda o token.
+	Credentials(fullName string) (*vault.Credentials, error)
+	// SSHKey retorna a chave SSH cadastrada para o host SSH do provedor e a
+	// organização (primeiro segmento do nome completo); nil se não houver.
+	SSHKey(fullName string) (*vault.SSHKey, error)
+	// Repository consulta os metadados do repositório na API do provedor.
+	Repository(ctx context.Context, fullName string) (*Repository, error)
+}
+
+// New cria o provedor kind com o nome name. baseURL é o endereço web do
+// servidor (ex.: "https://gitlab.empresa.com"); vazio usa o serviço público.
+func New(kind, name, baseURL string, v vault.VaultClient) (Provider, error) {
+	b := base{name: name, baseURL: strings.TrimSuffix(baseURL, "/"), vault: v, client: &http.Client{Timeout: apiTimeout}}
+	if b.baseURL != "" {
+		u, err := url.Parse(b.baseURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("provedor %s: URL inválida %q", name, baseURL)
+		}
+		b.sshHost = u.Hostname()
+	}
+	switch kind {
+	case KindGitHub:
+		return newGitHub(b), nil
+	case KindGitLab:
+		return newGitLab(b), nil
+	case KindBitbucket:
+		if b.baseURL == "" {
+			return nil, fmt.Errorf("provedor %s: URL do Bitbucket Server obrigatória", name)
+		}
+		return &bitbucket{base: b}, nil
+	case KindAzure:
+		return newAzure(b), nil
+	case KindGitea:
+		if b.baseURL == "" {
+			return nil, fmt.Errorf("provedor %s: URL do Gitea obrigatória", name)
+		}
+		return &gitea{base: b}, nil
+	}
+	return nil, fmt.Errorf("provedor %s: tipo desconhecido %q (use %s, %s, %s, %s ou %s)",
+		name, kind, KindGitHub, KindGitLab, KindBitbucket, KindAzure, KindGitea)
+}
+
+// Registry guarda os provedores configurados por nome.
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry cria o registro com os provedores informados.
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// Get retorna o provedor pelo nome; vazio retorna o provedor Default.
+func (r *Registry) Get(name string) (Provider, error) {
+	if name == "" {
+		name = Default
+	}
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q (configurados: %s)", ErrUnknownProvider, name, strings.Join(r.Names(), ", "))
+	}
+	return p, nil
+}
+
+// Names lista os nomes dos provedores configurados em ordem alfabética.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// base reúne o que é comum aos provedores.
+type base struct {
+	name    string
+	baseURL string
+	sshHost string // Host do servidor SSH; por padrão, o mesmo da URL web.
+	vault   vault.VaultClient
+	client  *http.Client
+}
+
+func (b *base) Name() string {
+	return b.name
+}
+
+// credentials busca no Vault as credenciais do provedor para o dono do
+// repositório (primeiro segmento do nome completo), usando defaultUser quando o
+// usuário não está cadastrado.
+func (b *base) credentials(fullName, defaultUser string) (*vault.Credentials, error) {
+	owner, _, _ := strings.Cut(fullName, "/")
+	creds, err := b.vault.GetCredentials(b.name, owner)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao recuperar credenciais do provedor %s: %v", b.name, err)
+	}
+	if creds.Username == "" {
+		return &vault.Credentials{Username: defaultUser, Token: creds.Token}, nil
+	}
+	return creds, nil
+}
+
+func (b *base) SSHKey(fullName string) (*vault.SSHKey, error) {
+	org, _, _ := strings.Cut(fullName, "/")
+	key, err := b.vault.GetSSHKey(b.sshHost, org)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao recuperar chave SSH do provedor %s: %v", b.name, err)
+	}
+	return key, nil
+}
+
+// getJSON faz o GET em u com a autenticação aplicada por auth e decodifica o
+// JSON da resposta em out. A consulta termina com ctx ou após apiTimeout.
+func (b *base) getJSON(ctx context.Context, u string, auth func(req *http.Request), out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	auth(req)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao consultar %s: %v", b.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("API do provedor %s retornou %d: %s", b.name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("erro ao decodificar resposta de %s: %v", b.name, err)
+	}
+	return nil
+}
+
+// splitName separa o nome completo em n partes não vazias.
+func splitName(fullName string, n int, format string) ([]string, error) {
+	parts := strings.Split(fullName, "/")
+	if len(parts) != n {
+		return nil, fmt.Errorf("repositório %q não está no formato %s", fullName, format)
+	}
+	for _, p := range parts {
+		if p == "" {
+			return nil, fmt.Errorf("repositório %q não está no formato %s", fullName, format)
+		}
+	}
+	return parts, nil
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"yourproject/internal/vault"
+)
+
+type fakeVault struct{}
+
+func (fakeVault) GetGitHubCredentials() (*vault.GitHubCredentials, error) { return nil, nil }
+
+func (fakeVault) GetCredentials(provider, owner string) (*vault.Credentials, error) {
+	return &vault.Credentials{Token: "token-" + owner}, nil
+}
+
+func (fakeVault) GetSSHKey(host, org string) (*vault.SSHKey, error) { return nil, nil }
+
+func TestGiteaRepository(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != "token token-org":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/api/v1/repos/org/lento":
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		case r.URL.Path == "/api/v1/repos/org/repo":
+			w.Write([]byte(`{"id":7,"full_name":"org/repo","size":42,"language":"Go","default_branch":"main"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p, err := New(KindGitea, "gitea", srv.URL, fakeVault{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.(*gitea).client.Timeout = 50 * time.Millisecond
+
+	repo, err := p.Repository(context.Background(), "org/repo")
+	if err != nil || repo.ID != "7" || repo.SizeKB != 42 || repo.DefaultBranch != "main" {
+		t.Errorf("Repository = %+v, %v", repo, err)
+	}
+	if _, err := p.Repository(context.Background(), "org/inexistente"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("repositório inexistente: %v, esperado 404", err)
+	}
+
+	start := time.Now()
+	if _, err := p.Repository(context.Background(), "org/lento"); err == nil || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("consulta lenta terminou em %s com %v, esperado erro pelo timeout do cliente", time.Since(start), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.Repository(ctx, "org/repo"); err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Errorf("consulta com ctx cancelado: %v", err)
+	}
+}
@@ -1,51 +1,62 @@
-package scan
-
-import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"os/exec"
-	"time"
-
-	"yourproject/models"
-	"yourproject/internal/logger"
-)
-
-type GitleaksScanner struct {
-	GitleaksPath string
-}
-
-func (s *GitleaksScanner) Run(repoPath string) ([]models.GitleaksFinding, error) {
-	start := time.Now()
-	defer logger.Trace("RunGitleaks", start)
-
-	tempFile, err := os.CreateTemp("", "gitleaks_report_*.json")
-	if err != nil {
-		return nil, fmt.Errorf("erro ao criar arquivo temporário: %v", err)
-	}
-	reportPath := tempFile.Name()
-	tempFile.Close()
-	defer os.Remove(reportPath)
-
-	cmd := exec.Command(s.GitleaksPath,
-		"detect",
-		"--source="+repoPath,
-		"--report-format=json",
-		"--report-path="+reportPath,
-	)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("gitleaks detect falhou: %v, output: %s", err, string(output))
-	}
-
-	reportContent, err := os.ReadFile(reportPath)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao ler o relatório: %v", err)
-	}
-
-	var findings []models.GitleaksFinding
-	if err := json.Unmarshal(reportContent, &findings); err != nil {
-		return nil, fmt.Errorf("erro ao parsear JSON do gitleaks: %v", err)
-	}
-	return findings, nil
-}
+package scan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"yourproject/models"
+	"yourproject/internal/logger"
+)
+
+type GitleaksScanner struct {
+	GitleaksPath string
+}
+
+func (s *GitleaksScanner) Run(ctx context.Context, repoPath string, commits CommitRange, env []string) ([]models.GitleaksFinding, error) {
+	start := time.Now()
+	defer logger.Trace("RunGitleaks", start)
+
+	tempFile, err := os.CreateTemp("", "gitleaks_report_*.json")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar arquivo temporário: %v", err)
+	}
+	reportPath := tempFile.Name()
+	tempFile.Close()
+	defer os.Remove(reportPath)
+
+	args := []string{
+		"detect",
+		"--source=" + repoPath,
+		"--report-format=json",
+		"--report-path=" + reportPath,
+	}
+	if opts := commits.present(ctx, repoPath, env).logOpts(); opts != "" {
+		args = append(args, "--log-opts="+opts)
+	}
+	cmd := exec.CommandContext(ctx, s.GitleaksPath, args...)
+	cmd.Env = append(os.Environ(), env...)
+	// Se o contexto for cancelado, o processo é morto; aguarda no máximo mais 5s pela saída.
+	cmd.WaitDelay = 5 * time.Second
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("gitleaks detect interrompido: %w", context.Cause(ctx))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gitleaks detect falhou: %v, output: %s", err, string(output))
+	}
+
+	reportContent, err := os.ReadFile(reportPath)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler o relatório: %v", err)
+	}
+
+	var findings []models.GitleaksFinding
+	if err := json.Unmarshal(reportContent, &findings); err != nil {
+		return nil, fmt.Errorf("erro ao parsear JSON do gitleaks: %v", err)
+	}
+	return findings, nil
+}
@@ -1,8 +1,66 @@
-package scan
-
-import "yourproject/models"
-
-// Scanner define uma interface para executar o scanner.
-type Scanner interface {
-	Run(repoPath string) ([]models.GitleaksFinding, error)
-}
+package scan
+
+import (
+	"context"
+	"os"
+	"os/exec"
+
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+// Scanner define uma interface para executar o scanner.
+// A execução é interrompida quando ctx é cancelado. env é acrescentado ao
+// ambiente dos processos que leem o repositório (ver git.Clone.Env).
+type Scanner interface {
+	Run(ctx context.Context, repoPath string, commits CommitRange, env []string) ([]models.GitleaksFinding, error)
+}
+
+// zeroSHA é o "before" de pushes que criam uma branch.
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// CommitRange limita o scan aos commits após Before (exclusivo) até After.
+// O valor zero escaneia o histórico completo; sem Before (ou com o SHA zero de
+// uma branch nova) são escaneados todos os commits alcançáveis a partir de After.
+type CommitRange struct {
+	Before string
+	After  string
+}
+
+// present ajusta o intervalo aos commits presentes no clone em repoPath. Sem
+// After (ex.: head de um pull request de fork, que não está nas branches do
+// repositório) o histórico completo é escaneado; sem Before (ex.: force-push que
+// reescreveu a branch) são escaneados todos os commits alcançáveis a partir de After.
+func (c CommitRange) present(ctx context.Context, repoPath string, env []string) CommitRange {
+	if c.After == "" {
+		return c
+	}
+	if !hasCommit(ctx, repoPath, c.After, env) {
+		logger.Log.Warnf("Scanner: commit %s ausente do clone; escaneando o histórico completo", c.After)
+		return CommitRange{}
+	}
+	if c.Before != "" && c.Before != zeroSHA && !hasCommit(ctx, repoPath, c.Before, env) {
+		logger.Log.Warnf("Scanner: commit %s ausente do clone (force-push?); escaneando os commits alcançáveis a partir de %s", c.Before, c.After)
+		return CommitRange{After: c.After}
+	}
+	return c
+}
+
+// hasCommit indica se o commit sha existe no repositório em repoPath.
+func hasCommit(ctx context.Context, repoPath, sha string, env []string) bool {
+	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "cat-file", "-e", sha+"^{commit}")
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Run() == nil
+}
+
+// logOpts retorna o argumento de git log correspondente ao intervalo.
+func (c CommitRange) logOpts() string {
+	switch {
+	case c.After == "":
+		return ""
+	case c.Before == "" || c.Before == zeroSHA:
+		return c.After
+	default:
+		return c.Before + ".." + c.After
+	}
+}
@@ -0,0 +1,67 @@
+package scan
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCommitRangeLogOpts(t *testing.T) {
+	tests := []struct {
+		in   CommitRange
+		want string
+	}{
+		{in: CommitRange{}, want: ""},
+		{in: CommitRange{After: "b"}, want: "b"},
+		{in: CommitRange{Before: zeroSHA, After: "b"}, want: "b"},
+		{in: CommitRange{Before: "a", After: "b"}, want: "a..b"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.logOpts(); got != tt.want {
+			t.Errorf("%+v.logOpts() = %q, esperado %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// gitRepo cria um repositório com dois commits e retorna o caminho e os SHAs.
+func gitRepo(t *testing.T) (string, string, string) {
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	run("init", "-q")
+	run("commit", "-q", "--allow-empty", "-m", "primeiro")
+	first := run("rev-parse", "HEAD")
+	run("commit", "-q", "--allow-empty", "-m", "segundo")
+	return dir, first, run("rev-parse", "HEAD")
+}
+
+func TestCommitRangePresent(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git não encontrado")
+	}
+	dir, first, second := gitRepo(t)
+	missing := strings.Repeat("ab", 20)
+	tests := []struct {
+		name string
+		in   CommitRange
+		want CommitRange
+	}{
+		{name: "histórico completo", in: CommitRange{}, want: CommitRange{}},
+		{name: "intervalo presente", in: CommitRange{Before: first, After: second}, want: CommitRange{Before: first, After: second}},
+		{name: "branch nova", in: CommitRange{Before: zeroSHA, After: second}, want: CommitRange{Before: zeroSHA, After: second}},
+		{name: "force-push", in: CommitRange{Before: missing, After: second}, want: CommitRange{After: second}},
+		{name: "head ausente", in: CommitRange{Before: first, After: missing}, want: CommitRange{}},
+	}
+	for _, tt := range tests {
+		if got := tt.in.present(context.Background(), dir, nil); got != tt.want {
+			t.Errorf("%s: %+v, esperado %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule é uma expressão cron de 5 campos: minuto, hora, dia do mês, mês e
+// dia da semana. Suporta "*", listas (1,15), intervalos (1-5) e passos (*/10, 0-30/5).
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{0, 59}, // minuto
+	{0, 23}, // hora
+	{1, 31}, // dia do mês
+	{1, 12}, // mês
+	{0, 7},  // dia da semana (0 e 7 = domingo)
+}
+
+// ParseCron interpreta uma expressão cron de 5 campos.
+func ParseCron(expr string) (*CronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("expressão cron %q deve ter 5 campos", expr)
+	}
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("expressão cron %q: campo %d: %v", expr, i+1, err)
+		}
+		bits[i] = b
+	}
+	// Domingo pode ser 0 ou 7.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &CronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(parts[2], "*"),
+		dowStar: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			s, err := strconv.Atoi(item[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("passo inválido em %q", item)
+			}
+			rangePart, step = item[:i], s
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("intervalo inválido %q", rangePart)
+			}
+		default:
+			v, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("valor inválido %q", rangePart)
+			}
+			lo, hi = v, v
+			if step > 1 {
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%q fora do intervalo [%d, %d]", item, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next retorna o primeiro instante, em minutos cheios, estritamente após t que
+// satisfaz a expressão. Retorna o zero de time.Time se nada casar em 5 anos.
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches segue a semântica do cron: se dia do mês e dia da semana estão
+// restritos, basta um dos dois casar.
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
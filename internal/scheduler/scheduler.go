@@ -0,0 +1,171 @@
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"yourproject/internal/db"
+	"yourproject/internal/jobschema"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+const (
+	defaultPollInterval = 30 * time.Second
+	defaultBatchSize    = 100
+	// MaxJitter é o maior jitter de um agendamento: o atraso é aplicado pela
+	// fila (DelaySeconds da SQS), que aceita no máximo 15 minutos.
+	MaxJitter = 15 * time.Minute
+)
+
+// Enqueuer coloca um job no pipeline de scans.
+type Enqueuer interface {
+	// EnqueueAfter enfileira o job para ser entregue só após delay.
+	EnqueueAfter(job *models.ScanJob, delay time.Duration) error
+}
+
+// ScanStore cria a linha em scans antes de o job ser enfileirado e a marca como
+// "error" se o envio falhar.
+type ScanStore interface {
+	CreateScan(job *models.ScanJob, status string) error
+	UpdateScanStatus(scanID, status string) error
+}
+
+// Scheduler dispara os agendamentos vencidos. Cada disparo é protegido por um
+// advisory lock do Postgres, de forma que apenas uma réplica o execute.
+type Scheduler struct {
+	Store        db.ScheduleStore
+	Scans        ScanStore
+	Queue        Enqueuer
+	PollInterval time.Duration
+}
+
+// Start inicia a varredura periódica em background.
+func (s *Scheduler) Start() {
+	if s.PollInterval <= 0 {
+		s.PollInterval = defaultPollInterval
+	}
+	go func() {
+		for {
+			s.tick(time.Now())
+			time.Sleep(s.PollInterval)
+		}
+	}()
+}
+
+func (s *Scheduler) tick(now time.Time) {
+	due, err := s.Store.ListDueSchedules(now, defaultBatchSize)
+	if err != nil {
+		logger.Log.Errorf("Scheduler: erro ao listar agendamentos vencidos: %v", err)
+		return
+	}
+	for _, sched := range due {
+		if err := s.fire(sched, now); err != nil {
+			logger.Log.Errorf("Scheduler: erro ao disparar agendamento %s: %v", sched.ID, err)
+		}
+	}
+}
+
+// fire avança o agendamento e enfileira os jobs. O avanço acontece antes do
+// enfileiramento: uma falha no meio perde no máximo este disparo, nunca duplica.
+// Com jitter, cada job recebe um atraso aleatório próprio, para que os
+// repositórios de uma sigla não sejam escaneados todos no mesmo minuto. Os jobs
+// são enfileirados na hora e o atraso fica a cargo da fila, de forma que um
+// restart depois do disparo não perde os jobs que ainda aguardam o atraso.
+func (s *Scheduler) fire(sched models.ScanSchedule, now time.Time) error {
+	unlock, ok, err := s.Store.TryScheduleLock(sched.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		logger.Log.Debugf("Scheduler: agendamento %s sendo disparado por outra réplica", sched.ID)
+		return nil
+	}
+	defer unlock()
+
+	next, err := NextRun(sched.CronExpr, now)
+	if err != nil {
+		return err
+	}
+	advanced, err := s.Store.AdvanceSchedule(sched.ID, sched.NextRunAt, next)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return nil
+	}
+
+	targets, err := s.targets(sched)
+	if err != nil {
+		return err
+	}
+	// Agendamentos criados antes do limite têm o jitter reduzido a MaxJitter.
+	jitter := min(sched.JitterSeconds, int(MaxJitter.Seconds()))
+	for _, job := range targets {
+		var delay time.Duration
+		if jitter > 0 {
+			delay = time.Duration(rand.Intn(jitter+1)) * time.Second
+		}
+		s.dispatch(job, delay)
+	}
+	logger.Log.Infof("Scheduler: agendamento %s disparou %d jobs (jitter de até %ds); próximo disparo em %s",
+		sched.ID, len(targets), jitter, next.Format(time.RFC3339))
+	return nil
+}
+
+// dispatch cria o scan e enfileira o job para ser entregue após delay. Se o
+// envio falhar, o scan é marcado como "error" em vez de ficar em "queued" para
+// sempre. A idade do job conta a partir do fim do atraso.
+func (s *Scheduler) dispatch(job *models.ScanJob, delay time.Duration) {
+	job.MessageCreatedAt = time.Now().Add(delay).UTC()
+	if err := s.Scans.CreateScan(job, "queued"); err != nil {
+		logger.Log.Errorf("Scheduler: %v", err)
+		return
+	}
+	if err := s.Queue.EnqueueAfter(job, delay); err != nil {
+		logger.Log.Errorf("Scheduler: erro ao enfileirar job %s: %v", job.ScanID, err)
+		if err := s.Scans.UpdateScanStatus(job.ScanID, "error"); err != nil {
+			logger.Log.Errorf("Scheduler: %v", err)
+		}
+	}
+}
+
+// targets monta os jobs do disparo: o repositório do agendamento ou todos os
+// repositórios conhecidos da sigla.
+func (s *Scheduler) targets(sched models.ScanSchedule) ([]*models.ScanJob, error) {
+	repos := []models.ScanJob{{
+		RepositoryID:       sched.RepositoryID,
+		RepositoryFullName: sched.RepositoryFullName,
+		Sigla:              sched.Sigla,
+	}}
+	if sched.RepositoryID == "" {
+		var err error
+		repos, err = s.Store.ListRepositoriesBySigla(sched.Sigla)
+		if err != nil {
+			return nil, err
+		}
+	}
+	jobs := make([]*models.ScanJob, 0, len(repos))
+	for _, repo := range repos {
+		job := repo
+		job.SchemaVersion = jobschema.CurrentVersion
+		job.ScanID = uuid.New().String()
+		jobs = append(jobs, &job)
+	}
+	return jobs, nil
+}
+
+// NextRun calcula o próximo disparo da expressão cron após now.
+func NextRun(cronExpr string, now time.Time) (time.Time, error) {
+	cron, err := ParseCron(cronExpr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := cron.Next(now)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("expressão cron %q nunca dispara", cronExpr)
+	}
+	return next, nil
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"yourproject/models"
+)
+
+type fakeScans struct {
+	created map[string]bool
+	status  map[string]string
+}
+
+func (s *fakeScans) CreateScan(job *models.ScanJob, status string) error {
+	s.created[job.ScanID] = true
+	s.status[job.ScanID] = status
+	return nil
+}
+
+func (s *fakeScans) UpdateScanStatus(scanID, status string) error {
+	s.status[scanID] = status
+	return nil
+}
+
+type fakeQueue struct {
+	err   error
+	delay time.Duration
+}
+
+func (q *fakeQueue) EnqueueAfter(job *models.ScanJob, delay time.Duration) error {
+	q.delay = delay
+	return q.err
+}
+
+func TestDispatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		queueErr error
+		want     string
+	}{
+		{name: "enfileirado", want: "queued"},
+		{name: "falha no envio", queueErr: errors.New("fila indisponível"), want: "error"},
+	}
+	for _, tt := range tests {
+		scans := &fakeScans{created: map[string]bool{}, status: map[string]string{}}
+		queue := &fakeQueue{err: tt.queueErr}
+		s := &Scheduler{Scans: scans, Queue: queue}
+		job := &models.ScanJob{ScanID: "scan"}
+		s.dispatch(job, time.Minute)
+		if !scans.created["scan"] || scans.status["scan"] != tt.want {
+			t.Errorf("%s: status %q, esperado %q", tt.name, scans.status["scan"], tt.want)
+		}
+		// O scan é criado e o job enfileirado na hora; o atraso fica com a fila.
+		if queue.delay != time.Minute {
+			t.Errorf("%s: enfileirado com atraso %s, esperado 1m0s", tt.name, queue.delay)
+		}
+		if job.MessageCreatedAt.Before(time.Now().Add(50 * time.Second)) {
+			t.Errorf("%s: MessageCreatedAt %s não conta a partir do fim do atraso", tt.name, job.MessageCreatedAt)
+		}
+	}
+}
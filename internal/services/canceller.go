@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"yourproject/internal/db"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+const defaultCancelPollInterval = 5 * time.Second
+
+// ErrScanCancelled é a causa do cancelamento de um scan pedido via API ou mensagem de controle.
+var ErrScanCancelled = errors.New("scan cancelado")
+
+// ErrUnknownControl indica uma mensagem de controle de tipo desconhecido.
+var ErrUnknownControl = errors.New("tipo de mensagem de controle desconhecido")
+
+// ControlHandler trata mensagens de controle recebidas junto com os jobs.
+type ControlHandler interface {
+	HandleControl(msg *models.ControlMessage) error
+}
+
+// Canceller mantém os contextos dos scans em execução nesta réplica e os cancela
+// quando há um pedido de cancelamento, local ou registrado no banco por outra réplica.
+type Canceller struct {
+	Store        db.ScanStore
+	PollInterval time.Duration
+
+	mu      sync.Mutex
+	running map[string]context.CancelCauseFunc
+}
+
+// Track cria o contexto cancelável do scan; release deve ser chamado ao final.
+func (c *Canceller) Track(parent context.Context, scanID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	c.mu.Lock()
+	if c.running == nil {
+		c.running = make(map[string]context.CancelCauseFunc)
+	}
+	c.running[scanID] = cancel
+	c.mu.Unlock()
+
+	release := func() {
+		c.mu.Lock()
+		delete(c.running, scanID)
+		c.mu.Unlock()
+		cancel(nil)
+	}
+	return ctx, release
+}
+
+// Cancel registra o pedido no banco e interrompe o scan se ele roda nesta réplica.
+// Retorna false se o scan não está em execução.
+func (c *Canceller) Cancel(scanID string) (bool, error) {
+	requested, err := c.Store.RequestCancel(scanID)
+	if err != nil {
+		return false, err
+	}
+	if c.cancelLocal(scanID) {
+		return true, nil
+	}
+	return requested, nil
+}
+
+// HandleControl trata mensagens {"type":"cancel","scan_id":...}: scans na fila
+// são cancelados direto; scans em execução são interrompidos.
+func (c *Canceller) HandleControl(msg *models.ControlMessage) error {
+	if msg.Type != models.ControlCancel {
+		return fmt.Errorf("%w: %q", ErrUnknownControl, msg.Type)
+	}
+	cancelled, err := c.Store.CancelQueuedScan(msg.ScanID)
+	if err != nil || cancelled {
+		return err
+	}
+	if _, err := c.Cancel(msg.ScanID); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *Canceller) cancelLocal(scanID string) bool {
+	c.mu.Lock()
+	cancel, ok := c.running[scanID]
+	c.mu.Unlock()
+	if ok {
+		logger.Log.Infof("Canceller: cancelando scan %s", scanID)
+		cancel(ErrScanCancelled)
+	}
+	return ok
+}
+
+// Start observa periodicamente os pedidos de cancelamento dos scans locais.
+func (c *Canceller) Start() {
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultCancelPollInterval
+	}
+	go func() {
+		for {
+			time.Sleep(c.PollInterval)
+			c.poll()
+		}
+	}()
+}
+
+func (c *Canceller) poll() {
+	c.mu.Lock()
+	ids := make([]string, 0, len(c.running))
+	for id := range c.running {
+		ids = append(ids, id)
+	}
+	c.mu.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+
+	requested, err := c.Store.ListCancelRequested(ids)
+	if err != nil {
+		logger.Log.Errorf("Canceller: %v", err)
+		return
+	}
+	for _, id := range requested {
+		c.cancelLocal(id)
+	}
+}
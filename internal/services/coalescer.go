@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"yourproject/internal/db"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+const (
+	defaultLeaseTTL     = 2 * time.Minute
+	defaultPollInterval = 5 * time.Second
+)
+
+// RepoCoalescer garante um único scan em andamento por repositório (ou por
+// intervalo de commits, veja ScanJob.LeaseKey) entre todas as réplicas. Jobs que
+// chegam enquanto o repositório já está sendo escaneado são anexados ao scan
+// líder e recebem o mesmo resultado quando ele termina.
+type RepoCoalescer struct {
+	Store    db.LeaseStore
+	Owner    string        // Identificador da réplica (padrão: hostname-pid).
+	LeaseTTL time.Duration // Validade do lease; renovado a cada TTL/3 enquanto o scan roda.
+	// PollInterval é o intervalo com que um job anexado consulta o resultado do
+	// líder (padrão: 5s).
+	PollInterval time.Duration
+}
+
+// Run executa process se o job se tornar líder do repositório. Se o job for
+// anexado a outro scan, Run aguarda o resultado do líder ocupando o worker, para
+// que a entrega só seja confirmada depois dele: retorna nil se o líder terminou
+// com sucesso, ErrScanCancelled se ele foi cancelado e ErrPermanentFailure se
+// falhou de vez. Se o líder for interrompido ou falhar com novas tentativas
+// pendentes, o job é desanexado e volta a disputar o lease. Se ctx terminar
+// durante a espera, o job é desanexado e a causa é retornada.
+func (c *RepoCoalescer) Run(ctx context.Context, job *models.ScanJob, process func() error) error {
+	if c.LeaseTTL <= 0 {
+		c.LeaseTTL = defaultLeaseTTL
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultPollInterval
+	}
+	if c.Owner == "" {
+		host, _ := os.Hostname()
+		c.Owner = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(c.PollInterval)
+	defer ticker.Stop()
+	var following string
+	for {
+		// Reentregas de um job anexado cujo líder já terminou só leem o resultado.
+		outcome, err := c.Store.FollowerOutcome(job)
+		if err != nil {
+			return err
+		}
+		if outcome != "" {
+			return followerResult(job, outcome)
+		}
+
+		// Repetido a cada consulta: assume o lease se o líder morreu ou se o job
+		// foi desanexado, e se anexa ao novo líder se outro job o assumiu.
+		leader, err := c.Store.AcquireOrAttach(job, c.Owner, c.LeaseTTL)
+		if err != nil {
+			return err
+		}
+		if leader == job.ScanID {
+			return c.lead(job, process)
+		}
+		if leader != following {
+			logger.Log.Infof("Coalescer: job %s anexado ao scan %s em andamento para o repositório %s", job.ScanID, leader, job.RepositoryFullName)
+			following = leader
+		}
+
+		select {
+		case <-ctx.Done():
+			return c.detach(ctx, job, start)
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead executa process com o lease do repositório e repassa o resultado aos
+// scans anexados.
+func (c *RepoCoalescer) lead(job *models.ScanJob, process func() error) error {
+	stop := make(chan struct{})
+	go c.renew(job, stop)
+	procErr := process()
+	close(stop)
+
+	// Cancelamento e falha permanente também são resultados finais e são
+	// repassados aos scans anexados; nos demais casos eles voltam a disputar o lease.
+	final := procErr == nil || errors.Is(procErr, ErrScanCancelled) || errors.Is(procErr, ErrPermanentFailure)
+	followers, err := c.Store.ReleaseLease(job, final)
+	switch {
+	case err != nil:
+		logger.Log.Errorf("Coalescer: erro ao liberar lease do repositório %s: %v", job.RepositoryFullName, err)
+	case len(followers) > 0 && final:
+		logger.Log.Infof("Coalescer: resultado do scan %s aplicado a %d scans anexados", job.ScanID, len(followers))
+	case len(followers) > 0:
+		logger.Log.Infof("Coalescer: %d scans anexados ao scan %s desanexados", len(followers), job.ScanID)
+	}
+	return procErr
+}
+
+// followerResult converte o status final do líder no resultado do job anexado.
+func followerResult(job *models.ScanJob, outcome string) error {
+	switch outcome {
+	case "success":
+		logger.Log.Infof("Coalescer: job %s concluído pelo scan líder", job.ScanID)
+		return nil
+	case "cancelled":
+		return ErrScanCancelled
+	}
+	return fmt.Errorf("%w: scan líder do job %s terminou com status %q", ErrPermanentFailure, job.ScanID, outcome)
+}
+
+// detach desanexa o job interrompido enquanto aguardava o líder. Cancelamentos
+// pedidos pelo usuário marcam o scan como "cancelled"; nos demais casos ele
+// volta a "queued".
+func (c *RepoCoalescer) detach(ctx context.Context, job *models.ScanJob, start time.Time) error {
+	cause := context.Cause(ctx)
+	status := "queued"
+	var event *models.ScanEvent
+	if errors.Is(cause, ErrScanCancelled) {
+		status = "cancelled"
+		if EnableEvents() {
+			event = newScanEvent(job, models.EventScanCancelled, status, nil, start, cause)
+		}
+	}
+	if err := c.Store.DetachFollower(job, status, event); err != nil {
+		logger.Log.Errorf("Coalescer: erro ao desanexar scan %s: %v", job.ScanID, err)
+	}
+	if errors.Is(cause, ErrScanCancelled) {
+		return ErrScanCancelled
+	}
+	return fmt.Errorf("Coalescer: job %s interrompido: %w", job.ScanID, cause)
+}
+
+func (c *RepoCoalescer) renew(job *models.ScanJob, stop <-chan struct{}) {
+	ticker := time.NewTicker(c.LeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.Store.RenewLease(job, c.LeaseTTL); err != nil {
+				logger.Log.Warnf("Coalescer: %v", err)
+			}
+		}
+	}
+}
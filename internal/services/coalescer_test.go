@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"yourproject/models"
+)
+
+// fakeLeaseStore devolve, a cada chamada, o próximo líder e o próximo resultado
+// das listas; ao fim delas repete o último valor.
+type fakeLeaseStore struct {
+	leaders  []string
+	outcomes []string
+
+	acquired int
+	released []bool
+	detached string
+}
+
+// pop retorna o primeiro valor de values e o remove, exceto o último.
+func pop(values *[]string) string {
+	if len(*values) == 0 {
+		return ""
+	}
+	v := (*values)[0]
+	if len(*values) > 1 {
+		*values = (*values)[1:]
+	}
+	return v
+}
+
+func (s *fakeLeaseStore) AcquireOrAttach(job *models.ScanJob, owner string, ttl time.Duration) (string, error) {
+	s.acquired++
+	return pop(&s.leaders), nil
+}
+
+func (s *fakeLeaseStore) RenewLease(job *models.ScanJob, ttl time.Duration) error { return nil }
+
+func (s *fakeLeaseStore) ReleaseLease(job *models.ScanJob, final bool) ([]string, error) {
+	s.released = append(s.released, final)
+	return nil, nil
+}
+
+func (s *fakeLeaseStore) FollowerOutcome(job *models.ScanJob) (string, error) {
+	return pop(&s.outcomes), nil
+}
+
+func (s *fakeLeaseStore) DetachFollower(job *models.ScanJob, status string, event *models.ScanEvent) error {
+	s.detached = status
+	return nil
+}
+
+func TestRepoCoalescerLeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantFinal bool
+	}{
+		{name: "sucesso", wantFinal: true},
+		{name: "cancelado", err: ErrScanCancelled, wantFinal: true},
+		{name: "falha permanente", err: fmt.Errorf("%w: provedor", ErrPermanentFailure), wantFinal: true},
+		{name: "falha transitória", err: errors.New("timeout"), wantFinal: false},
+		{name: "encerramento", err: fmt.Errorf("interrompido: %w", ErrShutdown), wantFinal: false},
+	}
+	for _, tt := range tests {
+		store := &fakeLeaseStore{leaders: []string{"scan"}}
+		c := &RepoCoalescer{Store: store, PollInterval: time.Millisecond}
+		err := c.Run(context.Background(), &models.ScanJob{ScanID: "scan"}, func() error { return tt.err })
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: erro %v, esperado %v", tt.name, err, tt.err)
+		}
+		if len(store.released) != 1 || store.released[0] != tt.wantFinal {
+			t.Errorf("%s: ReleaseLease chamado com %v, esperado [%v]", tt.name, store.released, tt.wantFinal)
+		}
+	}
+}
+
+func TestRepoCoalescerFollower(t *testing.T) {
+	tests := []struct {
+		name     string
+		leaders  []string
+		outcomes []string
+		wantErr  error
+	}{
+		{name: "líder com sucesso", leaders: []string{"leader"}, outcomes: []string{"", "", "success"}},
+		{name: "líder cancelado", leaders: []string{"leader"}, outcomes: []string{"", "cancelled"}, wantErr: ErrScanCancelled},
+		{name: "líder com falha permanente", leaders: []string{"leader"}, outcomes: []string{"", "error"}, wantErr: ErrPermanentFailure},
+		{name: "reentrega após o líder", outcomes: []string{"success"}},
+	}
+	for _, tt := range tests {
+		store := &fakeLeaseStore{leaders: tt.leaders, outcomes: tt.outcomes}
+		c := &RepoCoalescer{Store: store, PollInterval: time.Millisecond}
+		processed := false
+		err := c.Run(context.Background(), &models.ScanJob{ScanID: "scan"}, func() error {
+			processed = true
+			return nil
+		})
+		if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+			t.Errorf("%s: erro %v, esperado %v", tt.name, err, tt.wantErr)
+		}
+		if processed {
+			t.Errorf("%s: job anexado foi processado", tt.name)
+		}
+		if len(store.released) != 0 {
+			t.Errorf("%s: job anexado liberou o lease", tt.name)
+		}
+	}
+}
+
+// Um job desanexado (o líder falhou com novas tentativas pendentes) volta a
+// disputar o lease e roda o scan ele mesmo.
+func TestRepoCoalescerFollowerDetached(t *testing.T) {
+	store := &fakeLeaseStore{leaders: []string{"leader", "leader", "scan"}}
+	c := &RepoCoalescer{Store: store, PollInterval: time.Millisecond}
+	processed := false
+	if err := c.Run(context.Background(), &models.ScanJob{ScanID: "scan"}, func() error {
+		processed = true
+		return nil
+	}); err != nil {
+		t.Fatalf("erro inesperado: %v", err)
+	}
+	if !processed || store.acquired != 3 {
+		t.Errorf("processado=%v após %d chamadas de AcquireOrAttach, esperado true após 3", processed, store.acquired)
+	}
+	if len(store.released) != 1 {
+		t.Errorf("ReleaseLease chamado %d vezes, esperado 1", len(store.released))
+	}
+}
+
+func TestRepoCoalescerFollowerInterrupted(t *testing.T) {
+	tests := []struct {
+		name       string
+		cause      error
+		wantErr    error
+		wantStatus string
+	}{
+		{name: "encerramento", cause: ErrShutdown, wantErr: ErrShutdown, wantStatus: "queued"},
+		{name: "cancelado", cause: ErrScanCancelled, wantErr: ErrScanCancelled, wantStatus: "cancelled"},
+	}
+	for _, tt := range tests {
+		store := &fakeLeaseStore{leaders: []string{"leader"}}
+		c := &RepoCoalescer{Store: store, PollInterval: time.Hour}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(tt.cause)
+		err := c.Run(ctx, &models.ScanJob{ScanID: "scan"}, func() error { return nil })
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: erro %v, esperado %v", tt.name, err, tt.wantErr)
+		}
+		if store.detached != tt.wantStatus {
+			t.Errorf("%s: scan desanexado como %q, esperado %q", tt.name, store.detached, tt.wantStatus)
+		}
+	}
+}
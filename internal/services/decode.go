@@ -0,0 +1,44 @@
+package services
+
+import (
+	"encoding/json"
+
+	"yourproject/internal/db"
+	"yourproject/internal/jobschema"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+// decodeJob decodifica e valida uma mensagem de job. Quando a mensagem é legível
+// mas inválida, o motivo da rejeição é gravado no scan correspondente e o job
+// parcialmente decodificado é retornado junto com o erro.
+func decodeJob(body []byte, scans db.ScanStore) (*models.ScanJob, error) {
+	job, err := jobschema.Decode(body)
+	if err == nil {
+		return job, nil
+	}
+	if job != nil && job.ScanID != "" && scans != nil {
+		if rejErr := scans.RejectScan(job.ScanID, err.Error()); rejErr != nil {
+			logger.Log.Errorf("Erro ao gravar rejeição do scan %s: %v", job.ScanID, rejErr)
+		}
+	}
+	return job, err
+}
+
+// rejectedScanID retorna o ScanID de um job rejeitado, se foi possível lê-lo.
+func rejectedScanID(job *models.ScanJob) string {
+	if job == nil {
+		return ""
+	}
+	return job.ScanID
+}
+
+// decodeControl identifica mensagens de controle, que trazem o campo "type" e
+// não são ScanJobs.
+func decodeControl(body []byte) (*models.ControlMessage, bool) {
+	var msg models.ControlMessage
+	if err := json.Unmarshal(body, &msg); err != nil || msg.Type == "" {
+		return nil, false
+	}
+	return &msg, true
+}
@@ -1,35 +1,345 @@
-package services
-
-import (
-	"database/sql"
-	"sync"
-
-	"yourproject/models"
-	"yourproject/internal/logger"
-)
-
-type JobConsumer interface {
-	Start(jobChan <-chan *models.ScanJob, dbConn *sql.DB, gitClient GitClient, scanner Scanner, cloneMaxConc, numWorkers int)
-}
-
-type DefaultJobConsumer struct{}
-
-func (c *DefaultJobConsumer) Start(jobChan <-chan *models.ScanJob, dbConn *sql.DB, gitClient GitClient, scanner Scanner, cloneMaxConc, numWorkers int) {
-	cloneSem := make(chan struct{}, cloneMaxConc)
-	var wg sync.WaitGroup
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			for job := range jobChan {
-				logger.Log.Debugf("[Consumer Worker %d] Processando job: %s", workerID, job.ScanID)
-				if err := ProcessJob(job, dbConn, gitClient, scanner, cloneSem); err != nil {
-					logger.Log.Errorf("[Consumer Worker %d] Erro no job %s: %v", workerID, job.ScanID, err)
-				} else {
-					logger.Log.Debugf("[Consumer Worker %d] Job %s finalizado com sucesso", workerID, job.ScanID)
-				}
-			}
-		}(i)
-	}
-	wg.Wait()
-}
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"yourproject/internal/logger"
+	"yourproject/internal/metrics"
+	"yourproject/internal/provider"
+	"yourproject/internal/workspace"
+)
+
+const (
+	defaultDrainTimeout = 25 * time.Second
+	// diskRetryDelay é o atraso de um job adiado por falta de espaço em disco.
+	diskRetryDelay = time.Minute
+)
+
+// ErrShutdown é a causa do cancelamento dos jobs que não terminaram dentro do
+// prazo de encerramento; eles voltam à origem e o scan volta a "queued".
+var ErrShutdown = errors.New("serviço encerrando")
+
+// ErrPermanentFailure indica uma falha que se repetiria em novas tentativas
+// (ex.: provedor não configurado); o scan já foi marcado como "error" e o job é
+// confirmado sem nova tentativa.
+var ErrPermanentFailure = errors.New("falha permanente")
+
+type JobConsumer interface {
+	Start(ctx context.Context, jobChan <-chan *Delivery, dbConn *sql.DB, gitClient GitClient, scanner Scanner, cloneMaxConc, numWorkers int)
+}
+
+// QueueLimit limita o uso dos workers pelos jobs de uma origem (Delivery.Source).
+type QueueLimit struct {
+	MaxConcurrency int // Máximo de jobs da origem em execução simultânea (0 = sem limite).
+	Reserved       int // Workers reservados para a origem, que as demais não podem ocupar.
+}
+
+type DefaultJobConsumer struct {
+	// Coalescer, se definido, agrupa jobs simultâneos do mesmo repositório.
+	Coalescer *RepoCoalescer
+	// Canceller, se definido, permite cancelar scans em execução.
+	Canceller *Canceller
+	// Limits define a concorrência máxima e a reserva de workers por origem.
+	// Origens ausentes não têm limite nem reserva.
+	Limits map[string]QueueLimit
+	// DrainTimeout é o prazo, após o início do encerramento, para os jobs em
+	// execução terminarem antes de serem interrompidos e devolvidos.
+	DrainTimeout time.Duration
+	// Workspaces cria e remove os diretórios dos clones; nil usa os.TempDir()
+	// sem limite de espaço livre.
+	Workspaces *workspace.Manager
+	// Providers resolve o provedor Git de cada job; nil clona tudo do GitHub.
+	Providers *provider.Registry
+
+	mu      sync.Mutex
+	workers int             // Workers iniciados.
+	running int             // Jobs em execução.
+	busy    map[string]int  // Jobs em execução por origem.
+	held    map[string]int  // Jobs recebidos aguardando um worker, por origem.
+	groups  map[string]bool // Grupos (Delivery.GroupID) com job em execução.
+	wake    chan struct{}
+	jobCtx  context.Context // Contexto dos jobs; cancelado com ErrShutdown ao fim do prazo.
+}
+
+// FreeWorkersFor retorna quantos jobs da origem ainda cabem no consumer,
+// respeitando o limite dela e as reservas das demais; dimensiona o prefetch.
+func (c *DefaultJobConsumer) FreeWorkersFor(source string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	held := 0
+	for _, n := range c.held {
+		held += n
+	}
+	free := c.workers - c.running - held - c.unmetReservations(source, true)
+	if limit := c.Limits[source].MaxConcurrency; limit > 0 && limit-c.busy[source]-c.held[source] < free {
+		free = limit - c.busy[source] - c.held[source]
+	}
+	if free < 0 {
+		return 0
+	}
+	return free
+}
+
+// Start recebe as entregas e as distribui entre numWorkers workers, retendo as
+// que excedem o limite da origem ou ocupariam workers reservados a outra origem.
+// Cada origem retém no máximo numWorkers entregas; as excedentes são devolvidas,
+// para que uma origem travada no seu limite não impeça o recebimento das demais.
+// Quando ctx é cancelado, as entregas retidas e as que ainda chegarem são
+// devolvidas às origens, e os jobs em execução têm até DrainTimeout para
+// terminar; Start retorna quando todos terminaram ou foram devolvidos.
+func (c *DefaultJobConsumer) Start(ctx context.Context, jobChan <-chan *Delivery, dbConn *sql.DB, gitClient GitClient, scanner Scanner, cloneMaxConc, numWorkers int) {
+	c.init(numWorkers)
+	if c.DrainTimeout <= 0 {
+		c.DrainTimeout = defaultDrainTimeout
+	}
+	jobCtx, abort := context.WithCancelCause(context.Background())
+	defer abort(nil)
+	c.jobCtx = jobCtx
+	workspaces := c.Workspaces
+	if workspaces == nil {
+		workspaces = &workspace.Manager{}
+	}
+	cloneSem := make(chan struct{}, cloneMaxConc)
+	process := func(ctx context.Context, delivery *Delivery) error {
+		return ProcessJob(ctx, delivery.Job, delivery.LastAttempt, dbConn, gitClient, c.Providers, workspaces, scanner, cloneSem)
+	}
+	workerIDs := make(chan int, numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		workerIDs <- i
+	}
+
+	var (
+		wg       sync.WaitGroup
+		held     []*Delivery
+		shutdown = ctx.Done()
+		draining bool
+	)
+	for jobChan != nil || len(held) > 0 {
+		// No encerramento recebe tudo para devolver até as origens fecharem.
+		select {
+		case delivery, ok := <-jobChan:
+			if !ok {
+				jobChan = nil
+				break
+			}
+			if draining {
+				delivery.Release()
+				break
+			}
+			if !c.hold(delivery.Source) {
+				logger.Log.Debugf("Consumer: origem %s já retém %d jobs; job %s devolvido", delivery.Source, numWorkers, delivery.Job.ScanID)
+				delivery.Release()
+				break
+			}
+			held = append(held, delivery)
+		case <-c.wake:
+		case <-shutdown:
+			shutdown = nil
+			draining = true
+			logger.Log.Infof("Consumer: encerrando; %d jobs em execução têm até %s para terminar, %d retidos devolvidos",
+				c.runningJobs(), c.DrainTimeout, len(held))
+			held = c.releaseHeld(held)
+			timer := time.AfterFunc(c.DrainTimeout, func() {
+				logger.Log.Warnf("Consumer: prazo de encerramento esgotado; interrompendo %d jobs", c.runningJobs())
+				abort(ErrShutdown)
+			})
+			defer timer.Stop()
+		}
+		if !draining {
+			held = c.startAdmitted(held, workerIDs, &wg, process)
+		}
+	}
+	wg.Wait()
+}
+
+// releaseHeld devolve às origens as entregas que aguardavam um worker.
+func (c *DefaultJobConsumer) releaseHeld(held []*Delivery) []*Delivery {
+	for _, delivery := range held {
+		c.mu.Lock()
+		c.held[delivery.Source]--
+		metrics.SetQueueJobs(delivery.Source, c.busy[delivery.Source], c.held[delivery.Source])
+		c.mu.Unlock()
+		delivery.Release()
+	}
+	return held[:0]
+}
+
+func (c *DefaultJobConsumer) runningJobs() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.running
+}
+
+func (c *DefaultJobConsumer) init(numWorkers int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.workers = numWorkers
+	c.busy = make(map[string]int)
+	c.held = make(map[string]int)
+	c.groups = make(map[string]bool)
+	c.wake = make(chan struct{}, 1)
+
+	reserved := 0
+	for _, l := range c.Limits {
+		reserved += l.Reserved
+	}
+	if reserved >= numWorkers {
+		logger.Log.Warnf("Consumer: %d workers reservados de %d; origens sem reserva não serão processadas", reserved, numWorkers)
+	}
+}
+
+// startAdmitted inicia, em ordem de chegada, as entregas retidas que cabem nos
+// limites das suas origens e retorna as que continuam aguardando. Jobs de um
+// mesmo grupo rodam um de cada vez e nunca ultrapassam um anterior do grupo.
+func (c *DefaultJobConsumer) startAdmitted(held []*Delivery, workerIDs chan int, wg *sync.WaitGroup, process func(context.Context, *Delivery) error) []*Delivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	waiting := held[:0]
+	blocked := make(map[string]bool)
+	for _, delivery := range held {
+		group := delivery.GroupID
+		if (group != "" && (c.groups[group] || blocked[group])) || !c.admit(delivery.Source) {
+			if group != "" {
+				blocked[group] = true
+			}
+			waiting = append(waiting, delivery)
+			continue
+		}
+		if group != "" {
+			c.groups[group] = true
+		}
+		c.held[delivery.Source]--
+		c.busy[delivery.Source]++
+		c.running++
+		metrics.SetQueueJobs(delivery.Source, c.busy[delivery.Source], c.held[delivery.Source])
+
+		wg.Add(1)
+		go func(workerID int, delivery *Delivery) {
+			defer wg.Done()
+			c.handle(workerID, delivery, process)
+			// Devolve o ID antes de liberar a vaga: admit só aprova com ID disponível.
+			workerIDs <- workerID
+			c.finish(delivery)
+		}(<-workerIDs, delivery)
+	}
+	return waiting
+}
+
+// admit indica se um job da origem pode ocupar um worker agora.
+func (c *DefaultJobConsumer) admit(source string) bool {
+	if limit := c.Limits[source].MaxConcurrency; limit > 0 && c.busy[source] >= limit {
+		return false
+	}
+	return c.workers-c.running >= 1+c.unmetReservations(source, false)
+}
+
+// unmetReservations soma os workers ainda reservados às outras origens. Com
+// countHeld, jobs retidos dessas origens também contam como reserva atendida.
+func (c *DefaultJobConsumer) unmetReservations(source string, countHeld bool) int {
+	n := 0
+	for other, l := range c.Limits {
+		if other == source {
+			continue
+		}
+		unmet := l.Reserved - c.busy[other]
+		if countHeld {
+			unmet -= c.held[other]
+		}
+		if unmet > 0 {
+			n += unmet
+		}
+	}
+	return n
+}
+
+// hold registra uma entrega retida da origem; retorna false se a origem já
+// retém tantas entregas quanto há workers.
+func (c *DefaultJobConsumer) hold(source string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.held[source] >= c.workers {
+		return false
+	}
+	c.held[source]++
+	metrics.SetQueueJobs(source, c.busy[source], c.held[source])
+	return true
+}
+
+func (c *DefaultJobConsumer) finish(delivery *Delivery) {
+	source := delivery.Source
+	c.mu.Lock()
+	c.busy[source]--
+	c.running--
+	if delivery.GroupID != "" {
+		delete(c.groups, delivery.GroupID)
+	}
+	metrics.SetQueueJobs(source, c.busy[source], c.held[source])
+	c.mu.Unlock()
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// handle processa uma entrega e a confirma ou devolve à origem conforme o resultado.
+func (c *DefaultJobConsumer) handle(workerID int, delivery *Delivery, process func(context.Context, *Delivery) error) {
+	job := delivery.Job
+	logger.Log.Debugf("[Consumer Worker %d] Processando job: %s", workerID, job.ScanID)
+	if !job.MessageCreatedAt.IsZero() {
+		metrics.ObserveQueueLag(delivery.Source, time.Since(job.MessageCreatedAt))
+	}
+	ctx := c.jobCtx
+	if c.Canceller != nil {
+		var release func()
+		ctx, release = c.Canceller.Track(ctx, job.ScanID)
+		defer release()
+	}
+
+	var err error
+	if c.Coalescer != nil {
+		err = c.Coalescer.Run(ctx, job, func() error { return process(ctx, delivery) })
+	} else {
+		err = process(ctx, delivery)
+	}
+	if errors.Is(err, ErrScanCancelled) {
+		logger.Log.Infof("[Consumer Worker %d] Job %s cancelado", workerID, job.ScanID)
+		metrics.IncJobsProcessed(delivery.Source, "cancelled")
+		delivery.Ack()
+		return
+	}
+	if errors.Is(err, ErrShutdown) {
+		logger.Log.Infof("[Consumer Worker %d] Job %s interrompido pelo encerramento; devolvido à origem", workerID, job.ScanID)
+		metrics.IncJobsProcessed(delivery.Source, "released")
+		delivery.Release()
+		return
+	}
+	if errors.Is(err, workspace.ErrInsufficientDisk) {
+		// Volta à origem sem contar a tentativa, para quando outros workspaces já
+		// tiverem sido removidos.
+		logger.Log.Warnf("[Consumer Worker %d] Job %s adiado por %s: %v", workerID, job.ScanID, diskRetryDelay, err)
+		metrics.IncJobsProcessed(delivery.Source, "deferred")
+		delivery.ReleaseAfter(diskRetryDelay)
+		return
+	}
+	if errors.Is(err, ErrPermanentFailure) {
+		logger.Log.Errorf("[Consumer Worker %d] Job %s falhou sem possibilidade de nova tentativa: %v", workerID, job.ScanID, err)
+		metrics.IncJobsProcessed(delivery.Source, "failed")
+		delivery.Ack()
+		return
+	}
+	if err != nil {
+		logger.Log.Errorf("[Consumer Worker %d] Erro no job %s: %v", workerID, job.ScanID, err)
+		metrics.IncJobsProcessed(delivery.Source, "error")
+		delivery.Nack(err)
+		return
+	}
+	logger.Log.Debugf("[Consumer Worker %d] Job %s finalizado com sucesso", workerID, job.ScanID)
+	metrics.IncJobsProcessed(delivery.Source, "success")
+	delivery.Ack()
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"yourproject/internal/workspace"
+	"yourproject/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// recordedDelivery cria uma entrega que registra em got a operação chamada.
+func recordedDelivery(got *string) *Delivery {
+	return &Delivery{
+		Job:    &models.ScanJob{ScanID: "scan"},
+		Source: "test",
+		ack:    func() { *got = "ack" },
+		nack:   func(error) { *got = "nack" },
+		release: func(delay time.Duration) {
+			*got = "release"
+			if delay > 0 {
+				*got = "release após " + delay.String()
+			}
+		},
+	}
+}
+
+func TestHandleResult(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "sucesso", want: "ack"},
+		{name: "cancelado", err: ErrScanCancelled, want: "ack"},
+		{name: "falha permanente", err: fmt.Errorf("%w: provedor", ErrPermanentFailure), want: "ack"},
+		{name: "encerramento", err: fmt.Errorf("interrompido: %w", ErrShutdown), want: "release"},
+		{name: "sem disco", err: fmt.Errorf("adiado: %w", workspace.ErrInsufficientDisk), want: "release após " + diskRetryDelay.String()},
+		{name: "falha transitória", err: errors.New("timeout"), want: "nack"},
+	}
+	for _, tt := range tests {
+		c := &DefaultJobConsumer{}
+		var got string
+		c.handle(0, recordedDelivery(&got), func(context.Context, *Delivery) error { return tt.err })
+		if got != tt.want {
+			t.Errorf("%s: %s, esperado %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Cada origem retém no máximo um job por worker; as demais continuam sendo retidas.
+func TestHoldPerSource(t *testing.T) {
+	c := &DefaultJobConsumer{}
+	c.init(2)
+	for i, want := range []bool{true, true, false} {
+		if got := c.hold("bulk"); got != want {
+			t.Errorf("hold(bulk) #%d = %v, esperado %v", i+1, got, want)
+		}
+	}
+	if !c.hold("urgent") {
+		t.Error("hold(urgent) recusado com a origem bulk cheia")
+	}
+}
+
+// sendJobs publica em queueURL um job válido para cada ScanID informado.
+func sendJobs(t *testing.T, client *sqs.Client, queueURL string, scanIDs ...string) {
+	for _, id := range scanIDs {
+		body := `{"schema_version":5,"scan_id":"` + id + `","repository_id":"1","repository_full_name":"acme/pix","sigla":"PIX"}`
+		if _, err := client.SendMessage(context.Background(), &sqs.SendMessageInput{QueueUrl: aws.String(queueURL), MessageBody: aws.String(body)}); err != nil {
+			t.Fatalf("erro ao publicar job %s: %v", id, err)
+		}
+	}
+}
+
+// queueCounts retorna quantas mensagens da fila estão visíveis e quantas em voo.
+func queueCounts(t *testing.T, client *sqs.Client, queueURL string) (visible, inFlight string) {
+	out, err := client.GetQueueAttributes(context.Background(), &sqs.GetQueueAttributesInput{
+		QueueUrl: aws.String(queueURL),
+		AttributeNames: []types.QueueAttributeName{
+			types.QueueAttributeNameApproximateNumberOfMessages,
+			types.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+		},
+	})
+	if err != nil {
+		t.Fatalf("erro ao ler atributos da fila %s: %v", queueURL, err)
+	}
+	return out.Attributes[string(types.QueueAttributeNameApproximateNumberOfMessages)],
+		out.Attributes[string(types.QueueAttributeNameApproximateNumberOfMessagesNotVisible)]
+}
+
+// A mensagem só sai da fila quando o job termina com sucesso; uma falha a
+// mantém na fila, invisível durante o backoff.
+func TestHandleSQSDelivery(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantInFlight string
+	}{
+		{name: "sucesso", wantInFlight: "0"},
+		{name: "falha transitória", err: errors.New("timeout"), wantInFlight: "1"},
+	}
+	for _, tt := range tests {
+		client, fake := newFakeSQS(t)
+		queueURL, err := fake.CreateQueue("jobs", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sendJobs(t, client, queueURL, "0b6f3c1e-5d2a-4c59-9d8e-2f3a4b5c6d7e")
+
+		ctx, stop := context.WithCancel(context.Background())
+		p := &DefaultSQSProducer{Client: client, QueueURL: queueURL}
+		deliveries := p.Start(ctx)
+		delivery := <-deliveries
+		if visible, inFlight := queueCounts(t, client, queueURL); visible != "0" || inFlight != "1" {
+			t.Errorf("%s: durante o processamento %s visíveis e %s em voo, esperado 0 e 1", tt.name, visible, inFlight)
+		}
+
+		c := &DefaultJobConsumer{}
+		c.handle(0, delivery, func(context.Context, *Delivery) error { return tt.err })
+		stop()
+		for range deliveries {
+		}
+		if visible, inFlight := queueCounts(t, client, queueURL); visible != "0" || inFlight != tt.wantInFlight {
+			t.Errorf("%s: %s visíveis e %s em voo, esperado 0 e %s", tt.name, visible, inFlight, tt.wantInFlight)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"yourproject/models"
+)
+
+// Delivery representa um job entregue ao consumer que ainda não foi confirmado.
+// A mensagem de origem só é removida da fila após Ack; Nack a devolve para nova
+// tentativa e Release a devolve sem contar como falha (ex.: no encerramento).
+type Delivery struct {
+	Job *models.ScanJob
+	// Source identifica a origem do job nas métricas (ex.: "sqs", "file", "memory").
+	Source string
+	// GroupID, se definido, faz o consumer processar os jobs do grupo em série,
+	// na ordem de chegada (ex.: grupos de mensagens de filas FIFO).
+	GroupID string
+	// LastAttempt indica que um Nack moverá o job para a quarentena em vez de
+	// devolvê-lo para nova tentativa.
+	LastAttempt bool
+
+	ack     func()
+	nack    func(reason error)
+	release func(delay time.Duration)
+	once    sync.Once
+}
+
+// Ack confirma que o job foi processado (com sucesso ou com falha permanente)
+// e o remove da origem.
+func (d *Delivery) Ack() {
+	d.once.Do(func() {
+		if d.ack != nil {
+			d.ack()
+		}
+	})
+}
+
+// Nack sinaliza falha no processamento; o job volta a ficar disponível com backoff.
+func (d *Delivery) Nack(reason error) {
+	d.once.Do(func() {
+		if d.nack != nil {
+			d.nack(reason)
+		}
+	})
+}
+
+// Release devolve o job à origem imediatamente, sem backoff, para que outra
+// réplica (ou a próxima execução) o processe. A tentativa é contada como em
+// ReleaseAfter.
+func (d *Delivery) Release() {
+	d.ReleaseAfter(0)
+}
+
+// ReleaseAfter devolve o job à origem para ser entregue de novo após delay
+// (ex.: falta momentânea de disco), sem contar a tentativa. Em filas SQS padrão
+// a mensagem é reenviada com as tentativas anteriores em um atributo e o atraso
+// fica limitado a 15 minutos. Em filas FIFO ela volta por visibilidade, o
+// ApproximateReceiveCount aumenta e a devolução conta como tentativa.
+func (d *Delivery) ReleaseAfter(delay time.Duration) {
+	d.once.Do(func() {
+		if d.release != nil {
+			d.release(delay)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"yourproject/internal/db"
+	"yourproject/internal/logger"
+	"yourproject/internal/metrics"
+	"yourproject/models"
+)
+
+// Ações aplicadas a jobs que excedem a idade máxima da origem.
+const (
+	ExpiryDrop         = "drop"         // Marca o scan como "expired" e descarta o job.
+	ExpiryDeprioritize = "deprioritize" // Move o job para a fila de menor prioridade (veja DefaultSQSProducer).
+)
+
+// ExpiryPolicy define como uma origem trata jobs antigos, com a idade medida a
+// partir de ScanJob.MessageCreatedAt (ex.: filas reprocessadas após uma indisponibilidade).
+type ExpiryPolicy struct {
+	MaxAge time.Duration // Idade máxima (0 = sem limite).
+	Action string        // ExpiryDrop (padrão) ou ExpiryDeprioritize.
+}
+
+// ParseExpiryAction valida a ação configurada para jobs vencidos; "" retorna ExpiryDrop.
+func ParseExpiryAction(s string) (string, error) {
+	switch s {
+	case "", ExpiryDrop:
+		return ExpiryDrop, nil
+	case ExpiryDeprioritize:
+		return ExpiryDeprioritize, nil
+	}
+	return "", fmt.Errorf("ação de expiração desconhecida %q (use drop ou deprioritize)", s)
+}
+
+// Stale indica se o job excedeu a idade máxima. Jobs sem MessageCreatedAt nunca expiram.
+func (p ExpiryPolicy) Stale(job *models.ScanJob, now time.Time) bool {
+	return p.MaxAge > 0 && !job.MessageCreatedAt.IsZero() && now.Sub(job.MessageCreatedAt) > p.MaxAge
+}
+
+// Drops indica se jobs vencidos devem ser descartados em vez de só perderem prioridade.
+func (p ExpiryPolicy) Drops() bool {
+	return p.Action != ExpiryDeprioritize
+}
+
+// expireJob marca o scan do job como expirado e contabiliza a métrica da origem.
+func expireJob(scans db.ScanStore, source string, job *models.ScanJob, now time.Time) {
+	age := now.Sub(job.MessageCreatedAt).Truncate(time.Second)
+	logger.Log.Warnf("Origem %s: job %s descartado, criado há %s", source, job.ScanID, age)
+	metrics.IncJobsExpired(source)
+	if scans == nil {
+		return
+	}
+	if err := scans.ExpireScan(job.ScanID, fmt.Sprintf("job expirado: criado há %s", age)); err != nil {
+		logger.Log.Errorf("Erro ao marcar scan %s como expirado: %v", job.ScanID, err)
+	}
+}
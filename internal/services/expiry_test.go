@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"yourproject/internal/sqsfake"
+	"yourproject/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+func TestParseExpiryAction(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ExpiryDrop},
+		{in: "drop", want: ExpiryDrop},
+		{in: "deprioritize", want: ExpiryDeprioritize},
+		{in: "Drop", wantErr: true},
+		{in: "delay", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseExpiryAction(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseExpiryAction(%q) = %q, %v; esperado %q, erro=%v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestExpiryPolicyStale(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		maxAge    time.Duration
+		createdAt time.Time
+		want      bool
+	}{
+		{name: "sem limite", createdAt: now.Add(-48 * time.Hour)},
+		{name: "sem MessageCreatedAt", maxAge: time.Hour},
+		{name: "recente", maxAge: time.Hour, createdAt: now.Add(-time.Minute)},
+		{name: "vencido", maxAge: time.Hour, createdAt: now.Add(-2 * time.Hour), want: true},
+	}
+	for _, tt := range tests {
+		p := ExpiryPolicy{MaxAge: tt.maxAge}
+		if got := p.Stale(&models.ScanJob{MessageCreatedAt: tt.createdAt}, now); got != tt.want {
+			t.Errorf("%s: Stale = %v, esperado %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// newFakeSQS sobe o SQS fake e retorna um cliente apontado para ele.
+func newFakeSQS(t *testing.T) (*sqs.Client, *sqsfake.Server) {
+	fake := &sqsfake.Server{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	fake.BaseURL = srv.URL
+	client := sqs.New(sqs.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "sqsfake", SecretAccessKey: "sqsfake"}, nil
+		}),
+	})
+	return client, fake
+}
+
+// queueDepth retorna quantas mensagens visíveis a fila tem.
+func queueDepth(t *testing.T, client *sqs.Client, url string) string {
+	out, err := client.GetQueueAttributes(context.Background(), &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(url),
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameApproximateNumberOfMessages},
+	})
+	if err != nil {
+		t.Fatalf("erro ao ler atributos da fila %s: %v", url, err)
+	}
+	return out.Attributes[string(types.QueueAttributeNameApproximateNumberOfMessages)]
+}
+
+func TestSQSProducerDemote(t *testing.T) {
+	client, fake := newFakeSQS(t)
+	urgentURL, err := fake.CreateQueue("urgent", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lowURL, err := fake.CreateQueue("low", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urgent := SQSQueue{Name: "urgent", URL: urgentURL}
+	low := SQSQueue{Name: "low", URL: lowURL}
+	p := &DefaultSQSProducer{Client: client, Queues: []SQSQueue{urgent, low}}
+
+	ctx := context.Background()
+	if _, err := client.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: aws.String(urgentURL), MessageBody: aws.String(`{"scan_id":"scan"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: aws.String(urgentURL), MaxNumberOfMessages: 1})
+	if err != nil || len(resp.Messages) != 1 {
+		t.Fatalf("mensagem não recebida: %v", err)
+	}
+	job := &models.ScanJob{ScanID: "scan"}
+
+	if !p.demote(ctx, urgent, resp.Messages[0], job) {
+		t.Fatal("job vencido da fila urgente não foi movido")
+	}
+	if got := queueDepth(t, client, lowURL); got != "1" {
+		t.Errorf("fila low com %s mensagens, esperado 1", got)
+	}
+	resp, err = client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: aws.String(urgentURL), MaxNumberOfMessages: 1})
+	if err != nil || len(resp.Messages) != 0 {
+		t.Errorf("mensagem movida continua na fila urgente: %v", err)
+	}
+
+	resp, err = client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: aws.String(lowURL), MaxNumberOfMessages: 1})
+	if err != nil || len(resp.Messages) != 1 {
+		t.Fatalf("mensagem não recebida da fila low: %v", err)
+	}
+	if p.demote(ctx, low, resp.Messages[0], job) {
+		t.Error("job vencido da última fila foi movido")
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"yourproject/internal/db"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+const defaultFileRetryDelay = 5 * time.Second
+
+// FileJobSource lê jobs de um arquivo JSONL (um ScanJob por linha) ou de todos os
+// arquivos *.jsonl de um diretório. Permite rodar o pipeline local e em CI sem AWS.
+// O canal é fechado quando todos os jobs foram confirmados ou postos em quarentena.
+// Se ctx for cancelado, a leitura para e o canal fecha assim que os jobs já
+// entregues terminam; os demais são lidos de novo na próxima execução.
+type FileJobSource struct {
+	Path        string        // Arquivo .jsonl ou diretório com arquivos .jsonl.
+	MaxAttempts int           // Tentativas antes da quarentena (0 = sem limite).
+	RetryDelay  time.Duration // Atraso base entre tentativas; cresce linearmente.
+	Expiry      ExpiryPolicy  // Tratamento de jobs antigos; os despriorizados vão para o fim.
+
+	// Quarantine guarda linhas inválidas ou que esgotaram as tentativas.
+	Quarantine db.QuarantineStore
+	// Scans recebe o motivo de rejeição de jobs inválidos.
+	Scans db.ScanStore
+}
+
+// fileJob identifica a linha de origem de um job para logs e quarentena.
+type fileJob struct {
+	job  *models.ScanJob
+	path string
+	line int
+	body string
+}
+
+func (s *FileJobSource) Start(ctx context.Context) <-chan *Delivery {
+	if s.RetryDelay <= 0 {
+		s.RetryDelay = defaultFileRetryDelay
+	}
+	jobChan := make(chan *Delivery)
+	go func() {
+		var (
+			pending sync.WaitGroup
+			stale   []*fileJob
+		)
+		files, err := s.files()
+		if err != nil {
+			logger.Log.Errorf("FileJobSource: erro ao listar arquivos em %s: %v", s.Path, err)
+		}
+		for _, path := range files {
+			if err := s.readFile(ctx, path, jobChan, &pending, &stale); err != nil {
+				logger.Log.Errorf("FileJobSource: erro ao ler %s: %v", path, err)
+			}
+		}
+		// Jobs vencidos e despriorizados só são entregues depois dos recentes.
+		for _, fj := range stale {
+			if !s.send(ctx, jobChan, s.newDelivery(ctx, fj, 1, jobChan, &pending), &pending) {
+				break
+			}
+		}
+		pending.Wait()
+		logger.Log.Infof("FileJobSource: todos os jobs de %s foram finalizados", s.Path)
+		close(jobChan)
+	}()
+	return jobChan
+}
+
+// files retorna o próprio Path ou os arquivos *.jsonl do diretório, em ordem.
+func (s *FileJobSource) files() ([]string, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{s.Path}, nil
+	}
+	files, err := filepath.Glob(filepath.Join(s.Path, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (s *FileJobSource) readFile(ctx context.Context, path string, jobChan chan<- *Delivery, pending *sync.WaitGroup, stale *[]*fileJob) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for ctx.Err() == nil && scanner.Scan() {
+		lineNo++
+		body := strings.TrimSpace(scanner.Text())
+		if body == "" {
+			continue
+		}
+		fj := &fileJob{path: path, line: lineNo, body: body}
+		job, err := decodeJob([]byte(body), s.Scans)
+		if err != nil {
+			logger.Log.Errorf("FileJobSource: job rejeitado em %s:%d: %v", path, lineNo, err)
+			fj.job = job
+			s.quarantine(fj, 1, err)
+			continue
+		}
+		fj.job = job
+		if now := time.Now(); s.Expiry.Stale(job, now) {
+			if s.Expiry.Drops() {
+				expireJob(s.Scans, "file", job, now)
+			} else {
+				*stale = append(*stale, fj)
+			}
+			continue
+		}
+		logger.Log.Debugf("FileJobSource: job %s para o repositório %s lido de %s:%d", job.ScanID, job.RepositoryFullName, path, lineNo)
+		if !s.send(ctx, jobChan, s.newDelivery(ctx, fj, 1, jobChan, pending), pending) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// send conta o job como pendente e o entrega ao consumer; se ctx for cancelado
+// antes da entrega, desiste do job e retorna false.
+func (s *FileJobSource) send(ctx context.Context, jobChan chan<- *Delivery, d *Delivery, pending *sync.WaitGroup) bool {
+	pending.Add(1)
+	select {
+	case jobChan <- d:
+		return true
+	case <-ctx.Done():
+		pending.Done()
+		return false
+	}
+}
+
+// newDelivery reentrega o job após Nack até MaxAttempts; depois o põe em quarentena.
+func (s *FileJobSource) newDelivery(ctx context.Context, fj *fileJob, attempt int, jobChan chan<- *Delivery, pending *sync.WaitGroup) *Delivery {
+	return &Delivery{
+		Job:         fj.job,
+		Source:      "file",
+		LastAttempt: s.MaxAttempts > 0 && attempt >= s.MaxAttempts,
+		ack: func() {
+			pending.Done()
+		},
+		release: func(delay time.Duration) {
+			if delay == 0 {
+				logger.Log.Infof("FileJobSource: job %s (%s:%d) devolvido; será lido na próxima execução", fj.job.ScanID, fj.path, fj.line)
+				pending.Done()
+				return
+			}
+			logger.Log.Infof("FileJobSource: job %s (%s:%d) devolvido; nova entrega em %s", fj.job.ScanID, fj.path, fj.line, delay)
+			time.AfterFunc(delay, func() {
+				s.send(ctx, jobChan, s.newDelivery(ctx, fj, attempt, jobChan, pending), pending)
+				pending.Done()
+			})
+		},
+		nack: func(reason error) {
+			if s.MaxAttempts > 0 && attempt >= s.MaxAttempts {
+				s.quarantine(fj, attempt, fmt.Errorf("falhou em %d tentativas: %v", attempt, reason))
+				pending.Done()
+				return
+			}
+			delay := s.RetryDelay * time.Duration(attempt)
+			logger.Log.Warnf("FileJobSource: job %s falhou (tentativa %d), nova tentativa em %s: %v", fj.job.ScanID, attempt, delay, reason)
+			// O job continua pendente até a nova tentativa ser entregue ou abandonada.
+			time.AfterFunc(delay, func() {
+				s.send(ctx, jobChan, s.newDelivery(ctx, fj, attempt+1, jobChan, pending), pending)
+				pending.Done()
+			})
+		},
+	}
+}
+
+func (s *FileJobSource) quarantine(fj *fileJob, attempts int, reason error) {
+	messageID := fmt.Sprintf("%s:%d", fj.path, fj.line)
+	if s.Quarantine == nil {
+		logger.Log.Errorf("FileJobSource: quarentena não configurada; descartando %s: %v", messageID, reason)
+		return
+	}
+	entry := &models.QuarantinedJob{
+		Source:     fj.path,
+		MessageID:  messageID,
+		Body:       fj.body,
+		Attributes: map[string]string{"line": fmt.Sprint(fj.line)},
+		Reason:     reason.Error(),
+		Attempts:   attempts,
+	}
+	if fj.job != nil {
+		entry.ScanID = fj.job.ScanID
+	}
+	if err := s.Quarantine.InsertQuarantine(entry); err != nil {
+		logger.Log.Errorf("FileJobSource: erro ao mover %s para a quarentena: %v", messageID, err)
+		return
+	}
+	logger.Log.Warnf("FileJobSource: %s movido para a quarentena (%s): %v", messageID, entry.ID, reason)
+}
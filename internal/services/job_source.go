@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// JobSource é uma origem de jobs para o consumer. Cada Delivery deve ser
+// confirmada com Ack após o processamento ou devolvida com Nack em caso de falha.
+// Quando ctx é cancelado a origem para de receber jobs novos e fecha o canal.
+type JobSource interface {
+	Start(ctx context.Context) <-chan *Delivery
+}
+
+// MergeSources combina várias origens em um único canal, fechado quando todas
+// as origens fecham os seus.
+func MergeSources(ctx context.Context, sources ...JobSource) <-chan *Delivery {
+	if len(sources) == 1 {
+		return sources[0].Start(ctx)
+	}
+	merged := make(chan *Delivery)
+	var wg sync.WaitGroup
+	for _, src := range sources {
+		wg.Add(1)
+		go func(ch <-chan *Delivery) {
+			defer wg.Done()
+			for d := range ch {
+				merged <- d
+			}
+		}(src.Start(ctx))
+	}
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+	return merged
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"yourproject/internal/db"
+	"yourproject/internal/logger"
+	"yourproject/models"
+)
+
+const (
+	defaultMemoryQueueSize  = 100
+	defaultMemoryRetryDelay = 30 * time.Second
+	releaseDelay            = time.Second
+)
+
+var (
+	// ErrQueueFull indica que a fila em memória atingiu a capacidade.
+	ErrQueueFull = errors.New("fila de jobs cheia")
+	// ErrQueueClosed indica que a fila em memória já foi encerrada.
+	ErrQueueClosed = errors.New("fila de jobs encerrada")
+)
+
+// MemoryJobSource é uma fila em memória alimentada pelo próprio serviço (ex.: API HTTP).
+// Os jobs entram no mesmo pipeline do consumer que os jobs vindos da SQS.
+type MemoryJobSource struct {
+	QueueSize   int           // Capacidade da fila (padrão: 100).
+	MaxAttempts int           // Tentativas antes da quarentena (0 = sem limite).
+	RetryDelay  time.Duration // Atraso base entre tentativas; cresce linearmente.
+
+	// Quarantine guarda jobs que esgotaram as tentativas.
+	Quarantine db.QuarantineStore
+	// Scans e Webhooks registram os jobs perdidos no encerramento: o scan fica
+	// "error" e a entrega do webhook que o criou é esquecida, para que uma
+	// reentrega pelo GitHub seja aceita.
+	Scans    db.ScanStore
+	Webhooks db.WebhookStore
+
+	queue   chan *Delivery
+	mu      sync.Mutex
+	closed  bool
+	waiting map[*time.Timer]*models.ScanJob // Jobs devolvidos aguardando o atraso.
+}
+
+// Init prepara a fila; chamado por Enqueue e Start se ainda não foi feito.
+func (s *MemoryJobSource) Init() {
+	if s.queue != nil {
+		return
+	}
+	if s.QueueSize <= 0 {
+		s.QueueSize = defaultMemoryQueueSize
+	}
+	if s.RetryDelay <= 0 {
+		s.RetryDelay = defaultMemoryRetryDelay
+	}
+	s.queue = make(chan *Delivery, s.QueueSize)
+	s.waiting = make(map[*time.Timer]*models.ScanJob)
+}
+
+// Start repassa os jobs da fila ao consumer até ctx ser cancelado. Os jobs que
+// ainda estão na fila nesse momento, ou que voltam a ela depois, se perdem com
+// o processo e são registrados com drop.
+func (s *MemoryJobSource) Start(ctx context.Context) <-chan *Delivery {
+	s.Init()
+	out := make(chan *Delivery)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case d := <-s.queue:
+				select {
+				case out <- d:
+				case <-ctx.Done():
+					s.close(d.Job)
+					return
+				}
+			case <-ctx.Done():
+				s.close()
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// close encerra a fila e registra como perdidos os jobs pendentes, inclusive
+// os que aguardavam o atraso de uma devolução ou nova tentativa.
+func (s *MemoryJobSource) close(pending ...*models.ScanJob) {
+	s.mu.Lock()
+	s.closed = true
+	for t, job := range s.waiting {
+		t.Stop()
+		pending = append(pending, job)
+	}
+	s.waiting = nil
+	for len(s.queue) > 0 {
+		pending = append(pending, (<-s.queue).Job)
+	}
+	s.mu.Unlock()
+
+	logger.Log.Infof("MemoryJobSource: encerrando com %d jobs na fila", len(pending))
+	for _, job := range pending {
+		s.drop(job)
+	}
+}
+
+// Enqueue adiciona o job à fila sem bloquear; retorna ErrQueueFull se não houver
+// espaço e ErrQueueClosed após o encerramento.
+func (s *MemoryJobSource) Enqueue(job *models.ScanJob) error {
+	s.Init()
+	if err := s.push(s.newDelivery(job, 1)); err != nil {
+		return err
+	}
+	logger.Log.Debugf("MemoryJobSource: job %s para o repositório %s enfileirado", job.ScanID, job.RepositoryFullName)
+	return nil
+}
+
+// EnqueueAfter adiciona o job à fila após delay. O job aguardando o atraso se
+// perde com o processo e é registrado com drop no encerramento.
+func (s *MemoryJobSource) EnqueueAfter(job *models.ScanJob, delay time.Duration) error {
+	if delay <= 0 {
+		return s.Enqueue(job)
+	}
+	s.Init()
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return ErrQueueClosed
+	}
+	s.later(delay, job, 1)
+	logger.Log.Debugf("MemoryJobSource: job %s para o repositório %s enfileirado em %s", job.ScanID, job.RepositoryFullName, delay)
+	return nil
+}
+
+// Full indica se a fila está cheia, para que produtores em lote parem antes de
+// criar scans que não caberiam nela.
+func (s *MemoryJobSource) Full() bool {
+	s.Init()
+	return len(s.queue) >= cap(s.queue)
+}
+
+// push coloca a entrega na fila sem bloquear.
+func (s *MemoryJobSource) push(d *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case s.queue <- d:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// later devolve o job à fila após delay. Se a fila estiver cheia ou encerrada
+// nesse momento, o job é registrado como perdido.
+func (s *MemoryJobSource) later(delay time.Duration, job *models.ScanJob, attempt int) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.drop(job)
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		_, ok := s.waiting[t]
+		delete(s.waiting, t)
+		s.mu.Unlock()
+		if !ok {
+			return // Já registrado por close.
+		}
+		if err := s.push(s.newDelivery(job, attempt)); err != nil {
+			logger.Log.Errorf("MemoryJobSource: job %s devolvido não voltou à fila: %v", job.ScanID, err)
+			s.drop(job)
+		}
+	})
+	s.waiting[t] = job
+	s.mu.Unlock()
+}
+
+// drop registra um job que se perdeu: o scan fica "error" e a entrega do
+// webhook que o criou, se houver, é esquecida.
+func (s *MemoryJobSource) drop(job *models.ScanJob) {
+	logger.Log.Warnf("MemoryJobSource: job %s para o repositório %s descartado", job.ScanID, job.RepositoryFullName)
+	if s.Scans != nil {
+		if err := s.Scans.UpdateScanStatus(job.ScanID, "error"); err != nil {
+			logger.Log.Errorf("MemoryJobSource: %v", err)
+		}
+	}
+	if s.Webhooks != nil {
+		if err := s.Webhooks.ForgetScanDelivery(job.ScanID); err != nil {
+			logger.Log.Errorf("MemoryJobSource: %v", err)
+		}
+	}
+}
+
+func (s *MemoryJobSource) newDelivery(job *models.ScanJob, attempt int) *Delivery {
+	return &Delivery{
+		Job:         job,
+		Source:      "memory",
+		LastAttempt: s.MaxAttempts > 0 && attempt >= s.MaxAttempts,
+		release: func(delay time.Duration) {
+			// O atraso mínimo evita que um job devolvido pelo consumer volte a ele na hora.
+			s.later(max(delay, releaseDelay), job, attempt)
+		},
+		nack: func(reason error) {
+			if s.MaxAttempts > 0 && attempt >= s.MaxAttempts {
+				s.quarantine(job, attempt, fmt.Errorf("falhou em %d tentativas: %v", attempt, reason))
+				return
+			}
+			delay := s.RetryDelay * time.Duration(attempt)
+			logger.Log.Warnf("MemoryJobSource: job %s falhou (tentativa %d), nova tentativa em %s: %v", job.ScanID, attempt, delay, reason)
+			s.later(delay, job, attempt+1)
+		},
+	}
+}
+
+func (s *MemoryJobSource) quarantine(job *models.ScanJob, attempts int, reason error) {
+	if s.Quarantine == nil {
+		logger.Log.Errorf("MemoryJobSource: quarentena não configurada; descartando job %s: %v", job.ScanID, reason)
+		return
+	}
+	body, err := json.Marshal(job)
+	if err != nil {
+		logger.Log.Errorf("MemoryJobSource: erro ao serializar job %s: %v", job.ScanID, err)
+		return
+	}
+	entry := &models.QuarantinedJob{
+		Source:    "memory",
+		MessageID: job.ScanID,
+		ScanID:    job.ScanID,
+		Body:      string(body),
+		Reason:    reason.Error(),
+		Attempts:  attempts,
+	}
+	if err := s.Quarantine.InsertQuarantine(entry); err != nil {
+		logger.Log.Errorf("MemoryJobSource: erro ao mover job %s para a quarentena: %v", job.ScanID, err)
+		return
+	}
+	logger.Log.Warnf("MemoryJobSource: job %s movido para a quarentena (%s): %v", job.ScanID, entry.ID, reason)
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"yourproject/internal/db"
+	"yourproject/models"
+)
+
+// droppedStore registra os scans marcados como "error" e as entregas esquecidas.
+type droppedStore struct {
+	db.ScanStore
+	db.WebhookStore
+	mu        sync.Mutex
+	failed    []string
+	forgotten []string
+}
+
+func (s *droppedStore) UpdateScanStatus(scanID, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status == "error" {
+		s.failed = append(s.failed, scanID)
+	}
+	return nil
+}
+
+func (s *droppedStore) ForgetScanDelivery(scanID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forgotten = append(s.forgotten, scanID)
+	return nil
+}
+
+func (s *droppedStore) dropped() ([]string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failed := append([]string(nil), s.failed...)
+	forgotten := append([]string(nil), s.forgotten...)
+	sort.Strings(failed)
+	sort.Strings(forgotten)
+	return failed, forgotten
+}
+
+func newMemorySource() (*MemoryJobSource, *droppedStore) {
+	store := &droppedStore{}
+	return &MemoryJobSource{Scans: store, Webhooks: store}, store
+}
+
+// drain devolve as entregas recebidas, como o consumer no encerramento, até a
+// origem fechar o canal.
+func drain(t *testing.T, out <-chan *Delivery) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case d, ok := <-out:
+			if !ok {
+				return
+			}
+			d.Release()
+		case <-timeout:
+			t.Fatal("origem não encerrou")
+		}
+	}
+}
+
+func receive(t *testing.T, out <-chan *Delivery) *Delivery {
+	select {
+	case d := <-out:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("nenhum job entregue")
+		return nil
+	}
+}
+
+func TestMemoryJobSourceShutdown(t *testing.T) {
+	tests := []struct {
+		name string
+		// run recebe a origem já iniciada e encerra ctx com cancel.
+		run func(t *testing.T, s *MemoryJobSource, out <-chan *Delivery, cancel func())
+	}{
+		{
+			name: "jobs na fila",
+			run: func(t *testing.T, s *MemoryJobSource, out <-chan *Delivery, cancel func()) {
+				cancel()
+				drain(t, out)
+			},
+		},
+		{
+			name: "devolvidos aguardando o atraso",
+			run: func(t *testing.T, s *MemoryJobSource, out <-chan *Delivery, cancel func()) {
+				receive(t, out).Release()
+				receive(t, out).Nack(errors.New("falha"))
+				cancel()
+				drain(t, out)
+			},
+		},
+		{
+			name: "devolvidos após o encerramento",
+			run: func(t *testing.T, s *MemoryJobSource, out <-chan *Delivery, cancel func()) {
+				a, b := receive(t, out), receive(t, out)
+				cancel()
+				drain(t, out)
+				a.Release()
+				b.Release()
+			},
+		},
+	}
+	for _, tt := range tests {
+		s, store := newMemorySource()
+		for _, id := range []string{"a", "b"} {
+			if err := s.Enqueue(&models.ScanJob{ScanID: id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		tt.run(t, s, s.Start(ctx), cancel)
+
+		failed, forgotten := store.dropped()
+		if len(failed) != 2 || failed[0] != "a" || failed[1] != "b" || len(forgotten) != 2 {
+			t.Errorf("%s: scans com erro %v, entregas esquecidas %v; esperado a e b", tt.name, failed, forgotten)
+		}
+		if err := s.Enqueue(&models.ScanJob{ScanID: "c"}); !errors.Is(err, ErrQueueClosed) {
+			t.Errorf("%s: Enqueue após o encerramento: %v, esperado ErrQueueClosed", tt.name, err)
+		}
+	}
+}
+
+func TestMemoryJobSourceRelease(t *testing.T) {
+	s, store := newMemorySource()
+	if err := s.Enqueue(&models.ScanJob{ScanID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := s.Start(ctx)
+
+	receive(t, out).Release()
+	if d := receive(t, out); d.Job.ScanID != "a" {
+		t.Errorf("job devolvido %s, esperado a", d.Job.ScanID)
+	}
+	if failed, _ := store.dropped(); len(failed) != 0 {
+		t.Errorf("job devolvido antes do encerramento marcado como erro: %v", failed)
+	}
+}
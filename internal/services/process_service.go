@@ -1,90 +1,291 @@
-package services
-
-import (
-	"database/sql"
-	"fmt"
-	"os"
-	"strconv"
-	"time"
-
-	"yourproject/models"
-	"yourproject/internal/db"
-	"yourproject/internal/git"
-	"yourproject/internal/logger"
-	"yourproject/internal/scan"
-)
-
-// GetEnvAsBool retorna o valor booleano de uma variável de ambiente, com padrão.
-func GetEnvAsBool(key string, defaultVal bool) bool {
-	val := os.Getenv(key)
-	if val == "" {
-		return defaultVal
-	}
-	b, err := strconv.ParseBool(val)
-	if err != nil {
-		return defaultVal
-	}
-	return b
-}
-
-func EnableClone() bool {
-	return GetEnvAsBool("ENABLE_GIT_CLONE", true)
-}
-
-func EnableScan() bool {
-	return GetEnvAsBool("ENABLE_GITLEAKS", true)
-}
-
-func ProcessJob(job *models.ScanJob, dbConn *sql.DB, gitClient git.GitClient, scanner scan.Scanner, cloneSem chan struct{}) error {
-	start := time.Now()
-	logger.Log.Debugf("ProcessService: Iniciando processamento do job %s", job.ScanID)
-
-	if err := db.UpdateScanStatus(dbConn, job.ScanID, "running"); err != nil {
-		logger.Log.Errorf("ProcessService: Erro ao atualizar status do scan %s: %v", job.ScanID, err)
-		return err
-	}
-
-	repoURL := fmt.Sprintf("https://github.com/%s", job.RepositoryFullName)
-
-	var repoPath string
-	if EnableClone() {
-		cloneSem <- struct{}{}
-		var err error
-		repoPath, err = gitClient.CloneRepo(repoURL)
-		<-cloneSem
-		if err != nil {
-			db.UpdateScanStatus(dbConn, job.ScanID, "error")
-			return fmt.Errorf("ProcessService: erro ao clonar repositório: %v", err)
-		}
-		logger.Log.Debugf("ProcessService: Repositório clonado em %s", repoPath)
-	} else {
-		logger.Log.Debug("ProcessService: Clone desabilitado; pulando etapa de clone")
-		repoPath = ""
-	}
-
-	var findings []models.GitleaksFinding
-	if EnableScan() {
-		f, err := scanner.Run(repoPath)
-		if err != nil {
-			db.UpdateScanStatus(dbConn, job.ScanID, "error")
-			return fmt.Errorf("ProcessService: erro ao executar o scanner: %v", err)
-		}
-		findings = f
-		logger.Log.Debugf("ProcessService: Scanner encontrou %d achados para o job %s", len(findings), job.ScanID)
-	} else {
-		logger.Log.Debug("ProcessService: Scanner desabilitado; retornando findings vazios")
-		findings = []models.GitleaksFinding{}
-	}
-
-	for _, f := range findings {
-		if err := db.InsertFinding(dbConn, job, f); err != nil {
-			logger.Log.Errorf("ProcessService: erro ao inserir achado para o job %s: %v", job.ScanID, err)
-		}
-	}
-
-	if err := db.UpdateScanStatus(dbConn, job.ScanID, "success"); err != nil {
-		logger.Log.Errorf("ProcessService: erro ao atualizar status final do scan %s: %v", job.ScanID, err)
-	}
-	logger.Log.Debugf("ProcessService: Processamento do job %s concluído em %d ms", job.ScanID, time.Since(start).Milliseconds())
-	return nil
-}
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"yourproject/models"
+	"yourproject/internal/db"
+	"yourproject/internal/git"
+	"yourproject/internal/logger"
+	"yourproject/internal/metrics"
+	"yourproject/internal/provider"
+	"yourproject/internal/scan"
+	"yourproject/internal/workspace"
+)
+
+// GetEnvAsBool retorna o valor booleano de uma variável de ambiente, com padrão.
+func GetEnvAsBool(key string, defaultVal bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return defaultVal
+	}
+	return b
+}
+
+func EnableClone() bool {
+	return GetEnvAsBool("ENABLE_GIT_CLONE", true)
+}
+
+func EnableScan() bool {
+	return GetEnvAsBool("ENABLE_GITLEAKS", true)
+}
+
+// EnableEvents indica se o resultado do scan deve gerar eventos na outbox.
+func EnableEvents() bool {
+	return GetEnvAsBool("ENABLE_EVENTS", false)
+}
+
+// ProcessJob clona e escaneia o repositório do job em um workspace de
+// workspaces, removido ao retornar. Se ctx for cancelado com ErrScanCancelled, o
+// clone ou o gitleaks são interrompidos, o scan fica "cancelled" e
+// ErrScanCancelled é retornado. Sem espaço em disco para o clone, o scan volta a
+// "queued" e o erro (workspace.ErrInsufficientDisk) adia o job. O repositório é
+// clonado do provedor do job (providers); sem registro, do GitHub. Em caso de
+// falha, o scan só é marcado como "error" se a falha for permanente
+// (ErrPermanentFailure) ou se lastAttempt indicar a última tentativa do job.
+func ProcessJob(ctx context.Context, job *models.ScanJob, lastAttempt bool, dbConn *sql.DB, gitClient git.GitClient, providers *provider.Registry, workspaces *workspace.Manager, scanner scan.Scanner, cloneSem chan struct{}) error {
+	start := time.Now()
+	logger.Log.Debugf("ProcessService: Iniciando processamento do job %s", job.ScanID)
+
+	store := &db.RDSStore{DB: dbConn}
+	started, err := store.StartScan(job.ScanID)
+	if err != nil {
+		logger.Log.Errorf("ProcessService: Erro ao atualizar status do scan %s: %v", job.ScanID, err)
+		return err
+	}
+	if !started {
+		logger.Log.Infof("ProcessService: scan %s foi cancelado antes de iniciar; job ignorado", job.ScanID)
+		return nil
+	}
+
+	var (
+		repoPath string
+		gitEnv   []string
+	)
+	if EnableClone() {
+		var opts git.CloneOptions
+		repoURL, err := resolveRepository(ctx, job, providers, &opts)
+		if err != nil {
+			err = fmt.Errorf("ProcessService: %w", err)
+			return failJob(store, job, start, lastAttempt, err)
+		}
+
+		ws, err := workspaces.Acquire(job.RepositorySize)
+		if errors.Is(err, workspace.ErrInsufficientDisk) {
+			if err := store.UpdateScanStatus(job.ScanID, "queued"); err != nil {
+				logger.Log.Errorf("ProcessService: erro ao devolver scan %s para a fila: %v", job.ScanID, err)
+			}
+			return fmt.Errorf("ProcessService: job %s adiado: %w", job.ScanID, err)
+		}
+		if err != nil {
+			err = fmt.Errorf("ProcessService: %w", err)
+			return failJob(store, job, start, lastAttempt, err)
+		}
+		defer ws.Release()
+
+		select {
+		case cloneSem <- struct{}{}:
+		case <-ctx.Done():
+			return abortScan(ctx, store, job, start)
+		}
+		strategy, _ := git.ParseStrategy(job.CloneStrategy)
+		opts.Dir = ws.Path
+		opts.Strategy = strategy
+		opts.SizeKB = job.RepositorySize
+		opts.RepositoryID = job.RepositoryKey()
+		opts.AllBranches = job.AfterSHA != ""
+		clone, err := gitClient.CloneRepo(ctx, repoURL, opts)
+		<-cloneSem
+		repoPath, gitEnv = clone.Dir, clone.Env
+		if clone.Strategy != git.StrategyAuto {
+			if err := store.SetCloneStrategy(job.ScanID, string(clone.Strategy)); err != nil {
+				logger.Log.Errorf("ProcessService: %v", err)
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return abortScan(ctx, store, job, start)
+			}
+			class := git.ErrorClass(err)
+			if class == "" {
+				metrics.IncCloneFailures("other")
+			} else {
+				metrics.IncCloneFailures(class)
+			}
+			if err := store.SetCloneError(job.ScanID, class, err.Error()); err != nil {
+				logger.Log.Errorf("ProcessService: %v", err)
+			}
+			err = fmt.Errorf("ProcessService: erro ao clonar repositório: %w", err)
+			return failJob(store, job, start, lastAttempt, err)
+		}
+		logger.Log.Debugf("ProcessService: Repositório clonado em %s", repoPath)
+	} else {
+		logger.Log.Debug("ProcessService: Clone desabilitado; pulando etapa de clone")
+		repoPath = ""
+	}
+
+	var findings []models.GitleaksFinding
+	if EnableScan() {
+		f, err := scanner.Run(ctx, repoPath, scan.CommitRange{Before: job.BeforeSHA, After: job.AfterSHA}, gitEnv)
+		if err != nil {
+			if ctx.Err() != nil {
+				return abortScan(ctx, store, job, start)
+			}
+			err = fmt.Errorf("ProcessService: erro ao executar o scanner: %v", err)
+			return failJob(store, job, start, lastAttempt, err)
+		}
+		findings = f
+		logger.Log.Debugf("ProcessService: Scanner encontrou %d achados para o job %s", len(findings), job.ScanID)
+	} else {
+		logger.Log.Debug("ProcessService: Scanner desabilitado; retornando findings vazios")
+		findings = []models.GitleaksFinding{}
+	}
+
+	// Achados, status final e evento são gravados atomicamente.
+	var event *models.ScanEvent
+	if EnableEvents() {
+		event = newScanEvent(job, models.EventScanCompleted, "success", findings, start, nil)
+	}
+	if err := store.CompleteScan(job, "success", findings, event); err != nil {
+		logger.Log.Errorf("ProcessService: erro ao gravar resultado do scan %s: %v", job.ScanID, err)
+		return err
+	}
+	logger.Log.Debugf("ProcessService: Processamento do job %s concluído em %d ms", job.ScanID, time.Since(start).Milliseconds())
+	return nil
+}
+
+// resolveRepository retorna a URL de clone pelo provedor do job e preenche a
+// autenticação em opts: a chave SSH do host ou da organização, se o Vault tiver
+// uma, ou as credenciais HTTPS do provedor. Jobs sem tamanho informado o
+// recebem da API do provedor, quando possível, para a escolha da estratégia de
+// clone e a reserva de disco.
+func resolveRepository(ctx context.Context, job *models.ScanJob, providers *provider.Registry, opts *git.CloneOptions) (string, error) {
+	if providers == nil {
+		return fmt.Sprintf("https://github.com/%s", job.RepositoryFullName), nil
+	}
+	p, err := providers.Get(job.Provider)
+	if err != nil {
+		return "", err
+	}
+	key, err := p.SSHKey(job.RepositoryFullName)
+	if err != nil {
+		return "", err
+	}
+	var repoURL string
+	if key != nil {
+		repoURL, err = p.SSHCloneURL(job.RepositoryFullName)
+		opts.SSHKey = key
+	} else {
+		repoURL, err = p.CloneURL(job.RepositoryFullName)
+		if err == nil {
+			opts.Credentials, err = p.Credentials(job.RepositoryFullName)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if job.RepositorySize == 0 {
+		if repo, err := p.Repository(ctx, job.RepositoryFullName); err != nil {
+			logger.Log.Warnf("ProcessService: tamanho de %s não obtido do provedor %s: %v", job.RepositoryFullName, p.Name(), err)
+		} else {
+			job.RepositorySize = repo.SizeKB
+		}
+	}
+	return repoURL, nil
+}
+
+// abortScan trata a interrupção do job pelo contexto. Cancelamentos pedidos pelo
+// usuário marcam o scan como "cancelled"; no encerramento do serviço
+// (ErrShutdown) o scan volta a "queued".
+func abortScan(ctx context.Context, store *db.RDSStore, job *models.ScanJob, start time.Time) error {
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrShutdown) {
+		// O job volta à fila; o scan aguarda a próxima entrega como "queued".
+		if err := store.UpdateScanStatus(job.ScanID, "queued"); err != nil {
+			logger.Log.Errorf("ProcessService: erro ao devolver scan %s para a fila: %v", job.ScanID, err)
+		}
+	}
+	if !errors.Is(cause, ErrScanCancelled) {
+		return fmt.Errorf("ProcessService: job %s interrompido: %w", job.ScanID, cause)
+	}
+
+	var event *models.ScanEvent
+	if EnableEvents() {
+		event = newScanEvent(job, models.EventScanCancelled, "cancelled", nil, start, cause)
+	}
+	if err := store.CompleteScan(job, "cancelled", nil, event); err != nil {
+		logger.Log.Errorf("ProcessService: erro ao registrar cancelamento do scan %s: %v", job.ScanID, err)
+	}
+	logger.Log.Infof("ProcessService: scan %s cancelado após %d ms", job.ScanID, time.Since(start).Milliseconds())
+	return ErrScanCancelled
+}
+
+// failJob registra a falha do job. Falhas permanentes e a da última tentativa
+// marcam o scan como "error" e emitem scan.failed; nas demais o job ainda será
+// tentado de novo e o scan volta a "queued". Falhas permanentes retornam
+// ErrPermanentFailure, para que o job não seja tentado de novo.
+func failJob(store *db.RDSStore, job *models.ScanJob, start time.Time, lastAttempt bool, cause error) error {
+	permanent := isPermanent(cause)
+	if !permanent && !lastAttempt {
+		if err := store.UpdateScanStatus(job.ScanID, "queued"); err != nil {
+			logger.Log.Errorf("ProcessService: erro ao devolver scan %s para a fila: %v", job.ScanID, err)
+		}
+		return cause
+	}
+	failScan(store, job, start, cause)
+	if permanent {
+		return fmt.Errorf("%w: %w", ErrPermanentFailure, cause)
+	}
+	return cause
+}
+
+// isPermanent indica se a falha se repetiria em novas tentativas do job.
+func isPermanent(err error) bool {
+	return errors.Is(err, provider.ErrUnknownProvider) || errors.Is(err, workspace.ErrTooLarge) ||
+		errors.Is(err, git.ErrCloneTooLarge) || errors.Is(err, git.ErrCloneAuth) || errors.Is(err, git.ErrCloneNotFound)
+}
+
+// failScan marca o scan como "error" e, se habilitado, registra o evento scan.failed.
+func failScan(store *db.RDSStore, job *models.ScanJob, start time.Time, cause error) {
+	var event *models.ScanEvent
+	if EnableEvents() {
+		event = newScanEvent(job, models.EventScanFailed, "error", nil, start, cause)
+	}
+	if err := store.CompleteScan(job, "error", nil, event); err != nil {
+		logger.Log.Errorf("ProcessService: erro ao registrar falha do scan %s: %v", job.ScanID, err)
+	}
+}
+
+func newScanEvent(job *models.ScanJob, eventType, status string, findings []models.GitleaksFinding, start time.Time, cause error) *models.ScanEvent {
+	byRule := make(map[string]int)
+	for _, f := range findings {
+		byRule[f.RuleID]++
+	}
+	event := &models.ScanEvent{
+		Type:               eventType,
+		ScanID:             job.ScanID,
+		RepositoryID:       job.RepositoryID,
+		RepositoryFullName: job.RepositoryFullName,
+		Sigla:              job.Sigla,
+		Status:             status,
+		TotalFindings:      len(findings),
+		FindingsByRule:     byRule,
+		DurationMs:         time.Since(start).Milliseconds(),
+		OccurredAt:         time.Now().UTC(),
+	}
+	if cause != nil {
+		event.Error = cause.Error()
+		event.ErrorClass = git.ErrorClass(cause)
+	}
+	return event
+}
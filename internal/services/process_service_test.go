@@ -0,0 +1,32 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"yourproject/internal/git"
+	"yourproject/internal/provider"
+	"yourproject/internal/workspace"
+)
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: fmt.Errorf("ProcessService: %w", provider.ErrUnknownProvider), want: true},
+		{err: fmt.Errorf("ProcessService: %w: 10 GB", workspace.ErrTooLarge), want: true},
+		{err: fmt.Errorf("ProcessService: %w", workspace.ErrInsufficientDisk)},
+		{err: fmt.Errorf("git clone (full) abortado: %w", git.ErrCloneTooLarge), want: true},
+		{err: fmt.Errorf("git clone (full) falhou: %w: 401", git.ErrCloneAuth), want: true},
+		{err: fmt.Errorf("git clone (full) falhou: %w: 404", git.ErrCloneNotFound), want: true},
+		{err: fmt.Errorf("git clone (full) abortado: %w", git.ErrCloneTimeout)},
+		{err: errors.New("timeout")},
+	}
+	for _, tt := range tests {
+		if got := isPermanent(tt.err); got != tt.want {
+			t.Errorf("isPermanent(%v) = %v, esperado %v", tt.err, got, tt.want)
+		}
+	}
+}
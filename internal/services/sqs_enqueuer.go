@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"yourproject/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// SQSEnqueuer publica ScanJobs na fila SQS consumida pelo DefaultSQSProducer,
+// distribuindo o trabalho entre todas as réplicas.
+type SQSEnqueuer struct {
+	Client   *sqs.Client
+	QueueURL string
+}
+
+// maxSQSDelay é o maior DelaySeconds aceito pela SQS.
+const maxSQSDelay = 15 * time.Minute
+
+func (e *SQSEnqueuer) Enqueue(job *models.ScanJob) error {
+	return e.EnqueueAfter(job, 0)
+}
+
+// EnqueueAfter publica o job com DelaySeconds, limitado a 15 minutos. Filas FIFO
+// não aceitam atraso por mensagem; nelas o job é entregue sem atraso.
+func (e *SQSEnqueuer) EnqueueAfter(job *models.ScanJob, delay time.Duration) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar job %s: %v", job.ScanID, err)
+	}
+	input := &sqs.SendMessageInput{
+		QueueUrl:    &e.QueueURL,
+		MessageBody: aws.String(string(body)),
+	}
+	if delay > 0 && !IsFIFOQueue(e.QueueURL) {
+		input.DelaySeconds = int32(min(delay, maxSQSDelay).Seconds())
+	}
+	_, err = e.Client.SendMessage(context.Background(), WithFIFO(input, MessageGroupID(job), job.ScanID))
+	if err != nil {
+		return fmt.Errorf("erro ao enviar job %s para a SQS: %v", job.ScanID, err)
+	}
+	return nil
+}
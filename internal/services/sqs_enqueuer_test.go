@@ -0,0 +1,32 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"yourproject/models"
+)
+
+func TestSQSEnqueuerDelay(t *testing.T) {
+	client, fake := newFakeSQS(t)
+	queueURL, err := fake.CreateQueue("jobs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &SQSEnqueuer{Client: client, QueueURL: queueURL}
+	job := &models.ScanJob{ScanID: testScanID(1), RepositoryID: "1", RepositoryFullName: "acme/pix", Sigla: "PIX"}
+
+	// Atrasos acima do limite da SQS são reduzidos a 15 minutos, não rejeitados.
+	if err := e.EnqueueAfter(job, time.Hour); err != nil {
+		t.Fatalf("EnqueueAfter: %v", err)
+	}
+	if visible, _ := queueCounts(t, client, queueURL); visible != "0" {
+		t.Errorf("job com atraso visível na hora: %s mensagens", visible)
+	}
+	if err := e.Enqueue(job); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if visible, _ := queueCounts(t, client, queueURL); visible != "1" {
+		t.Errorf("job sem atraso: %s mensagens visíveis, esperado 1", visible)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"strings"
+
+	"yourproject/models"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// IsFIFOQueue indica se a URL é de uma fila FIFO (nome com sufixo ".fifo").
+func IsFIFOQueue(queueURL string) bool {
+	return strings.HasSuffix(queueURL, ".fifo")
+}
+
+// MessageGroupID agrupa na fila FIFO os jobs do mesmo repositório, garantindo
+// que um scan completo e um incremental do repositório rodem na ordem de envio.
+func MessageGroupID(job *models.ScanJob) string {
+	if job.RepositoryID != "" {
+		return job.RepositoryKey()
+	}
+	return job.RepositoryFullName
+}
+
+// WithFIFO preenche MessageGroupId e MessageDeduplicationId quando a fila de
+// destino é FIFO; envios para filas padrão não são alterados.
+func WithFIFO(input *sqs.SendMessageInput, groupID, dedupID string) *sqs.SendMessageInput {
+	if input.QueueUrl == nil || !IsFIFOQueue(*input.QueueUrl) {
+		return input
+	}
+	input.MessageGroupId = &groupID
+	input.MessageDeduplicationId = &dedupID
+	return input
+}
+
+// messageGroup retorna o grupo da mensagem recebida de uma fila FIFO, ou vazio.
+func messageGroup(msg types.Message) string {
+	return msg.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]
+}
@@ -1,64 +1,630 @@
-package services
-
-import (
-	"context"
-	"encoding/json"
-	"time"
-
-	"yourproject/models"
-	"yourproject/internal/logger"
-
-	"github.com/aws/aws-sdk-go-v2/service/sqs"
-)
-
-type SQSProducer interface {
-	Start() <-chan *models.ScanJob
-}
-
-type DefaultSQSProducer struct {
-	Client   *sqs.Client
-	QueueURL string
-}
-
-func (p *DefaultSQSProducer) Start() <-chan *models.ScanJob {
-	jobChan := make(chan *models.ScanJob, 10)
-	go func() {
-		ctx := context.Background()
-		for {
-			resp, err := p.Client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
-				QueueUrl:            &p.QueueURL,
-				MaxNumberOfMessages: 1,
-				WaitTimeSeconds:     10,
-			})
-			if err != nil {
-				logger.Log.Errorf("Erro ao receber mensagem da SQS: %v", err)
-				time.Sleep(5 * time.Second)
-				continue
-			}
-			if len(resp.Messages) == 0 {
-				continue
-			}
-			msg := resp.Messages[0]
-			var job models.ScanJob
-			if err := json.Unmarshal([]byte(*msg.Body), &job); err != nil {
-				logger.Log.Errorf("Erro ao parsear JSON da mensagem: %v", err)
-				deleteMessage(ctx, p.Client, p.QueueURL, msg.ReceiptHandle)
-				continue
-			}
-			deleteMessage(ctx, p.Client, p.QueueURL, msg.ReceiptHandle)
-			logger.Log.Debugf("Producer: job %s para o repositório %s recebido", job.ScanID, job.RepositoryFullName)
-			jobChan <- &job
-		}
-	}()
-	return jobChan
-}
-
-func deleteMessage(ctx context.Context, client *sqs.Client, queueURL string, receiptHandle *string) {
-	_, err := client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
-		QueueUrl:      &queueURL,
-		ReceiptHandle: receiptHandle,
-	})
-	if err != nil {
-		logger.Log.Errorf("Erro ao deletar mensagem da SQS: %v", err)
-	}
-}
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"yourproject/internal/db"
+	"yourproject/internal/logger"
+	"yourproject/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+const (
+	defaultVisibilityTimeout = 2 * time.Minute
+	defaultRetryBaseDelay    = 30 * time.Second
+	defaultMaxRetryDelay     = 15 * time.Minute
+	maxSQSVisibilityTimeout  = 12 * time.Hour
+	maxReceiveBatch          = 10 // Limite da SQS por ReceiveMessage.
+	dispatchPollInterval     = time.Second
+	// priorAttemptsAttribute é o atributo da mensagem reenviada por requeue com
+	// as tentativas com falha do job antes do reenvio.
+	priorAttemptsAttribute = "ScanPriorAttempts"
+)
+
+// Estratégias de escolha entre as filas do producer.
+const (
+	QueueStrictPriority = "priority" // Sempre despacha da primeira fila com jobs retidos.
+	QueueWeighted       = "weighted" // Alterna entre as filas na proporção dos pesos.
+)
+
+// ParseQueueStrategy valida a estratégia configurada entre as filas; "" retorna
+// QueueStrictPriority.
+func ParseQueueStrategy(s string) (string, error) {
+	switch s {
+	case "", QueueStrictPriority:
+		return QueueStrictPriority, nil
+	case QueueWeighted:
+		return QueueWeighted, nil
+	}
+	return "", fmt.Errorf("estratégia de filas desconhecida %q (use priority ou weighted)", s)
+}
+
+// SQSQueue é uma das filas consumidas pelo producer.
+type SQSQueue struct {
+	Name   string // Identifica a fila nas métricas e nos limites do consumer.
+	URL    string
+	Weight int // Peso na estratégia QueueWeighted (padrão: 1).
+}
+
+// DefaultSQSProducer consome uma ou mais filas com semântica at-least-once: a
+// mensagem só é deletada depois que o consumer confirma o job, e enquanto ele roda
+// um heartbeat estende o timeout de visibilidade.
+type DefaultSQSProducer struct {
+	Client            *sqs.Client
+	QueueURL          string        // Fila única, usada quando Queues está vazio.
+	Queues            []SQSQueue    // Filas em ordem de prioridade (a primeira é a mais urgente).
+	Strategy          string        // QueueStrictPriority (padrão) ou QueueWeighted.
+	VisibilityTimeout time.Duration // Timeout de visibilidade renovado pelo heartbeat.
+	HeartbeatInterval time.Duration // Intervalo entre heartbeats (padrão: 1/3 do timeout).
+	RetryBaseDelay    time.Duration // Atraso da primeira nova tentativa após falha.
+	MaxRetryDelay     time.Duration // Teto do backoff exponencial.
+	ReleaseMargin     time.Duration // Antecedência com que mensagens retidas são devolvidas à fila.
+	MaxAttempts       int           // Recebimentos com falha antes da quarentena (0 = sem limite).
+
+	// Quarantine guarda mensagens inválidas ou que esgotaram as tentativas.
+	Quarantine db.QuarantineStore
+	// Scans recebe o motivo de rejeição de jobs inválidos.
+	Scans db.ScanStore
+
+	// Expiry define o tratamento de jobs antigos recebidos da fila. Com
+	// ExpiryDeprioritize, os jobs vencidos são movidos para a última de Queues.
+	Expiry ExpiryPolicy
+
+	// Control trata mensagens de controle (ex.: cancelamento) publicadas na fila.
+	Control ControlHandler
+
+	// Capacity informa quantos workers estão livres para a fila informada;
+	// dimensiona o prefetch. Se nil, o producer mantém até um lote completo retido.
+	Capacity func(queue string) int
+
+	mu     sync.Mutex
+	groups map[string]groupState // Grupos FIFO com mensagem entregue ao consumer.
+}
+
+// groupState indica a situação de um grupo de mensagens de uma fila FIFO.
+type groupState int
+
+const (
+	groupInFlight groupState = iota + 1 // Uma mensagem do grupo está com o consumer.
+	groupFailed                         // A mensagem falhou; as retidas do grupo voltam à fila.
+)
+
+// Start consome as filas até ctx ser cancelado; então as mensagens retidas
+// voltam à fila e o canal é fechado. Jobs já entregues continuam com heartbeat
+// até o consumer confirmá-los ou devolvê-los.
+func (p *DefaultSQSProducer) Start(ctx context.Context) <-chan *Delivery {
+	p.applyDefaults()
+	// Canal sem buffer: o prefetch fica sob controle do producer, que sabe
+	// quando uma mensagem retida está prestes a voltar a ficar visível.
+	jobChan := make(chan *Delivery)
+	go func() {
+		defer close(jobChan)
+		p.run(ctx, jobChan)
+	}()
+	return jobChan
+}
+
+// prefetched é uma mensagem recebida da fila que ainda aguarda um worker livre.
+type prefetched struct {
+	queue      SQSQueue
+	job        *models.ScanJob
+	msg        types.Message
+	receivedAt time.Time
+	stale      bool   // Excedeu Expiry.MaxAge; só é despachado se não houver jobs recentes.
+	group      string // Grupo FIFO ("<fila>/<MessageGroupId>"); vazio em filas padrão.
+}
+
+// queueLane guarda as mensagens retidas de uma fila.
+type queueLane struct {
+	SQSQueue
+	pending []*prefetched
+	credit  int // Crédito acumulado na estratégia QueueWeighted.
+}
+
+// run recebe e despacha mensagens enquanto stop não for cancelado. As chamadas
+// à SQS usam um contexto sem o cancelamento de stop, para que Ack, Nack e
+// heartbeats das entregas em andamento continuem durante o encerramento.
+func (p *DefaultSQSProducer) run(stop context.Context, jobChan chan<- *Delivery) {
+	ctx := context.WithoutCancel(stop)
+	lanes := make([]*queueLane, len(p.Queues))
+	for i, q := range p.Queues {
+		lanes[i] = &queueLane{SQSQueue: q}
+	}
+	for {
+		if stop.Err() != nil {
+			p.releaseAll(ctx, lanes)
+			return
+		}
+		failed := p.takeFailedGroups()
+		idle := true
+		for _, l := range lanes {
+			l.pending = p.releaseExpiring(ctx, l.pending, failed)
+			if len(l.pending) > 0 {
+				idle = false
+			}
+		}
+
+		// Só faz long polling quando não há nada aguardando despacho, e em uma
+		// fila por vez: a mais prioritária com capacidade.
+		polled := false
+		for _, l := range lanes {
+			want := p.prefetchSize(l.Name) - len(l.pending)
+			if want <= 0 {
+				continue
+			}
+			wait := int32(0)
+			if idle && !polled {
+				wait = 10
+				polled = true
+			}
+			received, err := p.receive(stop, l.SQSQueue, want, wait)
+			l.pending = append(l.pending, received...)
+			if stop.Err() != nil {
+				break
+			}
+			if err != nil {
+				logger.Log.Errorf("Erro ao receber mensagem da SQS (%s): %v", l.Name, err)
+				time.Sleep(5 * time.Second)
+			}
+		}
+
+		l := p.nextLane(lanes)
+		if l == nil {
+			if !polled {
+				time.Sleep(dispatchPollInterval)
+			}
+			continue
+		}
+		i := p.nextPending(l.pending)
+		if p.dispatch(stop, jobChan, l.pending[i]) {
+			l.pending = append(l.pending[:i], l.pending[i+1:]...)
+		}
+	}
+}
+
+// nextLane escolhe a fila do próximo despacho entre as que têm jobs retidos e
+// workers livres, conforme a estratégia configurada.
+func (p *DefaultSQSProducer) nextLane(lanes []*queueLane) *queueLane {
+	var (
+		chosen *queueLane
+		total  int
+	)
+	for _, l := range lanes {
+		if p.nextPending(l.pending) < 0 || p.prefetchSize(l.Name) <= 0 {
+			continue
+		}
+		if p.Strategy != QueueWeighted {
+			return l
+		}
+		// Round-robin ponderado suave: cada fila acumula o seu peso e a de maior
+		// crédito é escolhida, pagando o total acumulado na rodada.
+		l.credit += l.Weight
+		total += l.Weight
+		if chosen == nil || l.credit > chosen.credit {
+			chosen = l
+		}
+	}
+	if chosen != nil {
+		chosen.credit -= total
+	}
+	return chosen
+}
+
+// nextPending escolhe o primeiro job recente retido; jobs vencidos só saem
+// quando não há outro aguardando. Em filas FIFO só a primeira mensagem de cada
+// grupo é elegível, e apenas se o grupo não tiver outra com o consumer.
+// Retorna -1 se nenhuma mensagem pode ser despachada.
+func (p *DefaultSQSProducer) nextPending(pending []*prefetched) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stale := -1
+	seen := make(map[string]bool)
+	for i, m := range pending {
+		if m.group != "" {
+			if seen[m.group] || p.groups[m.group] != 0 {
+				continue
+			}
+			seen[m.group] = true
+		}
+		if !m.stale {
+			return i
+		}
+		if stale < 0 {
+			stale = i
+		}
+	}
+	return stale
+}
+
+// setGroup atualiza a situação do grupo FIFO; state zero libera o grupo.
+func (p *DefaultSQSProducer) setGroup(group string, state groupState) {
+	if group == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if state == 0 {
+		delete(p.groups, group)
+		return
+	}
+	if p.groups == nil {
+		p.groups = make(map[string]groupState)
+	}
+	p.groups[group] = state
+}
+
+// takeFailedGroups retorna e libera os grupos cuja mensagem falhou.
+func (p *DefaultSQSProducer) takeFailedGroups() map[string]bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	failed := make(map[string]bool)
+	for group, state := range p.groups {
+		if state == groupFailed {
+			failed[group] = true
+			delete(p.groups, group)
+		}
+	}
+	return failed
+}
+
+// dispatch entrega a mensagem a um worker e só então inicia o heartbeat dela.
+// Retorna false se nenhum worker a aceitou dentro do intervalo de despacho ou
+// se o producer está encerrando.
+func (p *DefaultSQSProducer) dispatch(stop context.Context, jobChan chan<- *Delivery, m *prefetched) bool {
+	ctx := context.WithoutCancel(stop)
+	hbCtx, stopHeartbeat := context.WithCancel(ctx)
+	delivery := p.newDelivery(ctx, m, stopHeartbeat)
+	// O grupo é marcado antes do envio: o Ack pode chegar antes do retorno do select.
+	p.setGroup(m.group, groupInFlight)
+	select {
+	case jobChan <- delivery:
+		go p.heartbeat(hbCtx, m.queue, m.job.ScanID, m.msg.ReceiptHandle, m.receivedAt)
+		return true
+	case <-time.After(dispatchPollInterval):
+	case <-stop.Done():
+	}
+	stopHeartbeat()
+	p.setGroup(m.group, 0)
+	return false
+}
+
+// releaseAll devolve à fila todas as mensagens retidas, no encerramento.
+func (p *DefaultSQSProducer) releaseAll(ctx context.Context, lanes []*queueLane) {
+	n := 0
+	for _, l := range lanes {
+		for _, m := range l.pending {
+			changeVisibility(ctx, p.Client, m.queue.URL, m.msg.ReceiptHandle, 0)
+			n++
+		}
+		l.pending = nil
+	}
+	logger.Log.Infof("Producer: encerrando; %d mensagens retidas devolvidas à fila", n)
+}
+
+// receive busca até limit mensagens em lote, descartando as que não são jobs válidos.
+func (p *DefaultSQSProducer) receive(ctx context.Context, q SQSQueue, limit int, waitSeconds int32) ([]*prefetched, error) {
+	if limit > maxReceiveBatch {
+		limit = maxReceiveBatch
+	}
+	resp, err := p.Client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            &q.URL,
+		MaxNumberOfMessages: int32(limit),
+		WaitTimeSeconds:     waitSeconds,
+		VisibilityTimeout:   int32(p.VisibilityTimeout.Seconds()),
+		AttributeNames:      []types.QueueAttributeName{types.QueueAttributeNameAll},
+		// Todos os atributos, para que um reenvio por requeue os preserve.
+		MessageAttributeNames: []string{"All"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Mensagens já recebidas são tratadas até o fim mesmo durante o encerramento.
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now()
+	received := make([]*prefetched, 0, len(resp.Messages))
+	for _, msg := range resp.Messages {
+		if ctrl, ok := decodeControl([]byte(aws.ToString(msg.Body))); ok {
+			p.handleControl(ctx, q, msg, ctrl)
+			continue
+		}
+		job, err := decodeJob([]byte(aws.ToString(msg.Body)), p.Scans)
+		if err != nil {
+			logger.Log.Errorf("Producer: mensagem %s rejeitada: %v", aws.ToString(msg.MessageId), err)
+			p.quarantineMessage(ctx, q, msg, rejectedScanID(job), err)
+			continue
+		}
+		stale := p.Expiry.Stale(job, now)
+		if stale && p.Expiry.Drops() {
+			expireJob(p.Scans, q.Name, job, now)
+			deleteMessage(ctx, p.Client, q.URL, msg.ReceiptHandle)
+			continue
+		}
+		if stale && p.demote(ctx, q, msg, job) {
+			continue
+		}
+		logger.Log.Debugf("Producer: job %s para o repositório %s recebido da fila %s", job.ScanID, job.RepositoryFullName, q.Name)
+		m := &prefetched{queue: q, job: job, msg: msg, receivedAt: now, stale: stale}
+		if group := messageGroup(msg); group != "" {
+			m.group = q.Name + "/" + group
+		}
+		received = append(received, m)
+	}
+	return received, nil
+}
+
+// demote move o job vencido para a última fila, a de menor prioridade, onde ele
+// só é despachado depois dos jobs das filas mais urgentes. Retorna false se q já
+// é a última fila (ou a única) ou se o envio falhou; o job então fica retido em
+// q e só é despachado quando não há jobs recentes retidos na mesma fila. A
+// mensagem movida recomeça a contagem de recebimentos.
+func (p *DefaultSQSProducer) demote(ctx context.Context, q SQSQueue, msg types.Message, job *models.ScanJob) bool {
+	low := p.Queues[len(p.Queues)-1]
+	if low.URL == q.URL {
+		return false
+	}
+	input := &sqs.SendMessageInput{
+		QueueUrl:    aws.String(low.URL),
+		MessageBody: msg.Body,
+	}
+	if _, err := p.Client.SendMessage(ctx, WithFIFO(input, MessageGroupID(job), job.ScanID)); err != nil {
+		logger.Log.Errorf("Producer: erro ao mover job %s vencido para a fila %s: %v", job.ScanID, low.Name, err)
+		return false
+	}
+	logger.Log.Infof("Producer: job %s vencido movido da fila %s para a fila %s", job.ScanID, q.Name, low.Name)
+	deleteMessage(ctx, p.Client, q.URL, msg.ReceiptHandle)
+	return true
+}
+
+// handleControl executa a mensagem de controle e a remove da fila. Falhas
+// transitórias a devolvem à fila com backoff.
+func (p *DefaultSQSProducer) handleControl(ctx context.Context, q SQSQueue, msg types.Message, ctrl *models.ControlMessage) {
+	if p.Control == nil {
+		p.quarantineMessage(ctx, q, msg, ctrl.ScanID, fmt.Errorf("mensagem de controle %q sem handler configurado", ctrl.Type))
+		return
+	}
+	if err := p.Control.HandleControl(ctrl); err != nil {
+		if errors.Is(err, ErrUnknownControl) {
+			p.quarantineMessage(ctx, q, msg, ctrl.ScanID, err)
+			return
+		}
+		logger.Log.Warnf("Producer: erro ao tratar mensagem de controle %s: %v", aws.ToString(msg.MessageId), err)
+		changeVisibility(ctx, p.Client, q.URL, msg.ReceiptHandle, p.retryDelay(receiveCount(msg)))
+		return
+	}
+	deleteMessage(ctx, p.Client, q.URL, msg.ReceiptHandle)
+}
+
+// prefetchSize retorna quantas mensagens da fila podem ficar retidas no producer.
+func (p *DefaultSQSProducer) prefetchSize(queue string) int {
+	if p.Capacity == nil {
+		return maxReceiveBatch
+	}
+	return p.Capacity(queue)
+}
+
+// releaseExpiring devolve à fila mensagens retidas cujo timeout de visibilidade
+// está perto de expirar, para que outra réplica possa processá-las. Em filas
+// FIFO, liberar uma mensagem libera também as seguintes do mesmo grupo, assim
+// como as de grupos em failed, preservando a ordem na nova entrega.
+func (p *DefaultSQSProducer) releaseExpiring(ctx context.Context, pending []*prefetched, failed map[string]bool) []*prefetched {
+	kept := pending[:0]
+	for _, m := range pending {
+		if !failed[m.group] && time.Since(m.receivedAt) < p.VisibilityTimeout-p.ReleaseMargin {
+			kept = append(kept, m)
+			continue
+		}
+		if m.group != "" {
+			failed[m.group] = true
+		}
+		logger.Log.Debugf("Producer: liberando job %s retido sem worker disponível", m.job.ScanID)
+		changeVisibility(ctx, p.Client, m.queue.URL, m.msg.ReceiptHandle, 0)
+	}
+	return kept
+}
+
+func (p *DefaultSQSProducer) applyDefaults() {
+	if len(p.Queues) == 0 {
+		p.Queues = []SQSQueue{{Name: "sqs", URL: p.QueueURL}}
+	}
+	for i := range p.Queues {
+		if p.Queues[i].Weight <= 0 {
+			p.Queues[i].Weight = 1
+		}
+	}
+	if p.VisibilityTimeout <= 0 {
+		p.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if p.HeartbeatInterval <= 0 || p.HeartbeatInterval >= p.VisibilityTimeout {
+		p.HeartbeatInterval = p.VisibilityTimeout / 3
+	}
+	if p.RetryBaseDelay <= 0 {
+		p.RetryBaseDelay = defaultRetryBaseDelay
+	}
+	if p.MaxRetryDelay <= 0 {
+		p.MaxRetryDelay = defaultMaxRetryDelay
+	}
+	if p.MaxRetryDelay > maxSQSVisibilityTimeout {
+		p.MaxRetryDelay = maxSQSVisibilityTimeout
+	}
+	if p.ReleaseMargin <= 0 || p.ReleaseMargin >= p.VisibilityTimeout {
+		p.ReleaseMargin = p.VisibilityTimeout / 4
+	}
+}
+
+// newDelivery amarra Ack/Nack às operações na fila; ambos encerram o heartbeat.
+// Em filas FIFO, o fim da entrega libera o grupo; após uma falha as mensagens
+// seguintes do grupo retidas no producer voltam à fila atrás da que falhou.
+func (p *DefaultSQSProducer) newDelivery(ctx context.Context, m *prefetched, stopHeartbeat context.CancelFunc) *Delivery {
+	q, job, msg := m.queue, m.job, m.msg
+	attempts := attemptCount(msg)
+	return &Delivery{
+		Job:         job,
+		Source:      q.Name,
+		GroupID:     m.group,
+		LastAttempt: p.MaxAttempts > 0 && attempts >= p.MaxAttempts,
+		ack: func() {
+			stopHeartbeat()
+			deleteMessage(ctx, p.Client, q.URL, msg.ReceiptHandle)
+			p.setGroup(m.group, 0)
+		},
+		release: func(delay time.Duration) {
+			stopHeartbeat()
+			logger.Log.Infof("Producer: job %s devolvido à fila %s", job.ScanID, q.Name)
+			if !p.requeue(ctx, q, msg, job, attempts-1, delay) {
+				changeVisibility(ctx, p.Client, q.URL, msg.ReceiptHandle, delay)
+			}
+			// Em filas FIFO as seguintes do grupo também voltam, atrás desta.
+			p.setGroup(m.group, groupFailed)
+		},
+		nack: func(reason error) {
+			stopHeartbeat()
+			defer p.setGroup(m.group, groupFailed)
+			if p.MaxAttempts > 0 && attempts >= p.MaxAttempts {
+				p.quarantineMessage(ctx, q, msg, job.ScanID, fmt.Errorf("falhou em %d tentativas: %v", attempts, reason))
+				return
+			}
+			delay := p.retryDelay(attempts)
+			logger.Log.Warnf("Producer: job %s falhou (tentativa %d), volta à fila em %s: %v", job.ScanID, attempts, delay, reason)
+			changeVisibility(ctx, p.Client, q.URL, msg.ReceiptHandle, delay)
+		},
+	}
+}
+
+// requeue reenvia a mensagem para a mesma fila com atraso delay (até 15
+// minutos), guardando em priorAttemptsAttribute as tentativas com falha, e
+// remove a original. Devolvê-la por visibilidade aumentaria o
+// ApproximateReceiveCount e a devolução contaria como tentativa. Retorna false
+// em filas FIFO, onde o reenvio colocaria o job atrás das mensagens seguintes
+// do grupo, e se o envio falhar; a mensagem então é devolvida por visibilidade.
+func (p *DefaultSQSProducer) requeue(ctx context.Context, q SQSQueue, msg types.Message, job *models.ScanJob, failed int, delay time.Duration) bool {
+	if IsFIFOQueue(q.URL) {
+		return false
+	}
+	attrs := make(map[string]types.MessageAttributeValue, len(msg.MessageAttributes)+1)
+	for name, v := range msg.MessageAttributes {
+		attrs[name] = v
+	}
+	attrs[priorAttemptsAttribute] = types.MessageAttributeValue{
+		DataType:    aws.String("Number"),
+		StringValue: aws.String(strconv.Itoa(failed)),
+	}
+	_, err := p.Client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:          aws.String(q.URL),
+		MessageBody:       msg.Body,
+		DelaySeconds:      int32(min(delay, maxSQSDelay).Seconds()),
+		MessageAttributes: attrs,
+	})
+	if err != nil {
+		logger.Log.Errorf("Producer: erro ao reenviar job %s para a fila %s: %v", job.ScanID, q.Name, err)
+		return false
+	}
+	deleteMessage(ctx, p.Client, q.URL, msg.ReceiptHandle)
+	return true
+}
+
+// quarantineMessage grava a mensagem na quarentena e só então a remove da fila.
+// Se a gravação falhar, a mensagem volta à fila com backoff para não ser perdida.
+func (p *DefaultSQSProducer) quarantineMessage(ctx context.Context, q SQSQueue, msg types.Message, scanID string, reason error) {
+	messageID := aws.ToString(msg.MessageId)
+	if p.Quarantine == nil {
+		logger.Log.Errorf("Producer: quarentena não configurada; descartando mensagem %s: %v", messageID, reason)
+		deleteMessage(ctx, p.Client, q.URL, msg.ReceiptHandle)
+		return
+	}
+	entry := &models.QuarantinedJob{
+		Source:     q.URL,
+		MessageID:  messageID,
+		ScanID:     scanID,
+		Body:       aws.ToString(msg.Body),
+		Attributes: msg.Attributes,
+		Reason:     reason.Error(),
+		Attempts:   attemptCount(msg),
+	}
+	if err := p.Quarantine.InsertQuarantine(entry); err != nil {
+		logger.Log.Errorf("Producer: erro ao mover mensagem %s para a quarentena: %v", messageID, err)
+		changeVisibility(ctx, p.Client, q.URL, msg.ReceiptHandle, p.retryDelay(entry.Attempts))
+		return
+	}
+	logger.Log.Warnf("Producer: mensagem %s movida para a quarentena (%s): %v", messageID, entry.ID, reason)
+	deleteMessage(ctx, p.Client, q.URL, msg.ReceiptHandle)
+}
+
+func (p *DefaultSQSProducer) heartbeat(ctx context.Context, q SQSQueue, scanID string, receiptHandle *string, receivedAt time.Time) {
+	// Mensagens que ficaram retidas no prefetch já consumiram parte da visibilidade.
+	if time.Since(receivedAt) >= p.HeartbeatInterval {
+		changeVisibility(ctx, p.Client, q.URL, receiptHandle, p.VisibilityTimeout)
+	}
+	ticker := time.NewTicker(p.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			logger.Log.Debugf("Producer: heartbeat do job %s, estendendo visibilidade por %s", scanID, p.VisibilityTimeout)
+			changeVisibility(ctx, p.Client, q.URL, receiptHandle, p.VisibilityTimeout)
+		}
+	}
+}
+
+// retryDelay calcula o backoff exponencial a partir do número de recebimentos da mensagem.
+func (p *DefaultSQSProducer) retryDelay(attempts int) time.Duration {
+	delay := p.RetryBaseDelay
+	for i := 1; i < attempts && delay < p.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxRetryDelay {
+		delay = p.MaxRetryDelay
+	}
+	return delay
+}
+
+// attemptCount conta as tentativas do job: os recebimentos da mensagem mais as
+// falhas anteriores a um requeue, que recomeça o ApproximateReceiveCount.
+func attemptCount(msg types.Message) int {
+	n := receiveCount(msg)
+	if v, ok := msg.MessageAttributes[priorAttemptsAttribute]; ok {
+		if failed, err := strconv.Atoi(aws.ToString(v.StringValue)); err == nil && failed > 0 {
+			n += failed
+		}
+	}
+	return n
+}
+
+func receiveCount(msg types.Message) int {
+	n, err := strconv.Atoi(msg.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+func deleteMessage(ctx context.Context, client *sqs.Client, queueURL string, receiptHandle *string) {
+	_, err := client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      &queueURL,
+		ReceiptHandle: receiptHandle,
+	})
+	if err != nil {
+		logger.Log.Errorf("Erro ao deletar mensagem da SQS: %v", err)
+	}
+}
+
+func changeVisibility(ctx context.Context, client *sqs.Client, queueURL string, receiptHandle *string, timeout time.Duration) {
+	_, err := client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          &queueURL,
+		ReceiptHandle:     receiptHandle,
+		VisibilityTimeout: int32(timeout.Seconds()),
+	})
+	if err != nil && ctx.Err() == nil {
+		logger.Log.Errorf("Erro ao alterar visibilidade da mensagem na SQS: %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// testScanID retorna um ScanID válido distinto para cada n.
+func testScanID(n int) string {
+	return fmt.Sprintf("0b6f3c1e-5d2a-4c59-9d8e-%012d", n)
+}
+
+// waitFor espera cond ser verdadeira por até 5 segundos.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("tempo esgotado esperando %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestParseQueueStrategy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: QueueStrictPriority},
+		{in: "priority", want: QueueStrictPriority},
+		{in: "weighted", want: QueueWeighted},
+		{in: "weigthed", wantErr: true},
+		{in: "Weighted", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseQueueStrategy(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseQueueStrategy(%q) = %q, %v; esperado %q, erro=%v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// O producer só retém tantas mensagens quantos workers livres há e as devolve
+// à fila no encerramento.
+func TestProducerPrefetch(t *testing.T) {
+	client, fake := newFakeSQS(t)
+	queueURL, err := fake.CreateQueue("jobs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		sendJobs(t, client, queueURL, testScanID(i))
+	}
+
+	var free atomic.Int32
+	free.Store(2)
+	ctx, stop := context.WithCancel(context.Background())
+	p := &DefaultSQSProducer{
+		Client:   client,
+		QueueURL: queueURL,
+		Capacity: func(queue string) int { return int(free.Load()) },
+	}
+	// Ninguém lê as entregas: as mensagens ficam retidas no producer.
+	deliveries := p.Start(ctx)
+	waitFor(t, "o primeiro lote", func() bool {
+		_, inFlight := queueCounts(t, client, queueURL)
+		return inFlight == "2"
+	})
+	// Sem mais workers livres, o lote não cresce.
+	time.Sleep(2 * dispatchPollInterval)
+	if visible, inFlight := queueCounts(t, client, queueURL); visible != "3" || inFlight != "2" {
+		t.Errorf("com 2 workers livres: %s visíveis e %s retidas, esperado 3 e 2", visible, inFlight)
+	}
+
+	free.Store(4)
+	waitFor(t, "o lote com 4 workers livres", func() bool {
+		_, inFlight := queueCounts(t, client, queueURL)
+		return inFlight == "4"
+	})
+
+	stop()
+	// Uma entrega em andamento no cancelamento é devolvida, como faz o consumer.
+	for d := range deliveries {
+		d.Release()
+	}
+	if visible, inFlight := queueCounts(t, client, queueURL); visible != "5" || inFlight != "0" {
+		t.Errorf("após o encerramento: %s visíveis e %s retidas, esperado 5 e 0", visible, inFlight)
+	}
+}
+
+// nextDelivery retorna a próxima entrega do producer ou nil se nenhuma chegar em wait.
+func nextDelivery(deliveries <-chan *Delivery, wait time.Duration) *Delivery {
+	select {
+	case d := <-deliveries:
+		return d
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// Em filas FIFO, a mensagem seguinte de um grupo só é entregue depois que a
+// anterior termina; os demais grupos continuam sendo entregues.
+func TestProducerFIFOGroups(t *testing.T) {
+	client, fake := newFakeSQS(t)
+	queueURL, err := fake.CreateQueue("jobs.fifo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []struct {
+		id    int
+		group string
+	}{{1, "acme/pix"}, {2, "acme/pix"}, {3, "acme/legado"}} {
+		body := `{"schema_version":5,"scan_id":"` + testScanID(m.id) + `","repository_id":"1","repository_full_name":"acme/pix","sigla":"PIX"}`
+		if _, err := client.SendMessage(context.Background(), &sqs.SendMessageInput{
+			QueueUrl:               aws.String(queueURL),
+			MessageBody:            aws.String(body),
+			MessageGroupId:         aws.String(m.group),
+			MessageDeduplicationId: aws.String(testScanID(m.id)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	p := &DefaultSQSProducer{Client: client, QueueURL: queueURL}
+	deliveries := p.Start(ctx)
+	defer func() {
+		stop()
+		for d := range deliveries {
+			d.Release()
+		}
+	}()
+
+	first := nextDelivery(deliveries, 5*time.Second)
+	if first == nil || first.Job.ScanID != testScanID(1) {
+		t.Fatalf("primeira entrega %v, esperado o job 1", first)
+	}
+	second := nextDelivery(deliveries, 5*time.Second)
+	if second == nil || second.Job.ScanID != testScanID(3) {
+		t.Fatalf("segunda entrega %v, esperado o job 3 do outro grupo", second)
+	}
+	if d := nextDelivery(deliveries, 3*dispatchPollInterval); d != nil {
+		t.Fatalf("job %s entregue com o job 1 do mesmo grupo em execução", d.Job.ScanID)
+	}
+
+	first.Ack()
+	third := nextDelivery(deliveries, 5*time.Second)
+	if third == nil || third.Job.ScanID != testScanID(2) {
+		t.Fatalf("terceira entrega %v, esperado o job 2 após o fim do job 1", third)
+	}
+	if third.GroupID != first.GroupID || third.GroupID == second.GroupID {
+		t.Errorf("grupos %q, %q e %q", first.GroupID, second.GroupID, third.GroupID)
+	}
+	second.Ack()
+	third.Ack()
+}
+
+// ReleaseAfter em uma fila padrão não conta como tentativa: o job adiado
+// (ex.: por falta de disco) só chega à última tentativa depois de MaxAttempts
+// falhas, e volta à fila só após o atraso.
+func TestProducerReleaseAfter(t *testing.T) {
+	client, fake := newFakeSQS(t)
+	queueURL, err := fake.CreateQueue("jobs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendJobs(t, client, queueURL, testScanID(1))
+
+	ctx, stop := context.WithCancel(context.Background())
+	p := &DefaultSQSProducer{
+		Client:         client,
+		QueueURL:       queueURL,
+		MaxAttempts:    2,
+		RetryBaseDelay: time.Millisecond,
+	}
+	deliveries := p.Start(ctx)
+	defer func() {
+		stop()
+		for d := range deliveries {
+			d.Release()
+		}
+	}()
+
+	d := nextDelivery(deliveries, 5*time.Second)
+	if d == nil || d.LastAttempt {
+		t.Fatalf("primeira entrega %v", d)
+	}
+	d.ReleaseAfter(2 * time.Second)
+	if again := nextDelivery(deliveries, time.Second); again != nil {
+		t.Fatalf("job entregue de novo antes do atraso")
+	}
+	for _, adiado := range []int{1, 2} {
+		d = nextDelivery(deliveries, 5*time.Second)
+		if d == nil || d.LastAttempt {
+			t.Fatalf("entrega após %d adiamentos: %v, esperado sem ser a última tentativa", adiado, d)
+		}
+		if adiado == 1 {
+			d.ReleaseAfter(0)
+		}
+	}
+
+	// Só as falhas contam: após uma, a entrega seguinte é a última tentativa.
+	d.Nack(errors.New("falha transitória"))
+	d = nextDelivery(deliveries, 5*time.Second)
+	if d == nil || !d.LastAttempt {
+		t.Fatalf("entrega após uma falha: %v, esperado a última tentativa", d)
+	}
+	d.Ack()
+	waitFor(t, "a remoção do job", func() bool {
+		visible, inFlight := queueCounts(t, client, queueURL)
+		return visible == "0" && inFlight == "0"
+	})
+}
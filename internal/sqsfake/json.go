@@ -0,0 +1,124 @@
+package sqsfake
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"yourproject/internal/logger"
+)
+
+// serveJSON atende o protocolo AWS JSON 1.0 (header X-Amz-Target: AmazonSQS.<Ação>).
+func (s *Server) serveJSON(w http.ResponseWriter, r *http.Request, action string) {
+	var (
+		out any
+		err error
+	)
+	switch action {
+	case "CreateQueue":
+		var in struct {
+			QueueName  string
+			Attributes map[string]string
+		}
+		if err = decodeJSON(r, &in); err == nil {
+			var url string
+			s.mu.Lock()
+			url, err = s.createQueue(r.Host, in.QueueName, in.Attributes)
+			s.mu.Unlock()
+			out = map[string]string{"QueueUrl": url}
+		}
+	case "GetQueueUrl":
+		var in struct{ QueueName string }
+		if err = decodeJSON(r, &in); err == nil {
+			var url string
+			url, err = s.getQueueURL(r.Host, in.QueueName)
+			out = map[string]string{"QueueUrl": url}
+		}
+	case "ListQueues":
+		var in struct{ QueueNamePrefix string }
+		if err = decodeJSON(r, &in); err == nil {
+			out = map[string][]string{"QueueUrls": s.listQueues(r.Host, in.QueueNamePrefix)}
+		}
+	case "DeleteQueue", "PurgeQueue":
+		var in struct{ QueueUrl string }
+		if err = decodeJSON(r, &in); err == nil {
+			if action == "DeleteQueue" {
+				err = s.deleteQueue(in.QueueUrl)
+			} else {
+				err = s.purgeQueue(in.QueueUrl)
+			}
+			out = struct{}{}
+		}
+	case "SendMessage":
+		var in sendMessageInput
+		if err = decodeJSON(r, &in); err == nil {
+			out, err = s.sendMessage(&in)
+		}
+	case "ReceiveMessage":
+		var in receiveMessageInput
+		if err = decodeJSON(r, &in); err == nil {
+			var msgs []receivedMessage
+			msgs, err = s.receiveMessage(r.Context(), &in)
+			out = struct {
+				Messages []receivedMessage `json:",omitempty"`
+			}{msgs}
+		}
+	case "DeleteMessage":
+		var in struct{ QueueUrl, ReceiptHandle string }
+		if err = decodeJSON(r, &in); err == nil {
+			err = s.deleteMessage(in.QueueUrl, in.ReceiptHandle)
+			out = struct{}{}
+		}
+	case "ChangeMessageVisibility":
+		var in struct {
+			QueueUrl          string
+			ReceiptHandle     string
+			VisibilityTimeout int32
+		}
+		if err = decodeJSON(r, &in); err == nil {
+			err = s.changeMessageVisibility(in.QueueUrl, in.ReceiptHandle, in.VisibilityTimeout)
+			out = struct{}{}
+		}
+	case "GetQueueAttributes":
+		var in struct {
+			QueueUrl       string
+			AttributeNames []string
+		}
+		if err = decodeJSON(r, &in); err == nil {
+			var attrs map[string]string
+			attrs, err = s.getQueueAttributes(in.QueueUrl, in.AttributeNames)
+			out = struct {
+				Attributes map[string]string `json:",omitempty"`
+			}{attrs}
+		}
+	case "SetQueueAttributes":
+		var in struct {
+			QueueUrl   string
+			Attributes map[string]string
+		}
+		if err = decodeJSON(r, &in); err == nil {
+			err = s.setQueueAttributes(in.QueueUrl, in.Attributes)
+			out = struct{}{}
+		}
+	default:
+		err = errUnsupportedOperation(action)
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Header().Set("x-amzn-RequestId", requestID())
+	if err != nil {
+		e := asAPIError(err)
+		w.Header().Set("x-amzn-query-error", e.queryCode+";Sender")
+		w.WriteHeader(http.StatusBadRequest)
+		out = map[string]string{"__type": "com.amazonaws.sqs#" + e.code, "message": e.message}
+	}
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		logger.Log.Errorf("SQS fake: erro ao escrever resposta de %s: %v", action, err)
+	}
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errInvalidParameter("corpo JSON inválido: " + err.Error())
+	}
+	return nil
+}
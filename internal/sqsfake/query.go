@@ -0,0 +1,290 @@
+package sqsfake
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"yourproject/internal/logger"
+)
+
+const queryNamespace = "http://queue.amazonaws.com/doc/2012-11-05/"
+
+// serveQuery atende o protocolo query (formulário com Action=<Ação>, resposta em XML).
+func (s *Server) serveQuery(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeQueryError(w, errInvalidParameter("formulário inválido: "+err.Error()))
+		return
+	}
+	form := queryForm{r}
+	action := r.Form.Get("Action")
+	queueURL := r.Form.Get("QueueUrl")
+	if queueURL == "" && r.URL.Path != "" && r.URL.Path != "/" {
+		queueURL = r.URL.Path
+	}
+
+	var (
+		result []any
+		err    error
+	)
+	switch action {
+	case "CreateQueue":
+		var url string
+		s.mu.Lock()
+		url, err = s.createQueue(r.Host, r.Form.Get("QueueName"), form.attributes())
+		s.mu.Unlock()
+		result = append(result, xmlElem("QueueUrl", url))
+	case "GetQueueUrl":
+		var url string
+		url, err = s.getQueueURL(r.Host, r.Form.Get("QueueName"))
+		result = append(result, xmlElem("QueueUrl", url))
+	case "ListQueues":
+		for _, url := range s.listQueues(r.Host, r.Form.Get("QueueNamePrefix")) {
+			result = append(result, xmlElem("QueueUrl", url))
+		}
+	case "DeleteQueue":
+		err = s.deleteQueue(queueURL)
+	case "PurgeQueue":
+		err = s.purgeQueue(queueURL)
+	case "SendMessage":
+		in := &sendMessageInput{
+			QueueUrl:               queueURL,
+			MessageBody:            r.Form.Get("MessageBody"),
+			DelaySeconds:           form.integer("DelaySeconds"),
+			MessageGroupId:         r.Form.Get("MessageGroupId"),
+			MessageDeduplicationId: r.Form.Get("MessageDeduplicationId"),
+			MessageAttributes:      form.messageAttributes(),
+		}
+		var out *sendMessageOutput
+		if out, err = s.sendMessage(in); err == nil {
+			result = append(result, xmlElem("MessageId", out.MessageId), xmlElem("MD5OfMessageBody", out.MD5OfMessageBody))
+			if out.SequenceNumber != "" {
+				result = append(result, xmlElem("SequenceNumber", out.SequenceNumber))
+			}
+		}
+	case "ReceiveMessage":
+		in := &receiveMessageInput{
+			QueueUrl:                    queueURL,
+			VisibilityTimeout:           form.integer("VisibilityTimeout"),
+			WaitTimeSeconds:             form.integer("WaitTimeSeconds"),
+			AttributeNames:              form.list("AttributeName"),
+			MessageSystemAttributeNames: form.list("MessageSystemAttributeName"),
+			MessageAttributeNames:       form.list("MessageAttributeName"),
+		}
+		if n := form.integer("MaxNumberOfMessages"); n != nil {
+			in.MaxNumberOfMessages = *n
+		}
+		var msgs []receivedMessage
+		msgs, err = s.receiveMessage(r.Context(), in)
+		for _, m := range msgs {
+			result = append(result, xmlMessage{
+				MessageId:        m.MessageId,
+				ReceiptHandle:    m.ReceiptHandle,
+				MD5OfBody:        m.MD5OfBody,
+				Body:             m.Body,
+				Attribute:        xmlAttributes(m.Attributes),
+				MessageAttribute: xmlMessageAttributes(m.MessageAttributes),
+			})
+		}
+	case "DeleteMessage":
+		err = s.deleteMessage(queueURL, r.Form.Get("ReceiptHandle"))
+	case "ChangeMessageVisibility":
+		timeout := form.integer("VisibilityTimeout")
+		if timeout == nil {
+			err = errMissingParameter("VisibilityTimeout")
+			break
+		}
+		err = s.changeMessageVisibility(queueURL, r.Form.Get("ReceiptHandle"), *timeout)
+	case "GetQueueAttributes":
+		var attrs map[string]string
+		attrs, err = s.getQueueAttributes(queueURL, form.list("AttributeName"))
+		for _, a := range xmlAttributes(attrs) {
+			result = append(result, a)
+		}
+	case "SetQueueAttributes":
+		err = s.setQueueAttributes(queueURL, form.attributes())
+	default:
+		err = errUnsupportedOperation(action)
+	}
+
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	writeQueryResponse(w, action, result)
+}
+
+// queryForm lê os parâmetros numerados do protocolo query (ex.: AttributeName.1).
+type queryForm struct {
+	r *http.Request
+}
+
+func (f queryForm) list(prefix string) []string {
+	var values []string
+	for i := 1; ; i++ {
+		v := f.r.Form.Get(fmt.Sprintf("%s.%d", prefix, i))
+		if v == "" {
+			return values
+		}
+		values = append(values, v)
+	}
+}
+
+// attributes lê pares Attribute.N.Name/Attribute.N.Value.
+func (f queryForm) attributes() map[string]string {
+	attrs := make(map[string]string)
+	for i := 1; ; i++ {
+		name := f.r.Form.Get(fmt.Sprintf("Attribute.%d.Name", i))
+		if name == "" {
+			return attrs
+		}
+		attrs[name] = f.r.Form.Get(fmt.Sprintf("Attribute.%d.Value", i))
+	}
+}
+
+// messageAttributes lê os grupos MessageAttribute.N.Name/Value.DataType/
+// Value.StringValue/Value.BinaryValue (este em base64).
+func (f queryForm) messageAttributes() map[string]messageAttribute {
+	var attrs map[string]messageAttribute
+	for i := 1; ; i++ {
+		key := fmt.Sprintf("MessageAttribute.%d.", i)
+		name := f.r.Form.Get(key + "Name")
+		if name == "" {
+			return attrs
+		}
+		if attrs == nil {
+			attrs = make(map[string]messageAttribute)
+		}
+		attr := messageAttribute{
+			DataType:    f.r.Form.Get(key + "Value.DataType"),
+			StringValue: f.r.Form.Get(key + "Value.StringValue"),
+		}
+		if v := f.r.Form.Get(key + "Value.BinaryValue"); v != "" {
+			// Valor inválido fica vazio e é rejeitado pela validação do envio.
+			attr.BinaryValue, _ = base64.StdEncoding.DecodeString(v)
+		}
+		attrs[name] = attr
+	}
+}
+
+func (f queryForm) integer(name string) *int32 {
+	v := f.r.Form.Get(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		// Valor fora do intervalo; a validação da operação o rejeita.
+		n = -1
+	}
+	n32 := int32(n)
+	return &n32
+}
+
+type xmlText struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+func xmlElem(name, value string) xmlText {
+	return xmlText{XMLName: xml.Name{Local: name}, Value: value}
+}
+
+type xmlAttribute struct {
+	XMLName xml.Name `xml:"Attribute"`
+	Name    string
+	Value   string
+}
+
+type xmlMessageAttribute struct {
+	XMLName xml.Name `xml:"MessageAttribute"`
+	Name    string
+	Value   struct {
+		DataType    string
+		StringValue string `xml:",omitempty"`
+		BinaryValue string `xml:",omitempty"` // Base64.
+	}
+}
+
+type xmlMessage struct {
+	XMLName          xml.Name `xml:"Message"`
+	MessageId        string
+	ReceiptHandle    string
+	MD5OfBody        string
+	Body             string
+	Attribute        []xmlAttribute
+	MessageAttribute []xmlMessageAttribute
+}
+
+func xmlAttributes(attrs map[string]string) []xmlAttribute {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]xmlAttribute, 0, len(names))
+	for _, name := range names {
+		out = append(out, xmlAttribute{Name: name, Value: attrs[name]})
+	}
+	return out
+}
+
+func xmlMessageAttributes(attrs map[string]messageAttribute) []xmlMessageAttribute {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]xmlMessageAttribute, 0, len(names))
+	for _, name := range names {
+		a := xmlMessageAttribute{Name: name}
+		a.Value.DataType = attrs[name].DataType
+		a.Value.StringValue = attrs[name].StringValue
+		if v := attrs[name].BinaryValue; len(v) > 0 {
+			a.Value.BinaryValue = base64.StdEncoding.EncodeToString(v)
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// writeQueryResponse escreve <Ação>Response com <Ação>Result (se houver) e o RequestId.
+func writeQueryResponse(w http.ResponseWriter, action string, result []any) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<%sResponse xmlns="%s">`, action, queryNamespace)
+	if len(result) > 0 {
+		fmt.Fprintf(&buf, "<%sResult>", action)
+		for _, v := range result {
+			if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+				writeQueryError(w, err)
+				return
+			}
+		}
+		fmt.Fprintf(&buf, "</%sResult>", action)
+	}
+	fmt.Fprintf(&buf, "<ResponseMetadata><RequestId>%s</RequestId></ResponseMetadata></%sResponse>", requestID(), action)
+	writeXML(w, http.StatusOK, buf.Bytes())
+}
+
+func writeQueryError(w http.ResponseWriter, err error) {
+	e := asAPIError(err)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<ErrorResponse xmlns="%s"><Error><Type>Sender</Type><Code>`, queryNamespace)
+	xml.EscapeText(&buf, []byte(e.queryCode))
+	buf.WriteString("</Code><Message>")
+	xml.EscapeText(&buf, []byte(e.message))
+	fmt.Fprintf(&buf, "</Message></Error><RequestId>%s</RequestId></ErrorResponse>", requestID())
+	writeXML(w, http.StatusBadRequest, buf.Bytes())
+}
+
+func writeXML(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(status)
+	if _, err := w.Write(append([]byte(xml.Header), body...)); err != nil {
+		logger.Log.Errorf("SQS fake: erro ao escrever resposta: %v", err)
+	}
+}
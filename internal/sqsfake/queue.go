@@ -0,0 +1,393 @@
+package sqsfake
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultVisibilityTimeout = 30 * time.Second
+	defaultRetentionPeriod   = 4 * 24 * time.Hour
+	maxVisibilityTimeout     = 12 * time.Hour
+	maxReceiveMessages       = 10
+	maxWaitTime              = 20 * time.Second
+	fifoDedupWindow          = 5 * time.Minute
+)
+
+// queue guarda as mensagens de uma fila e os atributos configuráveis.
+type queue struct {
+	name      string
+	fifo      bool
+	createdAt time.Time
+	updatedAt time.Time
+
+	visibilityTimeout time.Duration
+	delay             time.Duration
+	retention         time.Duration
+	waitTime          time.Duration
+	contentDedup      bool
+
+	messages []*message
+	dedup    map[string]dedupEntry // Deduplicação das filas FIFO.
+	sequence int64
+
+	// notify é fechado e recriado a cada mudança, acordando receives em long polling.
+	notify chan struct{}
+}
+
+// message é uma mensagem armazenada na fila.
+type message struct {
+	id            string
+	body          string
+	md5           string
+	groupID       string
+	dedupID       string
+	sequence      string
+	sentAt        time.Time
+	visibleAt     time.Time
+	receiptHandle string // Recibo do último recebimento; vazio se nunca recebida.
+	receiveCount  int
+	firstReceive  time.Time
+	attributes    map[string]messageAttribute // Atributos definidos por quem enviou.
+}
+
+// messageAttribute é um atributo de mensagem (MessageAttributes) enviado com o corpo.
+type messageAttribute struct {
+	DataType    string
+	StringValue string `json:",omitempty"`
+	BinaryValue []byte `json:",omitempty"`
+}
+
+type dedupEntry struct {
+	messageID string
+	sequence  string
+	at        time.Time
+}
+
+func newQueue(name string, now time.Time) *queue {
+	return &queue{
+		name:              name,
+		fifo:              strings.HasSuffix(name, ".fifo"),
+		createdAt:         now,
+		updatedAt:         now,
+		visibilityTimeout: defaultVisibilityTimeout,
+		retention:         defaultRetentionPeriod,
+		dedup:             make(map[string]dedupEntry),
+		notify:            make(chan struct{}),
+	}
+}
+
+// changed acorda os receives que aguardam mensagens.
+func (q *queue) changed() {
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+// inFlight indica se a mensagem foi recebida e ainda está invisível.
+func (m *message) inFlight(now time.Time) bool {
+	return m.receiptHandle != "" && now.Before(m.visibleAt)
+}
+
+func (q *queue) send(body string, delay *int32, groupID, dedupID string, now time.Time) (*message, error) {
+	if body == "" {
+		return nil, errMissingParameter("MessageBody")
+	}
+	m := &message{
+		id:      uuid.New().String(),
+		body:    body,
+		md5:     md5Hex(body),
+		sentAt:  now,
+		groupID: groupID,
+		dedupID: dedupID,
+	}
+
+	d := q.delay
+	if delay != nil {
+		if q.fifo {
+			return nil, errInvalidParameter("DelaySeconds por mensagem não é suportado em filas FIFO")
+		}
+		d = time.Duration(*delay) * time.Second
+	}
+	m.visibleAt = now.Add(d)
+
+	if q.fifo {
+		if groupID == "" {
+			return nil, errMissingParameter("MessageGroupId")
+		}
+		if dedupID == "" {
+			if !q.contentDedup {
+				return nil, errInvalidParameter("a fila FIFO exige MessageDeduplicationId ou ContentBasedDeduplication")
+			}
+			sum := sha256.Sum256([]byte(body))
+			m.dedupID = hex.EncodeToString(sum[:])
+		}
+		for id, e := range q.dedup {
+			if now.Sub(e.at) > fifoDedupWindow {
+				delete(q.dedup, id)
+			}
+		}
+		// Mensagem repetida dentro da janela: aceita sem enfileirar de novo.
+		if e, ok := q.dedup[m.dedupID]; ok {
+			return &message{id: e.messageID, md5: m.md5, sequence: e.sequence}, nil
+		}
+		q.sequence++
+		m.sequence = fmt.Sprintf("%020d", q.sequence)
+		q.dedup[m.dedupID] = dedupEntry{messageID: m.id, sequence: m.sequence, at: now}
+	}
+
+	q.messages = append(q.messages, m)
+	q.changed()
+	return m, nil
+}
+
+// receive entrega até limit mensagens visíveis, tornando-as invisíveis por
+// visibility. Em filas FIFO, um grupo com mensagem em voo fica bloqueado.
+func (q *queue) receive(limit int, visibility time.Duration, now time.Time) []*message {
+	q.expire(now)
+
+	blocked := make(map[string]bool)
+	if q.fifo {
+		for _, m := range q.messages {
+			if m.inFlight(now) {
+				blocked[m.groupID] = true
+			}
+		}
+	}
+
+	var out []*message
+	for _, m := range q.messages {
+		if len(out) >= limit {
+			break
+		}
+		if q.fifo && blocked[m.groupID] {
+			continue
+		}
+		if now.Before(m.visibleAt) {
+			// Em filas FIFO as mensagens seguintes do grupo não podem ultrapassá-la.
+			if q.fifo {
+				blocked[m.groupID] = true
+			}
+			continue
+		}
+		m.receiveCount++
+		if m.firstReceive.IsZero() {
+			m.firstReceive = now
+		}
+		m.receiptHandle = m.id + "#" + uuid.New().String()
+		m.visibleAt = now.Add(visibility)
+		out = append(out, m)
+	}
+	return out
+}
+
+// expire descarta mensagens além do período de retenção.
+func (q *queue) expire(now time.Time) {
+	kept := q.messages[:0]
+	for _, m := range q.messages {
+		if now.Sub(m.sentAt) < q.retention {
+			kept = append(kept, m)
+		}
+	}
+	for i := len(kept); i < len(q.messages); i++ {
+		q.messages[i] = nil
+	}
+	q.messages = kept
+}
+
+// find localiza a mensagem pelo recibo. Recibos bem formados de mensagens já
+// removidas ou recebidas de novo retornam nil sem erro, como na SQS.
+func (q *queue) find(receiptHandle string) (int, *message, error) {
+	id, _, ok := strings.Cut(receiptHandle, "#")
+	if !ok || id == "" {
+		return -1, nil, errInvalidReceiptHandle(receiptHandle)
+	}
+	for i, m := range q.messages {
+		if m.id == id {
+			if m.receiptHandle != receiptHandle {
+				return -1, nil, nil
+			}
+			return i, m, nil
+		}
+	}
+	return -1, nil, nil
+}
+
+func (q *queue) delete(receiptHandle string) error {
+	i, m, err := q.find(receiptHandle)
+	if err != nil || m == nil {
+		return err
+	}
+	q.messages = append(q.messages[:i], q.messages[i+1:]...)
+	q.changed()
+	return nil
+}
+
+func (q *queue) changeVisibility(receiptHandle string, timeout int32, now time.Time) error {
+	if timeout < 0 || time.Duration(timeout)*time.Second > maxVisibilityTimeout {
+		return errInvalidParameter(fmt.Sprintf("VisibilityTimeout inválido: %d", timeout))
+	}
+	_, m, err := q.find(receiptHandle)
+	if err != nil {
+		return err
+	}
+	if m == nil || !m.inFlight(now) {
+		return errMessageNotInflight()
+	}
+	m.visibleAt = now.Add(time.Duration(timeout) * time.Second)
+	q.changed()
+	return nil
+}
+
+func (q *queue) purge() {
+	q.messages = nil
+	q.changed()
+}
+
+// attributes retorna os atributos pedidos ("All" retorna todos).
+func (q *queue) attributes(s *Server, names []string, now time.Time) map[string]string {
+	q.expire(now)
+	var visible, inFlight, delayed int
+	for _, m := range q.messages {
+		switch {
+		case m.inFlight(now):
+			inFlight++
+		case now.Before(m.visibleAt):
+			delayed++
+		default:
+			visible++
+		}
+	}
+	all := map[string]string{
+		"QueueArn":                              s.queueARN(q.name),
+		"VisibilityTimeout":                     seconds(q.visibilityTimeout),
+		"DelaySeconds":                          seconds(q.delay),
+		"MessageRetentionPeriod":                seconds(q.retention),
+		"ReceiveMessageWaitTimeSeconds":         seconds(q.waitTime),
+		"MaximumMessageSize":                    "262144",
+		"CreatedTimestamp":                      strconv.FormatInt(q.createdAt.Unix(), 10),
+		"LastModifiedTimestamp":                 strconv.FormatInt(q.updatedAt.Unix(), 10),
+		"ApproximateNumberOfMessages":           strconv.Itoa(visible),
+		"ApproximateNumberOfMessagesNotVisible": strconv.Itoa(inFlight),
+		"ApproximateNumberOfMessagesDelayed":    strconv.Itoa(delayed),
+	}
+	if q.fifo {
+		all["FifoQueue"] = "true"
+		all["ContentBasedDeduplication"] = strconv.FormatBool(q.contentDedup)
+	}
+	return pick(all, names)
+}
+
+// setAttributes aplica os atributos configuráveis da fila.
+func (q *queue) setAttributes(attrs map[string]string, now time.Time) error {
+	for name, value := range attrs {
+		switch name {
+		case "VisibilityTimeout", "DelaySeconds", "MessageRetentionPeriod", "ReceiveMessageWaitTimeSeconds":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return errInvalidAttributeValue(name, value)
+			}
+			d := time.Duration(n) * time.Second
+			switch name {
+			case "VisibilityTimeout":
+				if d > maxVisibilityTimeout {
+					return errInvalidAttributeValue(name, value)
+				}
+				q.visibilityTimeout = d
+			case "DelaySeconds":
+				q.delay = d
+			case "MessageRetentionPeriod":
+				q.retention = d
+			case "ReceiveMessageWaitTimeSeconds":
+				if d > maxWaitTime {
+					return errInvalidAttributeValue(name, value)
+				}
+				q.waitTime = d
+			}
+		case "ContentBasedDeduplication":
+			if !q.fifo {
+				return errInvalidAttributeName(name)
+			}
+			q.contentDedup = value == "true"
+		case "FifoQueue":
+			if (value == "true") != q.fifo {
+				return errInvalidAttributeValue(name, value)
+			}
+		case "MaximumMessageSize", "Policy", "RedrivePolicy", "RedriveAllowPolicy", "KmsMasterKeyId",
+			"KmsDataKeyReusePeriodSeconds", "SqsManagedSseEnabled", "DeduplicationScope", "FifoThroughputLimit":
+			// Aceitos por compatibilidade, sem efeito no fake.
+		default:
+			return errInvalidAttributeName(name)
+		}
+	}
+	q.updatedAt = now
+	return nil
+}
+
+// systemAttributes monta os atributos de sistema da mensagem recebida.
+func (m *message) systemAttributes(names []string) map[string]string {
+	all := map[string]string{
+		"SenderId":                         "AIDASQSFAKE",
+		"SentTimestamp":                    strconv.FormatInt(m.sentAt.UnixMilli(), 10),
+		"ApproximateReceiveCount":          strconv.Itoa(m.receiveCount),
+		"ApproximateFirstReceiveTimestamp": strconv.FormatInt(m.firstReceive.UnixMilli(), 10),
+	}
+	if m.groupID != "" {
+		all["MessageGroupId"] = m.groupID
+		all["MessageDeduplicationId"] = m.dedupID
+		all["SequenceNumber"] = m.sequence
+	}
+	return pick(all, names)
+}
+
+// messageAttributes retorna os atributos da mensagem pedidos em names: "All" ou
+// ".*" trazem todos, "prefixo.*" os que começam com prefixo.
+func (m *message) messageAttributes(names []string) map[string]messageAttribute {
+	out := make(map[string]messageAttribute)
+	for _, name := range names {
+		prefix, wildcard := strings.CutSuffix(name, ".*")
+		if name == "All" || name == ".*" {
+			prefix, wildcard = "", true
+		}
+		for attr, v := range m.attributes {
+			if attr == name || wildcard && strings.HasPrefix(attr, prefix) {
+				out[attr] = v
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func pick(all map[string]string, names []string) map[string]string {
+	out := make(map[string]string)
+	for _, name := range names {
+		if name == "All" {
+			return all
+		}
+		if v, ok := all[name]; ok {
+			out[name] = v
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(d.Seconds()))
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
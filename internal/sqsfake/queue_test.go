@@ -0,0 +1,122 @@
+package sqsfake
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueueVisibilityTimeout(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	q := newQueue("jobs", now)
+	if _, err := q.send("a", nil, "", "", now); err != nil {
+		t.Fatal(err)
+	}
+
+	first := q.receive(10, 30*time.Second, now)
+	if len(first) != 1 {
+		t.Fatalf("recebidas %d mensagens, esperado 1", len(first))
+	}
+	oldHandle := first[0].receiptHandle
+	if got := q.receive(10, 30*time.Second, now.Add(10*time.Second)); len(got) != 0 {
+		t.Errorf("mensagem em voo entregue de novo antes do timeout")
+	}
+
+	second := q.receive(10, 30*time.Second, now.Add(31*time.Second))
+	if len(second) != 1 || second[0].receiveCount != 2 {
+		t.Fatalf("mensagem não voltou após o timeout de visibilidade: %v", second)
+	}
+	// Recibo de um recebimento anterior não remove a mensagem, como na SQS.
+	if err := q.delete(oldHandle); err != nil {
+		t.Errorf("delete com recibo antigo: %v", err)
+	}
+	if len(q.messages) != 1 {
+		t.Errorf("recibo antigo removeu a mensagem")
+	}
+	if err := q.delete(second[0].receiptHandle); err != nil || len(q.messages) != 0 {
+		t.Errorf("delete com recibo atual: erro %v, %d mensagens restantes", err, len(q.messages))
+	}
+	if err := q.delete("sem-separador"); err == nil {
+		t.Errorf("recibo malformado aceito")
+	}
+}
+
+func TestQueueChangeVisibility(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	q := newQueue("jobs", now)
+	if _, err := q.send("a", nil, "", "", now); err != nil {
+		t.Fatal(err)
+	}
+	msgs := q.receive(1, time.Minute, now)
+	if len(msgs) != 1 {
+		t.Fatalf("recebidas %d mensagens, esperado 1", len(msgs))
+	}
+	handle := msgs[0].receiptHandle
+	if err := q.changeVisibility(handle, 0, now); err != nil {
+		t.Fatalf("changeVisibility: %v", err)
+	}
+	if got := q.receive(1, time.Minute, now); len(got) != 1 {
+		t.Errorf("mensagem liberada com timeout 0 não foi entregue de novo")
+	}
+
+	var apiErr *apiError
+	if err := q.changeVisibility(handle, 10, now); !errors.As(err, &apiErr) || apiErr.code != "MessageNotInflight" {
+		t.Errorf("changeVisibility com recibo antigo: %v, esperado MessageNotInflight", err)
+	}
+}
+
+func TestQueueDelay(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	q := newQueue("jobs", now)
+	delay := int32(5)
+	if _, err := q.send("a", &delay, "", "", now); err != nil {
+		t.Fatal(err)
+	}
+	if got := q.receive(1, time.Minute, now.Add(4*time.Second)); len(got) != 0 {
+		t.Errorf("mensagem entregue antes do DelaySeconds")
+	}
+	if got := q.receive(1, time.Minute, now.Add(5*time.Second)); len(got) != 1 {
+		t.Errorf("mensagem não entregue após o DelaySeconds")
+	}
+}
+
+func TestQueueFIFO(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	q := newQueue("jobs.fifo", now)
+	if _, err := q.send("x", nil, "g1", "", now); err == nil {
+		t.Errorf("envio sem MessageDeduplicationId aceito sem ContentBasedDeduplication")
+	}
+	for _, m := range []struct{ body, group, dedup string }{
+		{"a", "g1", "a"}, {"b", "g1", "b"}, {"c", "g2", "c"}, {"a", "g1", "a"},
+	} {
+		if _, err := q.send(m.body, nil, m.group, m.dedup, now); err != nil {
+			t.Fatalf("send(%s): %v", m.body, err)
+		}
+	}
+	if len(q.messages) != 3 {
+		t.Errorf("%d mensagens na fila, esperado 3 (duplicada descartada)", len(q.messages))
+	}
+
+	// O grupo g1 fica bloqueado enquanto a sua primeira mensagem está em voo.
+	got := q.receive(1, time.Minute, now)
+	if len(got) != 1 || got[0].body != "a" {
+		t.Fatalf("recebidas %v, esperado a", bodies(got))
+	}
+	if more := q.receive(10, time.Minute, now); len(more) != 1 || more[0].body != "c" {
+		t.Errorf("recebidas %v com o grupo g1 em voo, esperado c", bodies(more))
+	}
+	if err := q.delete(got[0].receiptHandle); err != nil {
+		t.Fatal(err)
+	}
+	if next := q.receive(10, time.Minute, now); len(next) != 1 || next[0].body != "b" {
+		t.Errorf("recebidas %v, esperado b", bodies(next))
+	}
+}
+
+func bodies(msgs []*message) []string {
+	out := make([]string, len(msgs))
+	for i, m := range msgs {
+		out[i] = m.body
+	}
+	return out
+}
@@ -0,0 +1,449 @@
+// Package sqsfake implementa, em memória, um endpoint HTTP compatível com a SQS
+// para rodar o pipeline e os testes de integração sem AWS. Atende os protocolos
+// JSON (SDKs recentes) e query (SDKs antigos e AWS CLI v1).
+package sqsfake
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"yourproject/internal/logger"
+)
+
+const (
+	defaultRegion       = "us-east-1"
+	defaultAccountID    = "000000000000"
+	receivePollInterval = 100 * time.Millisecond
+	maxRequestBody      = 1024 * 1024
+)
+
+// Server é o endpoint SQS em memória. As filas são identificadas pelo último
+// segmento da URL, então URLs reais (https://sqs.<região>.amazonaws.com/<conta>/<fila>)
+// também são aceitas quando o cliente aponta para o fake.
+type Server struct {
+	Region    string // Região usada nos ARNs (padrão: us-east-1).
+	AccountID string // Conta usada nas URLs e ARNs (padrão: 000000000000).
+	// BaseURL prefixa as URLs das filas; se vazio, usa o Host da requisição.
+	BaseURL string
+	// AutoCreate cria sob demanda as filas referenciadas que ainda não existem.
+	AutoCreate bool
+
+	mu     sync.Mutex
+	queues map[string]*queue
+}
+
+// Start escuta em addr e atende em background. Retorna a URL do endpoint, a
+// ser configurada como endpoint customizado do cliente SQS.
+func (s *Server) Start(addr string) (string, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("erro ao escutar em %s: %v", addr, err)
+	}
+	s.mu.Lock()
+	if s.BaseURL == "" {
+		s.BaseURL = "http://" + ln.Addr().String()
+	}
+	endpoint := s.BaseURL
+	s.mu.Unlock()
+
+	srv := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		logger.Log.Infof("SQS fake: escutando em %s", endpoint)
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Log.Errorf("SQS fake: servidor HTTP encerrado com erro: %v", err)
+		}
+	}()
+	return endpoint, nil
+}
+
+// CreateQueue cria a fila (ou retorna a existente) e devolve a sua URL.
+func (s *Server) CreateQueue(name string, attributes map[string]string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createQueue("", name, attributes)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		s.serveJSON(w, r, strings.TrimPrefix(target, "AmazonSQS."))
+		return
+	}
+	s.serveQuery(w, r)
+}
+
+func (s *Server) region() string {
+	if s.Region == "" {
+		return defaultRegion
+	}
+	return s.Region
+}
+
+func (s *Server) account() string {
+	if s.AccountID == "" {
+		return defaultAccountID
+	}
+	return s.AccountID
+}
+
+func (s *Server) queueURL(host, name string) string {
+	base := s.BaseURL
+	if base == "" {
+		base = "http://" + host
+	}
+	return strings.TrimSuffix(base, "/") + "/" + s.account() + "/" + name
+}
+
+func (s *Server) queueARN(name string) string {
+	return fmt.Sprintf("arn:aws:sqs:%s:%s:%s", s.region(), s.account(), name)
+}
+
+// queueName extrai o nome da fila do último segmento da URL.
+func queueName(queueURL string) string {
+	queueURL = strings.TrimSuffix(queueURL, "/")
+	if i := strings.LastIndex(queueURL, "/"); i >= 0 {
+		return queueURL[i+1:]
+	}
+	return queueURL
+}
+
+func (s *Server) createQueue(host, name string, attributes map[string]string) (string, error) {
+	if name == "" {
+		return "", errMissingParameter("QueueName")
+	}
+	if s.queues == nil {
+		s.queues = make(map[string]*queue)
+	}
+	q, ok := s.queues[name]
+	if !ok {
+		if attributes["FifoQueue"] == "true" && !strings.HasSuffix(name, ".fifo") {
+			return "", errInvalidParameter("o nome de filas FIFO deve terminar em .fifo")
+		}
+		q = newQueue(name, time.Now())
+		if err := q.setAttributes(attributes, time.Now()); err != nil {
+			return "", err
+		}
+		s.queues[name] = q
+	}
+	return s.queueURL(host, name), nil
+}
+
+// lookup retorna a fila da URL; deve ser chamado com s.mu bloqueado.
+func (s *Server) lookup(queueURL string) (*queue, error) {
+	if queueURL == "" {
+		return nil, errMissingParameter("QueueUrl")
+	}
+	name := queueName(queueURL)
+	if q, ok := s.queues[name]; ok {
+		return q, nil
+	}
+	if !s.AutoCreate {
+		return nil, errQueueDoesNotExist()
+	}
+	if _, err := s.createQueue("", name, nil); err != nil {
+		return nil, err
+	}
+	logger.Log.Infof("SQS fake: fila %s criada sob demanda", name)
+	return s.queues[name], nil
+}
+
+func (s *Server) getQueueURL(host, name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.lookup(name); err != nil {
+		return "", err
+	}
+	return s.queueURL(host, name), nil
+}
+
+func (s *Server) listQueues(host, prefix string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var urls []string
+	for name := range s.queues {
+		if strings.HasPrefix(name, prefix) {
+			urls = append(urls, s.queueURL(host, name))
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func (s *Server) deleteQueue(queueURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.lookup(queueURL)
+	if err != nil {
+		return err
+	}
+	q.purge()
+	delete(s.queues, q.name)
+	return nil
+}
+
+func (s *Server) purgeQueue(queueURL string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.lookup(queueURL)
+	if err != nil {
+		return err
+	}
+	q.purge()
+	return nil
+}
+
+type sendMessageInput struct {
+	QueueUrl               string
+	MessageBody            string
+	DelaySeconds           *int32
+	MessageGroupId         string
+	MessageDeduplicationId string
+	MessageAttributes      map[string]messageAttribute
+}
+
+type sendMessageOutput struct {
+	MessageId        string
+	MD5OfMessageBody string
+	SequenceNumber   string `json:",omitempty"`
+}
+
+func (s *Server) sendMessage(in *sendMessageInput) (*sendMessageOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.lookup(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	for name, attr := range in.MessageAttributes {
+		if err := validateMessageAttribute(name, attr); err != nil {
+			return nil, err
+		}
+	}
+	m, err := q.send(in.MessageBody, in.DelaySeconds, in.MessageGroupId, in.MessageDeduplicationId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(in.MessageAttributes) > 0 {
+		m.attributes = in.MessageAttributes
+	}
+	return &sendMessageOutput{MessageId: m.id, MD5OfMessageBody: m.md5, SequenceNumber: m.sequence}, nil
+}
+
+// validateMessageAttribute confere que o atributo tem um tipo suportado pela
+// SQS (String, Number ou Binary, com sufixo opcional) e o valor correspondente.
+func validateMessageAttribute(name string, attr messageAttribute) error {
+	base, _, _ := strings.Cut(attr.DataType, ".")
+	switch {
+	case name == "":
+		return errInvalidParameter("nome de atributo de mensagem vazio")
+	case base == "String" || base == "Number":
+		if attr.StringValue == "" {
+			return errInvalidParameter(fmt.Sprintf("atributo de mensagem %s sem StringValue", name))
+		}
+	case base == "Binary":
+		if len(attr.BinaryValue) == 0 {
+			return errInvalidParameter(fmt.Sprintf("atributo de mensagem %s sem BinaryValue", name))
+		}
+	default:
+		return errInvalidParameter(fmt.Sprintf("tipo %q inválido no atributo de mensagem %s", attr.DataType, name))
+	}
+	return nil
+}
+
+type receiveMessageInput struct {
+	QueueUrl                    string
+	MaxNumberOfMessages         int32
+	VisibilityTimeout           *int32
+	WaitTimeSeconds             *int32
+	AttributeNames              []string
+	MessageSystemAttributeNames []string
+	MessageAttributeNames       []string
+}
+
+type receivedMessage struct {
+	MessageId         string
+	ReceiptHandle     string
+	MD5OfBody         string
+	Body              string
+	Attributes        map[string]string           `json:",omitempty"`
+	MessageAttributes map[string]messageAttribute `json:",omitempty"`
+}
+
+// receiveMessage faz long polling até WaitTimeSeconds (ou o padrão da fila)
+// enquanto não houver mensagens visíveis.
+func (s *Server) receiveMessage(ctx context.Context, in *receiveMessageInput) ([]receivedMessage, error) {
+	limit := int(in.MaxNumberOfMessages)
+	if limit == 0 {
+		limit = 1
+	}
+	if limit < 1 || limit > maxReceiveMessages {
+		return nil, errInvalidParameter(fmt.Sprintf("MaxNumberOfMessages deve estar entre 1 e %d", maxReceiveMessages))
+	}
+	if in.VisibilityTimeout != nil && (*in.VisibilityTimeout < 0 || time.Duration(*in.VisibilityTimeout)*time.Second > maxVisibilityTimeout) {
+		return nil, errInvalidParameter(fmt.Sprintf("VisibilityTimeout inválido: %d", *in.VisibilityTimeout))
+	}
+	if in.WaitTimeSeconds != nil && (*in.WaitTimeSeconds < 0 || time.Duration(*in.WaitTimeSeconds)*time.Second > maxWaitTime) {
+		return nil, errInvalidParameter(fmt.Sprintf("WaitTimeSeconds inválido: %d", *in.WaitTimeSeconds))
+	}
+	names := append(append([]string{}, in.AttributeNames...), in.MessageSystemAttributeNames...)
+
+	var deadline time.Time
+	for {
+		s.mu.Lock()
+		q, err := s.lookup(in.QueueUrl)
+		if err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+		now := time.Now()
+		if deadline.IsZero() {
+			wait := q.waitTime
+			if in.WaitTimeSeconds != nil {
+				wait = time.Duration(*in.WaitTimeSeconds) * time.Second
+			}
+			deadline = now.Add(wait)
+		}
+		visibility := q.visibilityTimeout
+		if in.VisibilityTimeout != nil {
+			visibility = time.Duration(*in.VisibilityTimeout) * time.Second
+		}
+		var out []receivedMessage
+		for _, m := range q.receive(limit, visibility, now) {
+			out = append(out, receivedMessage{
+				MessageId:         m.id,
+				ReceiptHandle:     m.receiptHandle,
+				MD5OfBody:         m.md5,
+				Body:              m.body,
+				Attributes:        m.systemAttributes(names),
+				MessageAttributes: m.messageAttributes(in.MessageAttributeNames),
+			})
+		}
+		notify := q.notify
+		s.mu.Unlock()
+
+		if len(out) > 0 || !now.Before(deadline) {
+			return out, nil
+		}
+		// Mensagens que voltam a ficar visíveis por timeout não disparam notify.
+		timer := time.NewTimer(min(time.Until(deadline), receivePollInterval))
+		select {
+		case <-notify:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil
+		}
+		timer.Stop()
+	}
+}
+
+func (s *Server) deleteMessage(queueURL, receiptHandle string) error {
+	if receiptHandle == "" {
+		return errMissingParameter("ReceiptHandle")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.lookup(queueURL)
+	if err != nil {
+		return err
+	}
+	return q.delete(receiptHandle)
+}
+
+func (s *Server) changeMessageVisibility(queueURL, receiptHandle string, timeout int32) error {
+	if receiptHandle == "" {
+		return errMissingParameter("ReceiptHandle")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.lookup(queueURL)
+	if err != nil {
+		return err
+	}
+	return q.changeVisibility(receiptHandle, timeout, time.Now())
+}
+
+func (s *Server) getQueueAttributes(queueURL string, names []string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.lookup(queueURL)
+	if err != nil {
+		return nil, err
+	}
+	return q.attributes(s, names, time.Now()), nil
+}
+
+func (s *Server) setQueueAttributes(queueURL string, attributes map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, err := s.lookup(queueURL)
+	if err != nil {
+		return err
+	}
+	if err := q.setAttributes(attributes, time.Now()); err != nil {
+		return err
+	}
+	q.changed()
+	return nil
+}
+
+// apiError é um erro no formato da SQS, com os códigos dos dois protocolos.
+type apiError struct {
+	code      string // Código do protocolo JSON (ex.: QueueDoesNotExist).
+	queryCode string // Código do protocolo query (ex.: AWS.SimpleQueueService.NonExistentQueue).
+	message   string
+}
+
+func (e *apiError) Error() string {
+	return e.code + ": " + e.message
+}
+
+func asAPIError(err error) *apiError {
+	if e, ok := err.(*apiError); ok {
+		return e
+	}
+	return &apiError{code: "InternalError", queryCode: "InternalError", message: err.Error()}
+}
+
+func errQueueDoesNotExist() error {
+	return &apiError{"QueueDoesNotExist", "AWS.SimpleQueueService.NonExistentQueue", "a fila informada não existe"}
+}
+
+func errMissingParameter(name string) error {
+	return &apiError{"MissingParameter", "MissingParameter", "parâmetro obrigatório ausente: " + name}
+}
+
+func errInvalidParameter(msg string) error {
+	return &apiError{"InvalidParameterValue", "InvalidParameterValue", msg}
+}
+
+func errInvalidReceiptHandle(handle string) error {
+	return &apiError{"ReceiptHandleIsInvalid", "ReceiptHandleIsInvalid", "recibo inválido: " + handle}
+}
+
+func errMessageNotInflight() error {
+	return &apiError{"MessageNotInflight", "AWS.SimpleQueueService.MessageNotInflight", "a mensagem não está em voo"}
+}
+
+func errInvalidAttributeName(name string) error {
+	return &apiError{"InvalidAttributeName", "InvalidAttributeName", "atributo desconhecido: " + name}
+}
+
+func errInvalidAttributeValue(name, value string) error {
+	return &apiError{"InvalidAttributeValue", "InvalidAttributeValue", fmt.Sprintf("valor inválido para %s: %q", name, value)}
+}
+
+func errUnsupportedOperation(action string) error {
+	return &apiError{"UnsupportedOperation", "AWS.SimpleQueueService.UnsupportedOperation", "operação não suportada pelo fake: " + action}
+}
+
+func requestID() string {
+	return uuid.New().String()
+}
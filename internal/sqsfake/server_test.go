@@ -0,0 +1,214 @@
+package sqsfake
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// newTestServer sobe o fake em um servidor HTTP de teste e retorna um cliente
+// do SDK apontado para ele.
+func newTestServer(t *testing.T, fake *Server) (*sqs.Client, string) {
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	client := sqs.New(sqs.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "sqsfake", SecretAccessKey: "sqsfake"}, nil
+		}),
+	})
+	return client, srv.URL
+}
+
+func TestServerSDK(t *testing.T) {
+	client, _ := newTestServer(t, &Server{})
+	ctx := context.Background()
+
+	created, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String("jobs")})
+	if err != nil {
+		t.Fatalf("CreateQueue: %v", err)
+	}
+	queueURL := created.QueueUrl
+	if _, err := client.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: queueURL, MessageBody: aws.String(`{"scan_id":"1"}`)}); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	recv, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            queueURL,
+		MaxNumberOfMessages: 10,
+		AttributeNames:      []types.QueueAttributeName{types.QueueAttributeNameAll},
+	})
+	if err != nil || len(recv.Messages) != 1 {
+		t.Fatalf("ReceiveMessage: %v, %d mensagens", err, len(recv.Messages))
+	}
+	msg := recv.Messages[0]
+	if aws.ToString(msg.Body) != `{"scan_id":"1"}` || msg.Attributes["ApproximateReceiveCount"] != "1" {
+		t.Errorf("mensagem recebida %q com atributos %v", aws.ToString(msg.Body), msg.Attributes)
+	}
+
+	attrs, err := client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       queueURL,
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameApproximateNumberOfMessagesNotVisible},
+	})
+	if err != nil || attrs.Attributes["ApproximateNumberOfMessagesNotVisible"] != "1" {
+		t.Errorf("GetQueueAttributes: %v, %v", err, attrs)
+	}
+
+	if _, err := client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl: queueURL, ReceiptHandle: msg.ReceiptHandle, VisibilityTimeout: 0,
+	}); err != nil {
+		t.Fatalf("ChangeMessageVisibility: %v", err)
+	}
+	again, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: queueURL})
+	if err != nil || len(again.Messages) != 1 {
+		t.Fatalf("mensagem liberada não foi entregue de novo: %v", err)
+	}
+	if _, err := client.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: queueURL, ReceiptHandle: again.Messages[0].ReceiptHandle}); err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+	empty, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: queueURL})
+	if err != nil || len(empty.Messages) != 0 {
+		t.Errorf("fila não ficou vazia após DeleteMessage: %v", err)
+	}
+}
+
+func TestServerMessageAttributes(t *testing.T) {
+	client, _ := newTestServer(t, &Server{})
+	ctx := context.Background()
+	// Visibilidade 0: a mesma mensagem é recebida em cada caso.
+	created, err := client.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName:  aws.String("jobs"),
+		Attributes: map[string]string{"VisibilityTimeout": "0"},
+	})
+	if err != nil {
+		t.Fatalf("CreateQueue: %v", err)
+	}
+	queueURL := created.QueueUrl
+
+	if _, err := client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    queueURL,
+		MessageBody: aws.String("x"),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"Sem.Tipo": {StringValue: aws.String("1")},
+		},
+	}); err == nil {
+		t.Error("atributo sem DataType aceito")
+	}
+	if _, err := client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    queueURL,
+		MessageBody: aws.String("x"),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"Scan.Tentativas": {DataType: aws.String("Number"), StringValue: aws.String("2")},
+			"Scan.Origem":     {DataType: aws.String("String"), StringValue: aws.String("api")},
+			"Trace":           {DataType: aws.String("Binary"), BinaryValue: []byte{1, 2}},
+		},
+	}); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	tests := []struct {
+		names []string
+		want  []string
+	}{
+		{names: nil, want: nil},
+		{names: []string{"All"}, want: []string{"Scan.Origem", "Scan.Tentativas", "Trace"}},
+		{names: []string{"Scan.*"}, want: []string{"Scan.Origem", "Scan.Tentativas"}},
+		{names: []string{"Scan.Tentativas", "Outro"}, want: []string{"Scan.Tentativas"}},
+	}
+	for _, tt := range tests {
+		recv, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              queueURL,
+			MessageAttributeNames: tt.names,
+		})
+		if err != nil || len(recv.Messages) != 1 {
+			t.Fatalf("ReceiveMessage(%v): %v", tt.names, err)
+		}
+		attrs := recv.Messages[0].MessageAttributes
+		got := make([]string, 0, len(attrs))
+		for name := range attrs {
+			got = append(got, name)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ReceiveMessage(%v): atributos %v, esperado %v", tt.names, got, tt.want)
+		}
+		if v, ok := attrs["Scan.Tentativas"]; ok && (aws.ToString(v.DataType) != "Number" || aws.ToString(v.StringValue) != "2") {
+			t.Errorf("Scan.Tentativas = %s %s", aws.ToString(v.DataType), aws.ToString(v.StringValue))
+		}
+		if v, ok := attrs["Trace"]; ok && string(v.BinaryValue) != "\x01\x02" {
+			t.Errorf("Trace = %v", v.BinaryValue)
+		}
+	}
+}
+
+func TestServerQueueDoesNotExist(t *testing.T) {
+	client, base := newTestServer(t, &Server{})
+	_, err := client.SendMessage(context.Background(), &sqs.SendMessageInput{
+		QueueUrl: aws.String(base + "/000000000000/missing"), MessageBody: aws.String("x"),
+	})
+	// O SDK pode expor o código do protocolo JSON ou o do query.
+	if err == nil || (!strings.Contains(err.Error(), "QueueDoesNotExist") && !strings.Contains(err.Error(), "NonExistentQueue")) {
+		t.Errorf("SendMessage em fila inexistente: %v", err)
+	}
+
+	client, base = newTestServer(t, &Server{AutoCreate: true})
+	if _, err := client.SendMessage(context.Background(), &sqs.SendMessageInput{
+		QueueUrl: aws.String(base + "/000000000000/created"), MessageBody: aws.String("x"),
+	}); err != nil {
+		t.Errorf("SendMessage com AutoCreate: %v", err)
+	}
+}
+
+func TestServerQueryProtocol(t *testing.T) {
+	fake := &Server{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	queueURL, err := fake.CreateQueue("jobs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(form url.Values) (int, string) {
+		resp, err := http.PostForm(srv.URL, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := post(url.Values{"Action": {"SendMessage"}, "QueueUrl": {queueURL}, "MessageBody": {"olá"}})
+	if status != http.StatusOK || !strings.Contains(body, "<MessageId>") {
+		t.Fatalf("SendMessage: %d %s", status, body)
+	}
+	status, body = post(url.Values{"Action": {"ReceiveMessage"}, "QueueUrl": {queueURL}, "AttributeName.1": {"All"}})
+	if status != http.StatusOK || !strings.Contains(body, "<Body>olá</Body>") || !strings.Contains(body, "ApproximateReceiveCount") {
+		t.Errorf("ReceiveMessage: %d %s", status, body)
+	}
+	status, body = post(url.Values{
+		"Action": {"SendMessage"}, "QueueUrl": {queueURL}, "MessageBody": {"com atributo"},
+		"MessageAttribute.1.Name": {"Origem"}, "MessageAttribute.1.Value.DataType": {"String"}, "MessageAttribute.1.Value.StringValue": {"cli"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("SendMessage com atributo: %d %s", status, body)
+	}
+	status, body = post(url.Values{"Action": {"ReceiveMessage"}, "QueueUrl": {queueURL}, "MessageAttributeName.1": {"All"}})
+	if status != http.StatusOK || !strings.Contains(body, "<MessageAttribute><Name>Origem</Name><Value><DataType>String</DataType><StringValue>cli</StringValue></Value></MessageAttribute>") {
+		t.Errorf("ReceiveMessage com atributo: %d %s", status, body)
+	}
+	status, body = post(url.Values{"Action": {"TagQueue"}, "QueueUrl": {queueURL}})
+	if status == http.StatusOK || !strings.Contains(body, "UnsupportedOperation") {
+		t.Errorf("ação não suportada: %d %s", status, body)
+	}
+}
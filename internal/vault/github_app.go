@@ -0,0 +1,297 @@
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"yourproject/internal/logger"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultGitHubAPIURL = "https://api.github.com"
+	// jwtTTL é a validade do JWT do app; o GitHub aceita no máximo 10 minutos.
+	jwtTTL = 9 * time.Minute
+	// tokenRefreshMargin renova o token de instalação antes de ele expirar, para
+	// que um clone longo não comece com um token prestes a vencer.
+	tokenRefreshMargin = 5 * time.Minute
+	// apiTimeout limita a busca da instalação e a geração do token.
+	apiTimeout = 30 * time.Second
+)
+
+// defaultHTTPClient é usado quando HTTPClient não é definido.
+var defaultHTTPClient = &http.Client{Timeout: apiTimeout}
+
+// errNoInstallation indica que o app não está instalado no owner.
+var errNoInstallation = errors.New("GitHub App não instalado")
+
+// apiError é uma resposta da API do GitHub com status inesperado.
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+// isNotFound indica se err é uma resposta 404 da API.
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound
+}
+
+// GitHubAppClient autentica no GitHub como GitHub App: assina um JWT com a
+// chave privada do app e o troca por um token da instalação do app no owner do
+// repositório. Os tokens ficam em cache até pouco antes de expirar. Os demais
+// provedores e as chaves SSH são atendidos por Fallback.
+type GitHubAppClient struct {
+	AppID string
+	// BaseURL é a URL da API; vazio usa https://api.github.com. Permite apontar
+	// para o GitHub Enterprise ou para um servidor local que simule a API.
+	BaseURL string
+	// DefaultOwner é usado quando o owner não é informado (GetGitHubCredentials).
+	DefaultOwner string
+	Fallback     VaultClient
+	HTTPClient   *http.Client // Vazio usa um cliente com timeout de 30s.
+
+	key *rsa.PrivateKey
+
+	// flights garante uma única geração de token por owner ao mesmo tempo; mu
+	// protege só os caches, sem ficar preso durante as chamadas à API.
+	flights       singleflight.Group
+	mu            sync.Mutex
+	installations map[string]int64 // Owner (minúsculo) → ID da instalação.
+	tokens        map[int64]installationToken
+}
+
+type installationToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// NewGitHubAppClient cria o cliente com a chave privada do app em PEM (PKCS#1,
+// como baixada do GitHub, ou PKCS#8).
+func NewGitHubAppClient(appID string, privateKeyPEM []byte, fallback VaultClient) (*GitHubAppClient, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("chave privada do GitHub App não está em PEM")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if err8 != nil || !ok {
+			return nil, fmt.Errorf("chave privada do GitHub App inválida: %v", err)
+		}
+		key = rsaKey
+	}
+	return &GitHubAppClient{
+		AppID:         appID,
+		Fallback:      fallback,
+		key:           key,
+		installations: make(map[string]int64),
+		tokens:        make(map[int64]installationToken),
+	}, nil
+}
+
+func (c *GitHubAppClient) GetGitHubCredentials() (*GitHubCredentials, error) {
+	return c.GetCredentials("github", "")
+}
+
+// GetCredentials retorna o token da instalação do app no owner para o provedor
+// "github"; os demais provedores vão para Fallback.
+func (c *GitHubAppClient) GetCredentials(provider, owner string) (*Credentials, error) {
+	if provider != "github" {
+		return c.Fallback.GetCredentials(provider, owner)
+	}
+	if owner == "" {
+		owner = c.DefaultOwner
+	}
+	if owner == "" {
+		return nil, fmt.Errorf("owner do repositório não informado para o token do GitHub App")
+	}
+	token, err := c.installationToken(owner)
+	if err != nil {
+		return nil, err
+	}
+	return &Credentials{Username: "x-access-token", Token: token}, nil
+}
+
+func (c *GitHubAppClient) GetSSHKey(host, org string) (*SSHKey, error) {
+	return c.Fallback.GetSSHKey(host, org)
+}
+
+// installationToken retorna o token em cache da instalação do owner ou gera um
+// novo. Chamadas simultâneas para o mesmo owner aguardam uma única geração.
+func (c *GitHubAppClient) installationToken(owner string) (string, error) {
+	owner = strings.ToLower(owner)
+	if token, ok := c.cachedToken(owner); ok {
+		return token, nil
+	}
+	token, err, _ := c.flights.Do(owner, func() (any, error) {
+		// Outra chamada pode ter gerado o token enquanto esta aguardava.
+		if token, ok := c.cachedToken(owner); ok {
+			return token, nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+		defer cancel()
+		return c.newToken(ctx, owner)
+	})
+	if err != nil {
+		return "", err
+	}
+	return token.(string), nil
+}
+
+// cachedToken retorna o token em cache do owner, se ainda não precisa ser renovado.
+func (c *GitHubAppClient) cachedToken(owner string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, ok := c.installations[owner]
+	if !ok {
+		return "", false
+	}
+	t, ok := c.tokens[id]
+	if !ok || time.Until(t.expiresAt) <= tokenRefreshMargin {
+		return "", false
+	}
+	return t.token, true
+}
+
+// newToken gera um token da instalação do owner, buscando a instalação se ela
+// ainda não está em cache.
+func (c *GitHubAppClient) newToken(ctx context.Context, owner string) (string, error) {
+	start := time.Now()
+	defer logger.Trace("GitHubAppToken", start)
+
+	jwt, err := c.signJWT()
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	id, ok := c.installations[owner]
+	c.mu.Unlock()
+	if !ok {
+		if id, err = c.findInstallation(ctx, jwt, owner); err != nil {
+			return "", err
+		}
+		c.mu.Lock()
+		c.installations[owner] = id
+		c.mu.Unlock()
+	}
+
+	var resp struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", id)
+	if err := c.call(ctx, http.MethodPost, path, jwt, http.StatusCreated, &resp); err != nil {
+		if isNotFound(err) {
+			// A instalação foi removida ou refeita; procura de novo na próxima chamada.
+			c.mu.Lock()
+			delete(c.installations, owner)
+			c.mu.Unlock()
+		}
+		return "", fmt.Errorf("erro ao gerar token da instalação %d (%s) do GitHub App: %v", id, owner, err)
+	}
+	c.mu.Lock()
+	c.tokens[id] = installationToken{token: resp.Token, expiresAt: resp.ExpiresAt}
+	c.mu.Unlock()
+	logger.Log.Debugf("GitHubApp: token da instalação %d (%s) válido até %s", id, owner, resp.ExpiresAt.Format(time.RFC3339))
+	return resp.Token, nil
+}
+
+// findInstallation procura a instalação do app na organização e, se não achar,
+// na conta de usuário owner.
+func (c *GitHubAppClient) findInstallation(ctx context.Context, jwt, owner string) (int64, error) {
+	var resp struct {
+		ID int64 `json:"id"`
+	}
+	err := c.call(ctx, http.MethodGet, "/orgs/"+url.PathEscape(owner)+"/installation", jwt, http.StatusOK, &resp)
+	if isNotFound(err) {
+		err = c.call(ctx, http.MethodGet, "/users/"+url.PathEscape(owner)+"/installation", jwt, http.StatusOK, &resp)
+	}
+	if isNotFound(err) {
+		return 0, fmt.Errorf("%w em %s", errNoInstallation, owner)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("erro ao buscar instalação do GitHub App em %s: %v", owner, err)
+	}
+	return resp.ID, nil
+}
+
+// call faz a requisição autenticada com o JWT do app e decodifica a resposta em out.
+func (c *GitHubAppClient) call(ctx context.Context, method, path, jwt string, wantStatus int, out any) error {
+	baseURL := strings.TrimSuffix(c.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultGitHubAPIURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultHTTPClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != wantStatus {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &apiError{
+			status: resp.StatusCode,
+			msg:    fmt.Sprintf("%s %s retornou %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(body))),
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("erro ao decodificar resposta de %s: %v", path, err)
+	}
+	return nil
+}
+
+// signJWT gera o JWT RS256 do app. O iat é recuado 60 segundos para tolerar
+// diferença de relógio com o GitHub.
+func (c *GitHubAppClient) signJWT() (string, error) {
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(jwtTTL).Unix(),
+		"iss": c.AppID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("erro ao assinar JWT do GitHub App: %v", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,253 @@
+package vault
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitHub simula a API de instalações do GitHub App.
+type fakeGitHub struct {
+	t   *testing.T
+	key *rsa.PublicKey
+
+	mu         sync.Mutex
+	orgs       map[string]int64 // Owner → ID da instalação na organização.
+	users      map[string]int64 // Owner → ID da instalação na conta de usuário.
+	removed    map[int64]bool   // Instalações removidas: o POST do token retorna 404.
+	tokenTTL   time.Duration
+	lookups    int
+	tokens     int
+	tokenDelay time.Duration
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f.checkJWT(r.Header.Get("Authorization")); err != nil {
+		f.t.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var owner string
+	var id int64
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/orgs/"):
+		f.lookups++
+		owner = strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/orgs/"), "/installation")
+		if id = f.orgs[owner]; id == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"id":%d}`, id)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/users/"):
+		f.lookups++
+		owner = strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/users/"), "/installation")
+		if id = f.users[owner]; id == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"id":%d}`, id)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/access_tokens"):
+		id, _ = strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/app/installations/"), "/access_tokens"), 10, 64)
+		time.Sleep(f.tokenDelay)
+		if f.removed[id] {
+			http.NotFound(w, r)
+			return
+		}
+		f.tokens++
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"token":      fmt.Sprintf("token-%d-%d", id, f.tokens),
+			"expires_at": time.Now().Add(f.tokenTTL),
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// counts retorna quantas buscas de instalação e quantos tokens o servidor atendeu.
+func (f *fakeGitHub) counts() (lookups, tokens int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lookups, f.tokens
+}
+
+// checkJWT confere a assinatura RS256 e as claims do JWT do app.
+func (f *fakeGitHub) checkJWT(header string) error {
+	jwt, ok := strings.CutPrefix(header, "Bearer ")
+	parts := strings.Split(jwt, ".")
+	if !ok || len(parts) != 3 {
+		return fmt.Errorf("Authorization sem JWT: %q", header)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(f.key, crypto.SHA256, digest[:], sig); err != nil {
+		return fmt.Errorf("assinatura do JWT inválida: %v", err)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	if claims.Iss != "123" || claims.Iat > now || claims.Exp <= now || claims.Exp-claims.Iat > 600 {
+		return fmt.Errorf("claims do JWT inválidas: %+v", claims)
+	}
+	return nil
+}
+
+type fallbackVault struct{}
+
+func (fallbackVault) GetGitHubCredentials() (*GitHubCredentials, error) { return nil, nil }
+
+func (fallbackVault) GetCredentials(provider, owner string) (*Credentials, error) {
+	return &Credentials{Username: provider, Token: "fallback"}, nil
+}
+
+func (fallbackVault) GetSSHKey(host, org string) (*SSHKey, error) { return nil, nil }
+
+func newTestApp(t *testing.T) (*GitHubAppClient, *fakeGitHub) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeGitHub{
+		t:        t,
+		key:      &key.PublicKey,
+		orgs:     map[string]int64{"acme": 1},
+		users:    map[string]int64{"fulano": 2},
+		removed:  map[int64]bool{},
+		tokenTTL: time.Hour,
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	app, err := NewGitHubAppClient("123", keyPEM, fallbackVault{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.BaseURL = srv.URL
+	return app, fake
+}
+
+func TestGitHubAppToken(t *testing.T) {
+	app, fake := newTestApp(t)
+
+	creds, err := app.GetCredentials("github", "ACME")
+	if err != nil || creds.Username != "x-access-token" || creds.Token != "token-1-1" {
+		t.Fatalf("GetCredentials(acme) = %+v, %v", creds, err)
+	}
+	if creds, err := app.GetCredentials("github", "acme"); err != nil || creds.Token != "token-1-1" {
+		t.Errorf("token em cache não reutilizado: %+v, %v", creds, err)
+	}
+	if creds, err := app.GetCredentials("github", "fulano"); err != nil || creds.Token != "token-2-2" {
+		t.Errorf("instalação na conta de usuário: %+v, %v", creds, err)
+	}
+	if lookups, tokens := fake.counts(); lookups != 3 || tokens != 2 {
+		t.Errorf("%d buscas de instalação e %d tokens, esperado 3 e 2", lookups, tokens)
+	}
+	if creds, err := app.GetCredentials("gitlab", "acme"); err != nil || creds.Token != "fallback" {
+		t.Errorf("outro provedor não foi para o Fallback: %+v, %v", creds, err)
+	}
+}
+
+func TestGitHubAppTokenRefresh(t *testing.T) {
+	app, fake := newTestApp(t)
+	// Tokens que vencem dentro da margem de renovação são gerados de novo.
+	fake.tokenTTL = tokenRefreshMargin - time.Minute
+	for i := 1; i <= 2; i++ {
+		creds, err := app.GetCredentials("github", "acme")
+		if want := fmt.Sprintf("token-1-%d", i); err != nil || creds.Token != want {
+			t.Errorf("chamada %d: %+v, %v; esperado %s", i, creds, err, want)
+		}
+	}
+	if lookups, _ := fake.counts(); lookups != 1 {
+		t.Errorf("%d buscas de instalação, esperado 1", lookups)
+	}
+}
+
+func TestGitHubAppTokenConcurrent(t *testing.T) {
+	app, fake := newTestApp(t)
+	fake.tokenDelay = 50 * time.Millisecond
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := app.GetCredentials("github", "acme"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// Outro owner não espera a geração em andamento.
+	start := time.Now()
+	if _, err := app.GetCredentials("github", "fulano"); err != nil {
+		t.Error(err)
+	}
+	elapsed := time.Since(start)
+	wg.Wait()
+	if _, tokens := fake.counts(); tokens != 2 {
+		t.Errorf("%d tokens gerados, esperado 1 por owner", tokens)
+	}
+	if elapsed > 5*fake.tokenDelay {
+		t.Errorf("token de outro owner levou %s", elapsed)
+	}
+}
+
+func TestGitHubAppNoInstallation(t *testing.T) {
+	app, fake := newTestApp(t)
+
+	if _, err := app.GetCredentials("github", "desconhecido"); !errors.Is(err, errNoInstallation) {
+		t.Errorf("owner sem instalação: %v, esperado errNoInstallation", err)
+	}
+
+	// 404 na geração do token não é falta de instalação: a instalação foi
+	// removida e é procurada de novo na próxima chamada.
+	if _, err := app.GetCredentials("github", "acme"); err != nil {
+		t.Fatal(err)
+	}
+	app.tokens = map[int64]installationToken{}
+	fake.mu.Lock()
+	fake.removed[1] = true
+	fake.mu.Unlock()
+	_, err := app.GetCredentials("github", "acme")
+	if err == nil || errors.Is(err, errNoInstallation) {
+		t.Errorf("token de instalação removida: %v", err)
+	}
+	before, _ := fake.counts()
+	fake.mu.Lock()
+	fake.orgs["acme"] = 3
+	fake.mu.Unlock()
+	creds, err := app.GetCredentials("github", "acme")
+	if after, _ := fake.counts(); err != nil || creds.Token != "token-3-2" || after != before+1 {
+		t.Errorf("instalação não procurada de novo: %+v, %v, %d buscas", creds, err, after-before)
+	}
+}
@@ -1,38 +1,119 @@
-package vault
-
-import (
-	"fmt"
-	"os"
-)
-
-type VaultClient interface {
-	GetGitHubCredentials() (*GitHubCredentials, error)
-}
-
-type GitHubCredentials struct {
-	Username string
-	Token    string
-}
-
-type DefaultVaultClient struct{}
-
-func (v *DefaultVaultClient) GetGitHubCredentials() (*GitHubCredentials, error) {
-	username := os.Getenv("GITHUB_USERNAME")
-	token := os.Getenv("GITHUB_TOKEN")
-	if username == "" || token == "" {
-		return nil, fmt.Errorf("credenciais do GitHub não encontradas")
-	}
-	return &GitHubCredentials{
-		Username: username,
-		Token:    token,
-	}, nil
-}
-
-type NoOpVaultClient struct{}
-
-func (v *NoOpVaultClient) GetGitHubCredentials() (*GitHubCredentials, error) {
-	return &GitHubCredentials{
-		Username: "default_user",
-		Token:    "default_token",
-	}, nil
-}
+package vault
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type VaultClient interface {
+	GetGitHubCredentials() (*GitHubCredentials, error)
+	// GetCredentials retorna as credenciais do provedor Git configurado com o
+	// nome informado (ex.: "gitlab") para os repositórios de owner (organização
+	// ou usuário); owner vazio retorna as credenciais padrão do provedor.
+	GetCredentials(provider, owner string) (*Credentials, error)
+	// GetSSHKey retorna a chave SSH de deploy da organização org no host ou,
+	// na falta dela, a do host. Sem chave cadastrada, retorna nil sem erro.
+	GetSSHKey(host, org string) (*SSHKey, error)
+}
+
+// Credentials são o usuário e o token (PAT ou token de acesso) de um provedor Git.
+// Username pode ser vazio quando o provedor aceita só o token.
+type Credentials struct {
+	Username string
+	Token    string
+}
+
+// SSHKey é uma chave SSH privada (PEM ou formato OpenSSH) e os known_hosts
+// usados para verificar o servidor.
+type SSHKey struct {
+	PrivateKey []byte
+	Passphrase string // Vazia para chaves sem passphrase.
+	// KnownHosts são linhas no formato do known_hosts do OpenSSH; vazio usa os
+	// arquivos known_hosts do sistema.
+	KnownHosts []byte
+}
+
+// GitHubCredentials são as credenciais do GitHub.
+type GitHubCredentials = Credentials
+
+type DefaultVaultClient struct{}
+
+func (v *DefaultVaultClient) GetGitHubCredentials() (*GitHubCredentials, error) {
+	username := os.Getenv("GITHUB_USERNAME")
+	token := os.Getenv("GITHUB_TOKEN")
+	if username == "" || token == "" {
+		return nil, fmt.Errorf("credenciais do GitHub não encontradas")
+	}
+	return &GitHubCredentials{
+		Username: username,
+		Token:    token,
+	}, nil
+}
+
+// GetCredentials lê <PROVEDOR>_USERNAME e <PROVEDOR>_TOKEN (ex.: GITLAB_TOKEN
+// para o provedor "gitlab"; hífens viram "_"); "github" equivale a
+// GetGitHubCredentials. As mesmas credenciais valem para todos os owners.
+func (v *DefaultVaultClient) GetCredentials(provider, owner string) (*Credentials, error) {
+	if provider == "github" {
+		return v.GetGitHubCredentials()
+	}
+	prefix := strings.ToUpper(strings.ReplaceAll(provider, "-", "_"))
+	token := os.Getenv(prefix + "_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("credenciais do provedor %s não encontradas (%s_TOKEN)", provider, prefix)
+	}
+	return &Credentials{
+		Username: os.Getenv(prefix + "_USERNAME"),
+		Token:    token,
+	}, nil
+}
+
+// GetSSHKey lê SSH_KEY_<HOST>_<ORG> ou SSH_KEY_<HOST>, a passphrase em
+// SSH_KEY_PASSPHRASE_<mesmo sufixo> e os known_hosts em SSH_KNOWN_HOSTS_<HOST>.
+// Nos sufixos, letras ficam maiúsculas e os demais caracteres viram "_" (ex.:
+// SSH_KEY_GITLAB_EMPRESA_COM_PIX para gitlab.empresa.com e a organização pix).
+func (v *DefaultVaultClient) GetSSHKey(host, org string) (*SSHKey, error) {
+	hostSuffix := envSuffix(host)
+	for _, suffix := range []string{hostSuffix + "_" + envSuffix(org), hostSuffix} {
+		key := os.Getenv("SSH_KEY_" + suffix)
+		if key == "" {
+			continue
+		}
+		return &SSHKey{
+			PrivateKey: []byte(key),
+			Passphrase: os.Getenv("SSH_KEY_PASSPHRASE_" + suffix),
+			KnownHosts: []byte(os.Getenv("SSH_KNOWN_HOSTS_" + hostSuffix)),
+		}, nil
+	}
+	return nil, nil
+}
+
+func envSuffix(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+type NoOpVaultClient struct{}
+
+func (v *NoOpVaultClient) GetGitHubCredentials() (*GitHubCredentials, error) {
+	return &GitHubCredentials{
+		Username: "default_user",
+		Token:    "default_token",
+	}, nil
+}
+
+func (v *NoOpVaultClient) GetCredentials(provider, owner string) (*Credentials, error) {
+	return v.GetGitHubCredentials()
+}
+
+func (v *NoOpVaultClient) GetSSHKey(host, org string) (*SSHKey, error) {
+	return nil, nil
+}
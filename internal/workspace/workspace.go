@@ -0,0 +1,154 @@
+//go:build linux || darwin
+
+// Package workspace gerencia os diretórios onde os repositórios são clonados:
+// cria um por job, remove-o ao fim do job e verifica o espaço em disco antes
+// de aceitar um clone.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"yourproject/internal/logger"
+)
+
+const (
+	dirPrefix = "repo_"
+	// defaultDir é o diretório, dentro de os.TempDir(), usado quando Root é vazio.
+	defaultDir = "clonescan-workspaces"
+	// sizeFactor estima o disco ocupado pelo clone (objetos e arquivos) a partir
+	// do tamanho informado pelo GitHub.
+	sizeFactor = 2
+	// unknownSize é a estimativa, em KB, para repositórios sem tamanho informado.
+	unknownSize = 100 * 1024
+)
+
+var (
+	// ErrInsufficientDisk indica falta de espaço livre no momento; o job deve
+	// ser adiado e tentado de novo quando outros workspaces forem removidos.
+	ErrInsufficientDisk = errors.New("espaço em disco insuficiente")
+	// ErrTooLarge indica que o repositório não cabe no disco mesmo vazio.
+	ErrTooLarge = errors.New("repositório maior que o disco disponível para workspaces")
+)
+
+// Manager cria os workspaces em Root, um diretório exclusivo dos workspaces:
+// Sweep remove dele tudo o que começar com "repo_". Antes de cada clone, o espaço livre menos
+// as estimativas dos workspaces em uso precisa cobrir a estimativa do novo
+// repositório e ainda deixar MinFreeBytes livres.
+type Manager struct {
+	Root         string // Vazio usa clonescan-workspaces em os.TempDir().
+	MinFreeBytes int64
+
+	mu       sync.Mutex
+	reserved int64 // Soma das estimativas dos workspaces em uso.
+}
+
+// Workspace é o diretório de um job. Path ainda não existe: é criado pelo clone.
+type Workspace struct {
+	Path string
+
+	m       *Manager
+	reserve int64
+	once    sync.Once
+}
+
+// Acquire reserva espaço para um repositório de sizeKB e retorna o workspace.
+// O chamador deve chamar Release ao fim do job, com sucesso ou falha.
+func (m *Manager) Acquire(sizeKB int) (*Workspace, error) {
+	if sizeKB <= 0 {
+		sizeKB = unknownSize
+	}
+	estimate := int64(sizeKB) * 1024 * sizeFactor
+
+	if err := os.MkdirAll(m.root(), 0o700); err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório de workspaces %s: %v", m.root(), err)
+	}
+	free, total, err := diskSpace(m.root())
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar espaço em disco de %s: %v", m.root(), err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case estimate+m.MinFreeBytes > total:
+		return nil, fmt.Errorf("%w: estimativa de %d MB, disco de %d MB", ErrTooLarge, estimate>>20, total>>20)
+	case free-m.reserved-estimate < m.MinFreeBytes:
+		return nil, fmt.Errorf("%w: estimativa de %d MB, %d MB livres e %d MB reservados para os jobs em execução",
+			ErrInsufficientDisk, estimate>>20, free>>20, m.reserved>>20)
+	}
+	m.reserved += estimate
+
+	return &Workspace{
+		Path:    filepath.Join(m.root(), fmt.Sprintf("%s%d", dirPrefix, time.Now().UnixNano())),
+		m:       m,
+		reserve: estimate,
+	}, nil
+}
+
+// Release remove o diretório do workspace e libera a reserva de espaço.
+func (w *Workspace) Release() {
+	w.once.Do(func() {
+		if err := os.RemoveAll(w.Path); err != nil {
+			logger.Log.Errorf("Workspace: erro ao remover %s: %v", w.Path, err)
+		}
+		w.m.mu.Lock()
+		w.m.reserved -= w.reserve
+		w.m.mu.Unlock()
+	})
+}
+
+// Sweep remove os workspaces deixados por execuções anteriores (ex.: processo
+// encerrado durante um clone). Deve ser chamado na inicialização, antes do
+// primeiro job.
+func (m *Manager) Sweep() error {
+	start := time.Now()
+	defer logger.Trace("WorkspaceSweep", start)
+
+	if err := os.MkdirAll(m.root(), 0o700); err != nil {
+		return fmt.Errorf("erro ao criar diretório de workspaces %s: %v", m.root(), err)
+	}
+	entries, err := os.ReadDir(m.root())
+	if err != nil {
+		return fmt.Errorf("erro ao listar workspaces em %s: %v", m.root(), err)
+	}
+	removed := 0
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), dirPrefix) {
+			continue
+		}
+		path := filepath.Join(m.root(), e.Name())
+		if err := os.RemoveAll(path); err != nil {
+			logger.Log.Errorf("Workspace: erro ao remover workspace órfão %s: %v", path, err)
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		logger.Log.Infof("Workspace: %d workspaces órfãos removidos de %s", removed, m.root())
+	}
+	return nil
+}
+
+func (m *Manager) root() string {
+	if m.Root == "" {
+		return filepath.Join(os.TempDir(), defaultDir)
+	}
+	return m.Root
+}
+
+// diskSpace retorna os bytes livres (para usuários sem privilégio) e o tamanho
+// total do sistema de arquivos de path.
+func diskSpace(path string) (free, total int64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), int64(st.Blocks) * int64(st.Bsize), nil
+}
@@ -0,0 +1,78 @@
+//go:build linux || darwin
+
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSweep(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "workspaces")
+	m := &Manager{Root: root}
+	if err := m.Sweep(); err != nil {
+		t.Fatalf("Sweep em diretório inexistente: %v", err)
+	}
+	for _, name := range []string{"repo_1", "repo_2", "outro"} {
+		if err := os.MkdirAll(filepath.Join(root, name, ".git"), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Sweep(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "outro" {
+		t.Errorf("restaram %v, esperado só outro", entries)
+	}
+}
+
+func TestDefaultRoot(t *testing.T) {
+	m := &Manager{}
+	if got, want := m.root(), filepath.Join(os.TempDir(), defaultDir); got != want {
+		t.Errorf("root() = %s, esperado %s", got, want)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	root := t.TempDir()
+	free, total, err := diskSpace(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		minFree int64
+		sizeKB  int
+		wantErr error
+	}{
+		{name: "cabe", sizeKB: 1},
+		{name: "maior que o disco", sizeKB: int(total>>10) + 1, wantErr: ErrTooLarge},
+		// Sobra menos que MinFreeBytes, mas o repositório caberia no disco vazio.
+		{name: "sem espaço livre", minFree: free, sizeKB: 1, wantErr: ErrInsufficientDisk},
+	}
+	for _, tt := range tests {
+		m := &Manager{Root: root, MinFreeBytes: tt.minFree}
+		ws, err := m.Acquire(tt.sizeKB)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: erro %v, esperado %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if filepath.Dir(ws.Path) != root || m.reserved != 1024*sizeFactor {
+			t.Errorf("%s: workspace %s com %d bytes reservados", tt.name, ws.Path, m.reserved)
+		}
+		ws.Release()
+		ws.Release()
+		if m.reserved != 0 {
+			t.Errorf("%s: %d bytes reservados após Release", tt.name, m.reserved)
+		}
+	}
+}
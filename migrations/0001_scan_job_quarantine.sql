@@ -0,0 +1,17 @@
+-- Mensagens de job em quarentena (JSON inválido ou falhas repetidas no processamento).
+CREATE TABLE IF NOT EXISTS scan_job_quarantine (
+    id          UUID PRIMARY KEY,
+    source      TEXT        NOT NULL,
+    message_id  TEXT        NOT NULL,
+    scan_id     TEXT,
+    body        TEXT        NOT NULL,
+    attributes  JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    reason      TEXT        NOT NULL,
+    attempts    INTEGER     NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    redriven_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_scan_job_quarantine_pending
+    ON scan_job_quarantine (created_at)
+    WHERE redriven_at IS NULL;
@@ -0,0 +1,11 @@
+-- Colunas usadas pela API HTTP para criar e consultar scans.
+CREATE TABLE IF NOT EXISTS scans (
+    id         TEXT PRIMARY KEY,
+    status     TEXT        NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS repository_id        TEXT;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS repository_full_name TEXT;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS sigla                TEXT;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS created_at           TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1,15 @@
+-- Outbox transacional de eventos de scan (scan.completed / scan.failed).
+CREATE TABLE IF NOT EXISTS scan_events_outbox (
+    id           UUID PRIMARY KEY,
+    event_type   TEXT        NOT NULL,
+    scan_id      TEXT        NOT NULL,
+    payload      JSONB       NOT NULL,
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_scan_events_outbox_pending
+    ON scan_events_outbox (created_at)
+    WHERE published_at IS NULL;
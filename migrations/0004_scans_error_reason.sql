@@ -0,0 +1,2 @@
+-- Motivo da rejeição ou falha gravado junto ao scan.
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS error_reason TEXT;
@@ -0,0 +1,21 @@
+-- Lease por repositório: apenas um scan por repositório roda por vez entre as réplicas.
+CREATE TABLE IF NOT EXISTS repo_scan_leases (
+    repository_id  TEXT PRIMARY KEY,
+    leader_scan_id TEXT        NOT NULL,
+    owner          TEXT        NOT NULL,
+    acquired_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at     TIMESTAMPTZ NOT NULL
+);
+
+-- Scans anexados a um scan líder; recebem o mesmo resultado quando ele termina.
+CREATE TABLE IF NOT EXISTS repo_scan_followers (
+    scan_id        TEXT PRIMARY KEY,
+    leader_scan_id TEXT        NOT NULL,
+    repository_id  TEXT        NOT NULL,
+    attached_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_repo_scan_followers_leader
+    ON repo_scan_followers (leader_scan_id);
+
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS coalesced_into TEXT;
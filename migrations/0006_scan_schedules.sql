@@ -0,0 +1,18 @@
+-- Agendamentos recorrentes de scan (cron de 5 campos) por repositório ou por sigla.
+CREATE TABLE IF NOT EXISTS scan_schedules (
+    id                   UUID PRIMARY KEY,
+    repository_id        TEXT,
+    repository_full_name TEXT,
+    sigla                TEXT        NOT NULL,
+    cron_expr            TEXT        NOT NULL,
+    jitter_seconds       INTEGER     NOT NULL DEFAULT 0,
+    enabled              BOOLEAN     NOT NULL DEFAULT TRUE,
+    next_run_at          TIMESTAMPTZ NOT NULL,
+    last_run_at          TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((repository_id IS NULL) = (repository_full_name IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_scan_schedules_due
+    ON scan_schedules (next_run_at)
+    WHERE enabled;
@@ -0,0 +1,2 @@
+-- Pedido de cancelamento de um scan em execução, observado pela réplica que o executa.
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS cancel_requested_at TIMESTAMPTZ;
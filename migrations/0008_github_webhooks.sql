@@ -0,0 +1,11 @@
+-- Intervalo de commits escaneado (push ou pull request); nulo escaneia o histórico completo.
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS before_sha TEXT;
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS after_sha  TEXT;
+
+-- Entregas de webhook do GitHub já processadas, para ignorar reentregas.
+CREATE TABLE IF NOT EXISTS github_webhook_deliveries (
+    delivery_id TEXT PRIMARY KEY,
+    event       TEXT        NOT NULL,
+    scan_id     TEXT        NOT NULL REFERENCES scans (id),
+    received_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
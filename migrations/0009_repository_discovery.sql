@@ -0,0 +1,9 @@
+-- Repositórios encontrados pela descoberta por organização e o último push já enfileirado.
+CREATE TABLE IF NOT EXISTS discovered_repositories (
+    repository_id        TEXT PRIMARY KEY,
+    repository_full_name TEXT        NOT NULL,
+    sigla                TEXT        NOT NULL,
+    pushed_at            TIMESTAMPTZ NOT NULL,
+    last_scan_id         TEXT        NOT NULL,
+    enqueued_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,30 @@
+-- Backfills: reescaneamentos em massa enfileirados em ritmo controlado.
+CREATE TABLE IF NOT EXISTS backfills (
+    id          UUID PRIMARY KEY,
+    name        TEXT             NOT NULL,
+    source      TEXT             NOT NULL,
+    rate        DOUBLE PRECISION NOT NULL,
+    status      TEXT             NOT NULL DEFAULT 'running',
+    created_at  TIMESTAMPTZ      NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ      NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+-- Um item por repositório; o scan_id é reservado na criação para que a retomada
+-- reutilize o mesmo scan em vez de duplicá-lo.
+CREATE TABLE IF NOT EXISTS backfill_items (
+    backfill_id          UUID    NOT NULL REFERENCES backfills (id) ON DELETE CASCADE,
+    position             INTEGER NOT NULL,
+    scan_id              TEXT    NOT NULL,
+    repository_id        TEXT    NOT NULL,
+    repository_full_name TEXT    NOT NULL,
+    sigla                TEXT    NOT NULL,
+    repository_size      INTEGER NOT NULL DEFAULT 0,
+    repository_language  TEXT    NOT NULL DEFAULT '',
+    enqueued_at          TIMESTAMPTZ,
+    PRIMARY KEY (backfill_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_backfill_items_pending
+    ON backfill_items (backfill_id, position)
+    WHERE enqueued_at IS NULL;
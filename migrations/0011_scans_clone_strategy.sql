@@ -0,0 +1,2 @@
+-- Estratégia de clone usada no scan (full, single-branch, shallow ou blobless).
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS clone_strategy TEXT;
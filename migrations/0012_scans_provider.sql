@@ -0,0 +1,2 @@
+-- Provedor Git do repositório escaneado; nulo é o GitHub.
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS provider TEXT;
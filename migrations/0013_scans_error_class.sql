@@ -0,0 +1,2 @@
+-- Classe da falha de clone do scan (timeout, too_large, auth ou not_found).
+ALTER TABLE scans ADD COLUMN IF NOT EXISTS error_class TEXT;
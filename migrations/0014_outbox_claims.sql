@@ -0,0 +1,3 @@
+-- Reserva dos eventos em publicação: o relay publica fora da transação e os
+-- eventos reservados só voltam a ser lidos após claimed_until.
+ALTER TABLE scan_events_outbox ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMPTZ;
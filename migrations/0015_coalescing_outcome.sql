@@ -0,0 +1,3 @@
+-- Resultado do líder para cada scan anexado: os anexados aguardam o líder e só
+-- confirmam a entrega depois de ler este resultado.
+ALTER TABLE repo_scan_followers ADD COLUMN IF NOT EXISTS outcome TEXT;
@@ -0,0 +1,2 @@
+-- Provedor Git do repositório do item; nulo é o GitHub.
+ALTER TABLE backfill_items ADD COLUMN IF NOT EXISTS provider TEXT;
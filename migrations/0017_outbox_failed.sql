@@ -0,0 +1,8 @@
+-- Eventos que esgotaram as tentativas de publicação ficam marcados em failed_at
+-- (com o último erro em last_error) e deixam de ser lidos pelo relay.
+ALTER TABLE scan_events_outbox ADD COLUMN IF NOT EXISTS failed_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS idx_scan_events_outbox_pending;
+CREATE INDEX IF NOT EXISTS idx_scan_events_outbox_pending
+    ON scan_events_outbox (created_at)
+    WHERE published_at IS NULL AND failed_at IS NULL;
@@ -1,22 +1,184 @@
-package models
-
-import "time"
-
-type ScanJob struct {
-	ScanID             string    `json:"scan_id"`
-	RepositoryID       string    `json:"repository_id"`
-	RepositoryFullName string    `json:"repository_full_name"` // Ex.: "org/repo"
-	RepositorySize     int       `json:"repository_size"`
-	RepositoryLanguage string    `json:"repository_language"`
-	Sigla              string    `json:"sigla"`
-	MessageCreatedAt   time.Time `json:"message_created_at"`
-}
-
-type GitleaksFinding struct {
-	Description string   `json:"Description"`
-	File        string   `json:"File"`
-	StartLine   int      `json:"StartLine"`
-	RuleID      string   `json:"RuleID"`
-	Secret      string   `json:"Secret"`
-	Tags        []string `json:"Tags"`
-}
+package models
+
+import "time"
+
+type ScanJob struct {
+	SchemaVersion      int       `json:"schema_version"`
+	ScanID             string    `json:"scan_id"`
+	RepositoryID       string    `json:"repository_id"`
+	RepositoryFullName string    `json:"repository_full_name"` // Ex.: "org/repo"
+	RepositorySize     int       `json:"repository_size"`
+	RepositoryLanguage string    `json:"repository_language"`
+	Sigla              string    `json:"sigla"`
+	MessageCreatedAt   time.Time `json:"message_created_at"`
+	// BeforeSHA e AfterSHA limitam o scan aos commits de um push ou pull request.
+	// Vazios, o histórico completo é escaneado.
+	BeforeSHA string `json:"before_sha,omitempty"`
+	AfterSHA  string `json:"after_sha,omitempty"`
+	// CloneStrategy força a estratégia de clone ("full", "single-branch",
+	// "shallow" ou "blobless"); vazia, o serviço escolhe pelo tamanho.
+	CloneStrategy string `json:"clone_strategy,omitempty"`
+	// Provider é o nome do provedor Git configurado (ex.: "gitlab"); vazio é o
+	// GitHub. Em outros provedores, RepositoryFullName segue o formato deles
+	// (ex.: "grupo/subgrupo/repo" no GitLab).
+	Provider string `json:"provider,omitempty"`
+}
+
+// Estratégias de clone aceitas em ScanJob.CloneStrategy; "" e "auto" deixam a
+// escolha ao serviço.
+const (
+	CloneStrategyAuto         = "auto"
+	CloneStrategyFull         = "full"
+	CloneStrategySingleBranch = "single-branch"
+	CloneStrategyShallow      = "shallow"
+	CloneStrategyBlobless     = "blobless"
+)
+
+// ValidCloneStrategy indica se s é aceita em ScanJob.CloneStrategy.
+func ValidCloneStrategy(s string) bool {
+	switch s {
+	case "", CloneStrategyAuto, CloneStrategyFull, CloneStrategySingleBranch, CloneStrategyShallow, CloneStrategyBlobless:
+		return true
+	}
+	return false
+}
+
+// RepositoryKey identifica o repositório entre provedores: IDs de provedores
+// diferentes podem coincidir, então fora do GitHub o ID leva o provedor.
+func (j *ScanJob) RepositoryKey() string {
+	if j.Provider == "" || j.Provider == "github" {
+		return j.RepositoryID
+	}
+	return j.Provider + ":" + j.RepositoryID
+}
+
+// LeaseKey identifica o scan para o coalescer: jobs com intervalo de commits
+// (webhooks) só são anexados a scans do mesmo repositório e do mesmo intervalo.
+func (j *ScanJob) LeaseKey() string {
+	if j.AfterSHA == "" {
+		return j.RepositoryKey()
+	}
+	return j.RepositoryKey() + "@" + j.BeforeSHA + ".." + j.AfterSHA
+}
+
+// ControlMessage é uma mensagem de controle recebida pela mesma fila dos jobs.
+// Hoje o único tipo suportado é "cancel".
+type ControlMessage struct {
+	Type   string `json:"type"`
+	ScanID string `json:"scan_id"`
+}
+
+// ControlCancel pede o cancelamento do scan indicado em ControlMessage.ScanID.
+const ControlCancel = "cancel"
+
+// Scan é a linha da tabela scans que acompanha o ciclo de vida de um ScanJob.
+type Scan struct {
+	ID                 string    `json:"id"`
+	RepositoryID       string    `json:"repository_id"`
+	RepositoryFullName string    `json:"repository_full_name"`
+	Sigla              string    `json:"sigla"`
+	Status             string    `json:"status"`
+	ErrorReason        string    `json:"error_reason,omitempty"`
+	ErrorClass         string    `json:"error_class,omitempty"` // Classe da falha de clone (timeout, too_large, auth, not_found).
+	BeforeSHA          string    `json:"before_sha,omitempty"`
+	AfterSHA           string    `json:"after_sha,omitempty"`
+	CloneStrategy      string    `json:"clone_strategy,omitempty"` // Estratégia usada no clone.
+	Provider           string    `json:"provider,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type GitleaksFinding struct {
+	Description string   `json:"Description"`
+	File        string   `json:"File"`
+	StartLine   int      `json:"StartLine"`
+	RuleID      string   `json:"RuleID"`
+	Secret      string   `json:"Secret"`
+	Tags        []string `json:"Tags"`
+}
+
+// QuarantinedJob é uma mensagem retirada do fluxo normal por ser inválida ou por
+// falhar repetidamente; fica guardada para inspeção e reenvio.
+type QuarantinedJob struct {
+	ID         string            `json:"id"`
+	Source     string            `json:"source"` // Origem da mensagem (ex.: URL da fila SQS).
+	MessageID  string            `json:"message_id"`
+	ScanID     string            `json:"scan_id,omitempty"`
+	Body       string            `json:"body"`
+	Attributes map[string]string `json:"attributes"`
+	Reason     string            `json:"reason"`
+	Attempts   int               `json:"attempts"`
+	CreatedAt  time.Time         `json:"created_at"`
+	RedrivenAt *time.Time        `json:"redriven_at,omitempty"`
+}
+
+// Tipos de evento publicados ao final de um scan.
+const (
+	EventScanCompleted = "scan.completed"
+	EventScanFailed    = "scan.failed"
+	EventScanCancelled = "scan.cancelled"
+)
+
+// ScanEvent é o evento emitido quando um scan termina, com sucesso ou falha.
+type ScanEvent struct {
+	EventID            string         `json:"event_id"`
+	Type               string         `json:"type"`
+	ScanID             string         `json:"scan_id"`
+	RepositoryID       string         `json:"repository_id"`
+	RepositoryFullName string         `json:"repository_full_name"`
+	Sigla              string         `json:"sigla"`
+	Status             string         `json:"status"`
+	TotalFindings      int            `json:"total_findings"`
+	FindingsByRule     map[string]int `json:"findings_by_rule"`
+	DurationMs         int64          `json:"duration_ms"`
+	Error              string         `json:"error,omitempty"`
+	ErrorClass         string         `json:"error_class,omitempty"` // Classe da falha de clone, se houver.
+	OccurredAt         time.Time      `json:"occurred_at"`
+}
+
+// OutboxEvent é um evento gravado na outbox aguardando publicação.
+type OutboxEvent struct {
+	ID        string
+	EventType string
+	ScanID    string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// ScanSchedule é um agendamento recorrente de scans para um repositório ou para
+// todos os repositórios conhecidos de uma sigla.
+type ScanSchedule struct {
+	ID                 string     `json:"id"`
+	RepositoryID       string     `json:"repository_id,omitempty"`
+	RepositoryFullName string     `json:"repository_full_name,omitempty"`
+	Sigla              string     `json:"sigla"`
+	CronExpr           string     `json:"cron_expr"`
+	JitterSeconds      int        `json:"jitter_seconds"`
+	Enabled            bool       `json:"enabled"`
+	NextRunAt          time.Time  `json:"next_run_at"`
+	LastRunAt          *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// Backfill é um reescaneamento em massa cujo progresso fica gravado, permitindo
+// retomar um backfill interrompido.
+type Backfill struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Source     string     `json:"source"` // Arquivo ou consulta de descoberta que gerou os itens.
+	Rate       float64    `json:"rate"`   // Jobs enfileirados por segundo.
+	Status     string     `json:"status"` // "running" ou "completed".
+	Total      int        `json:"total"`
+	Enqueued   int        `json:"enqueued"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// BackfillItem é um repositório do backfill; Job.ScanID é definido na criação.
+type BackfillItem struct {
+	Position   int        `json:"position"`
+	Job        ScanJob    `json:"job"`
+	EnqueuedAt *time.Time `json:"enqueued_at,omitempty"`
+}